@@ -0,0 +1,2 @@
+// Package chbatch is a buffered batch writer for inserts.
+package chbatch
@@ -0,0 +1,116 @@
+package chbatch
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/ClickHouse/ch-go/otelch"
+)
+
+var durationBuckets = []float64{
+	0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1,
+	0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300,
+}
+
+// writerMetrics is set of OpenTelemetry instruments of Writer.
+type writerMetrics struct {
+	meter         metric.Meter
+	attributes    []attribute.KeyValue
+	flushDuration metric.Float64Histogram
+	rows          metric.Int64Counter
+	rowsDropped   metric.Int64Counter
+	retries       metric.Int64Counter
+	// spoolCorrupted is number of quarantined spooled batches.
+	spoolCorrupted metric.Int64Counter
+}
+
+func newWriterMetrics(m metric.Meter, attrs []attribute.KeyValue) (*writerMetrics, error) {
+	var (
+		wm  = writerMetrics{meter: m, attributes: attrs}
+		err error
+	)
+	if wm.flushDuration, err = m.Float64Histogram(otelch.MetricBatchFlushDuration,
+		metric.WithDescription("Duration of batch flush attempt"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	); err != nil {
+		return nil, errors.Wrap(err, "flush duration")
+	}
+	if wm.rows, err = m.Int64Counter(otelch.MetricBatchRows,
+		metric.WithDescription("Rows of flushed batches"),
+		metric.WithUnit("{row}"),
+	); err != nil {
+		return nil, errors.Wrap(err, "rows")
+	}
+	if wm.rowsDropped, err = m.Int64Counter(otelch.MetricBatchRowsDropped,
+		metric.WithDescription("Rows dropped due to backpressure"),
+		metric.WithUnit("{row}"),
+	); err != nil {
+		return nil, errors.Wrap(err, "rows dropped")
+	}
+	if wm.retries, err = m.Int64Counter(otelch.MetricBatchRetries,
+		metric.WithDescription("Retries of batch flush"),
+		metric.WithUnit("{retry}"),
+	); err != nil {
+		return nil, errors.Wrap(err, "retries")
+	}
+	if wm.spoolCorrupted, err = m.Int64Counter(otelch.MetricBatchSpoolCorrupted,
+		metric.WithDescription("Spooled batches that can't be decoded and were quarantined"),
+		metric.WithUnit("{batch}"),
+	); err != nil {
+		return nil, errors.Wrap(err, "spool corrupted")
+	}
+	return &wm, nil
+}
+
+// attrs returns writer attributes with additional ones.
+func (m *writerMetrics) attrs(kv ...attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(append(kv, m.attributes...)...)
+}
+
+// registerMetrics registers observable instruments reporting queue depth
+// and spool size.
+func (w *Writer[T]) registerMetrics() (metric.Registration, error) {
+	m := w.metrics.meter
+	rows, err := m.Int64ObservableGauge(otelch.MetricBatchQueueRows,
+		metric.WithDescription("Number of rows that are not flushed yet"),
+		metric.WithUnit("{row}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "queue rows")
+	}
+	batches, err := m.Int64ObservableGauge(otelch.MetricBatchQueueBatches,
+		metric.WithDescription("Number of full batches waiting for flush"),
+		metric.WithUnit("{batch}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "queue batches")
+	}
+	spoolBatches, err := m.Int64ObservableGauge(otelch.MetricBatchSpoolBatches,
+		metric.WithDescription("Number of spooled batches"),
+		metric.WithUnit("{batch}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "spool batches")
+	}
+	spoolSize, err := m.Int64ObservableGauge(otelch.MetricBatchSpoolSize,
+		metric.WithDescription("Total size of spool files"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "spool size")
+	}
+	attrs := w.metrics.attrs()
+	return m.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		o.ObserveInt64(rows, w.pending.Load(), attrs)
+		o.ObserveInt64(batches, int64(len(w.queue)), attrs)
+		if s := w.opt.Spool; s != nil {
+			o.ObserveInt64(spoolBatches, int64(s.Len()), attrs)
+			o.ObserveInt64(spoolSize, s.Size(), attrs)
+		}
+		return nil
+	}, rows, batches, spoolBatches, spoolSize)
+}
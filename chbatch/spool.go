@@ -0,0 +1,461 @@
+package chbatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/compress"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// SyncPolicy is fsync policy of Spool.
+type SyncPolicy byte
+
+const (
+	// SyncAlways syncs each saved batch and spool directory to disk.
+	SyncAlways SyncPolicy = iota
+	// SyncNever relies on operating system to write data to disk, so
+	// batches can be lost on power failure.
+	SyncNever
+)
+
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncAlways:
+		return "Always"
+	case SyncNever:
+		return "Never"
+	default:
+		return "Unknown"
+	}
+}
+
+// ErrSpoolFull is returned by Spool.Save if size limits are exceeded.
+var ErrSpoolFull = errors.New("spool is full")
+
+// CorruptedError means that spooled batch can't be decoded, e.g. due to
+// disk corruption or column type that can't be inferred.
+//
+// File of batch is renamed with ".corrupt" extension, so it is kept for
+// investigation and is not replayed again.
+type CorruptedError struct {
+	File string
+	Err  error
+}
+
+func (e *CorruptedError) Error() string {
+	return fmt.Sprintf("corrupted spool file %s: %s", e.File, e.Err)
+}
+
+func (e *CorruptedError) Unwrap() error {
+	return e.Err
+}
+
+// SpoolOptions for Spool.
+type SpoolOptions struct {
+	// Dir is directory of spool files, created if not exists.
+	Dir string
+	// MaxBytes is maximum total size of spool files, zero is unlimited.
+	MaxBytes int64
+	// MaxBatches is maximum number of spooled batches, zero is unlimited.
+	MaxBatches int
+	// Sync is fsync policy, default is SyncAlways.
+	Sync SyncPolicy
+	// Compression of spooled blocks, default is LZ4.
+	Compression compress.Method
+
+	Logger *zap.Logger
+}
+
+func (o *SpoolOptions) setDefaults() {
+	if o.Compression == 0 {
+		o.Compression = compress.LZ4
+	}
+	if o.Logger == nil {
+		o.Logger = zap.NewNop()
+	}
+}
+
+// Spool file layout:
+//
+//	magic (4 bytes) | protocol version (uint32) | compressed frames
+//
+// Frames contain table name, insert settings and raw Native block.
+const (
+	spoolMagic      = "CHSP"
+	spoolHeaderSize = len(spoolMagic) + 4
+	spoolChunkSize  = 1024 * 1024
+
+	spoolExt     = ".batch"
+	spoolTempExt = ".tmp"
+	corruptExt   = ".corrupt"
+)
+
+type spoolFile struct {
+	seq  uint64
+	size int64
+}
+
+// Spool is durable on-disk queue of batches, e.g. for batches that
+// failed to insert during server outage.
+//
+// Each batch is written to separate file as compressed Native block,
+// batches are replayed in order of saving. Incomplete files are removed
+// on OpenSpool and corrupted ones are renamed with ".corrupt" extension
+// during replay.
+type Spool struct {
+	opt SpoolOptions
+	lg  *zap.Logger
+
+	mux   sync.Mutex
+	files []spoolFile
+	size  int64
+	seq   uint64
+	w     *compress.Writer
+	buf   proto.Buffer
+
+	// replay ensures that batches are replayed in order.
+	replay sync.Mutex
+}
+
+// OpenSpool opens spool in directory, recovering saved batches.
+func OpenSpool(opt SpoolOptions) (*Spool, error) {
+	opt.setDefaults()
+	if opt.Dir == "" {
+		return nil, errors.New("dir is required")
+	}
+	if err := os.MkdirAll(opt.Dir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "mkdir")
+	}
+	entries, err := os.ReadDir(opt.Dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "read dir")
+	}
+	s := &Spool{
+		opt: opt,
+		lg:  opt.Logger,
+		w:   compress.NewWriter(),
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, spoolTempExt) {
+			// Incomplete write, batch was not saved.
+			s.lg.Warn("Removing incomplete spool file", zap.String("file", name))
+			if err := os.Remove(filepath.Join(opt.Dir, name)); err != nil {
+				return nil, errors.Wrap(err, "remove incomplete")
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, spoolExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, errors.Wrap(err, "stat")
+		}
+		s.files = append(s.files, spoolFile{seq: seq, size: info.Size()})
+		s.size += info.Size()
+	}
+	sort.Slice(s.files, func(i, j int) bool {
+		return s.files[i].seq < s.files[j].seq
+	})
+	if n := len(s.files); n > 0 {
+		s.seq = s.files[n-1].seq + 1
+	}
+	return s, nil
+}
+
+func (s *Spool) path(seq uint64, ext string) string {
+	return filepath.Join(s.opt.Dir, fmt.Sprintf("%020d%s", seq, ext))
+}
+
+// Len returns number of spooled batches.
+func (s *Spool) Len() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.files)
+}
+
+// Size returns total size of spool files in bytes.
+func (s *Spool) Size() int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.size
+}
+
+// encode batch to spool file format, requires lock.
+func (s *Spool) encode(table string, settings []ch.Setting, input proto.Input) ([]byte, error) {
+	rows := 0
+	if len(input) > 0 {
+		rows = input[0].Data.Rows()
+	}
+	s.buf.Reset()
+	s.buf.PutString(table)
+	s.buf.PutUVarInt(uint64(len(settings)))
+	for _, v := range settings {
+		s.buf.PutString(v.Key)
+		s.buf.PutString(v.Value)
+		s.buf.PutBool(v.Important)
+	}
+	block := proto.Block{Columns: len(input), Rows: rows}
+	if err := block.EncodeRawBlock(&s.buf, proto.Version, input); err != nil {
+		return nil, errors.Wrap(err, "encode block")
+	}
+
+	data := make([]byte, spoolHeaderSize, spoolHeaderSize+len(s.buf.Buf)/2)
+	copy(data, spoolMagic)
+	binary.LittleEndian.PutUint32(data[len(spoolMagic):], uint32(proto.Version))
+	for raw := s.buf.Buf; len(raw) > 0; {
+		n := len(raw)
+		if n > spoolChunkSize {
+			n = spoolChunkSize
+		}
+		if err := s.w.Compress(s.opt.Compression, raw[:n]); err != nil {
+			return nil, errors.Wrap(err, "compress")
+		}
+		data = append(data, s.w.Data...)
+		raw = raw[n:]
+	}
+	return data, nil
+}
+
+// Save batch to spool with settings of insert query, e.g.
+// insert_deduplication_token.
+//
+// Returns ErrSpoolFull if size limits are exceeded. Returns error if
+// type of column can't be inferred on replay (see proto.ColAuto),
+// so batch is not lost.
+func (s *Spool) Save(table string, settings []ch.Setting, input proto.Input) error {
+	for _, col := range input {
+		// Replay decodes batch with proto.ColAuto.
+		if err := new(proto.ColAuto).Infer(col.Data.Type()); err != nil {
+			return errors.Wrapf(err, "column %q can't be spooled", col.Name)
+		}
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.opt.MaxBatches > 0 && len(s.files) >= s.opt.MaxBatches {
+		return ErrSpoolFull
+	}
+	data, err := s.encode(table, settings, input)
+	if err != nil {
+		return err
+	}
+	size := int64(len(data))
+	if s.opt.MaxBytes > 0 && s.size+size > s.opt.MaxBytes {
+		return ErrSpoolFull
+	}
+
+	seq := s.seq
+	if err := s.write(seq, data); err != nil {
+		return errors.Wrap(err, "write")
+	}
+	s.seq++
+	s.files = append(s.files, spoolFile{seq: seq, size: size})
+	s.size += size
+	return nil
+}
+
+// write data to temporary file and rename it, so partially written
+// batches are never replayed.
+func (s *Spool) write(seq uint64, data []byte) (rErr error) {
+	tmp := s.path(seq, spoolTempExt)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return errors.Wrap(err, "create")
+	}
+	defer func() {
+		if rErr != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if s.opt.Sync == SyncAlways {
+		if err := f.Sync(); err != nil {
+			return errors.Wrap(err, "sync")
+		}
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "close")
+	}
+	if err := os.Rename(tmp, s.path(seq, spoolExt)); err != nil {
+		return errors.Wrap(err, "rename")
+	}
+	if s.opt.Sync == SyncAlways {
+		if err := syncDir(s.opt.Dir); err != nil {
+			return errors.Wrap(err, "sync dir")
+		}
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir) // #nosec G304
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+	return d.Close()
+}
+
+// spooledBatch is batch decoded from spool file.
+type spooledBatch struct {
+	table    string
+	settings []ch.Setting
+	input    proto.Input
+}
+
+func decodeSpooled(data []byte) (*spooledBatch, error) {
+	if len(data) < spoolHeaderSize || string(data[:len(spoolMagic)]) != spoolMagic {
+		return nil, errors.New("invalid header")
+	}
+	version := int(binary.LittleEndian.Uint32(data[len(spoolMagic):]))
+	r := proto.NewReader(compress.NewReader(bytes.NewReader(data[spoolHeaderSize:])))
+
+	var (
+		b   spooledBatch
+		err error
+	)
+	if b.table, err = r.Str(); err != nil {
+		return nil, errors.Wrap(err, "table")
+	}
+	settings, err := r.UVarInt()
+	if err != nil {
+		return nil, errors.Wrap(err, "settings")
+	}
+	if settings > uint64(len(data)) {
+		return nil, errors.Errorf("invalid settings count %d", settings)
+	}
+	for i := uint64(0); i < settings; i++ {
+		var v ch.Setting
+		if v.Key, err = r.Str(); err != nil {
+			return nil, errors.Wrap(err, "setting key")
+		}
+		if v.Value, err = r.Str(); err != nil {
+			return nil, errors.Wrap(err, "setting value")
+		}
+		if v.Important, err = r.Bool(); err != nil {
+			return nil, errors.Wrap(err, "setting flag")
+		}
+		b.settings = append(b.settings, v)
+	}
+	var (
+		block   proto.Block
+		results proto.Results
+	)
+	if err := block.DecodeRawBlock(r, version, results.Auto()); err != nil {
+		return nil, errors.Wrap(err, "decode block")
+	}
+	for _, col := range results {
+		data, ok := col.Data.(proto.ColInput)
+		if !ok {
+			return nil, errors.Errorf("column %q: %T is not input", col.Name, col.Data)
+		}
+		b.input = append(b.input, proto.InputColumn{Name: col.Name, Data: data})
+	}
+	return &b, nil
+}
+
+// Replay inserts spooled batches in order of saving, removing them
+// from spool. Replay stops on first failed insert, so order of batches
+// is preserved.
+//
+// Batches are inserted with settings they were saved with, including
+// deduplication tokens. Batches that can't be decoded are skipped and
+// reported as *CorruptedError along with insert error, if any.
+//
+// Returns number of replayed batches.
+func (s *Spool) Replay(ctx context.Context, client Client) (int, error) {
+	s.replay.Lock()
+	defer s.replay.Unlock()
+
+	var (
+		n    int
+		errs error
+	)
+	for {
+		s.mux.Lock()
+		if len(s.files) == 0 {
+			s.mux.Unlock()
+			return n, errs
+		}
+		f := s.files[0]
+		s.mux.Unlock()
+
+		err := s.replayFile(ctx, client, f)
+		var corrupted *CorruptedError
+		if err != nil && !errors.As(err, &corrupted) {
+			return n, multierr.Append(errs, errors.Wrapf(err, "batch %d", f.seq))
+		}
+		s.mux.Lock()
+		s.files = s.files[1:]
+		s.size -= f.size
+		s.mux.Unlock()
+		if corrupted != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		n++
+	}
+}
+
+// replayFile inserts spooled batch and removes file.
+func (s *Spool) replayFile(ctx context.Context, client Client, f spoolFile) error {
+	name := s.path(f.seq, spoolExt)
+	data, err := os.ReadFile(name) // #nosec G304
+	if err != nil {
+		return errors.Wrap(err, "read")
+	}
+	b, err := decodeSpooled(data)
+	if err != nil {
+		// Corrupted file, keeping it for investigation.
+		corrupt := s.path(f.seq, corruptExt)
+		s.lg.Error("Corrupted spool file",
+			zap.String("file", corrupt),
+			zap.Error(err),
+		)
+		if err := os.Rename(name, corrupt); err != nil {
+			return errors.Wrap(err, "rename corrupted")
+		}
+		return &CorruptedError{File: corrupt, Err: err}
+	}
+	if err := client.Do(ctx, ch.Query{
+		Body:     b.input.Into(b.table),
+		Input:    b.input,
+		Settings: b.settings,
+	}); err != nil {
+		return errors.Wrap(err, "insert")
+	}
+	if err := os.Remove(name); err != nil {
+		return errors.Wrap(err, "remove")
+	}
+	return nil
+}
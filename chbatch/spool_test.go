@@ -0,0 +1,235 @@
+package chbatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/compress"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func eventInput(ids ...uint64) proto.Input {
+	b := new(eventBatch)
+	for _, id := range ids {
+		b.Append(event{ID: id, Name: "foo"})
+	}
+	return b.Input()
+}
+
+// dedup returns settings with deduplication token.
+func dedup(token string) []ch.Setting {
+	return []ch.Setting{{
+		Key:       "insert_deduplication_token",
+		Value:     token,
+		Important: true,
+	}}
+}
+
+// settingsClient records settings of inserts.
+type settingsClient struct {
+	settings [][]ch.Setting
+}
+
+func (c *settingsClient) Do(ctx context.Context, q ch.Query) error {
+	c.settings = append(c.settings, q.Settings)
+	return nil
+}
+
+func TestSpool(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Replay", func(t *testing.T) {
+		for _, m := range []compress.Method{compress.LZ4, compress.ZSTD, compress.None} {
+			t.Run(m.String(), func(t *testing.T) {
+				s, err := OpenSpool(SpoolOptions{
+					Dir:         t.TempDir(),
+					Compression: m,
+					Logger:      zaptest.NewLogger(t),
+				})
+				require.NoError(t, err)
+				require.NoError(t, s.Save("events", dedup("a"), eventInput(1, 2)))
+				require.NoError(t, s.Save("events", nil, eventInput(3)))
+				require.Equal(t, 2, s.Len())
+				require.NotZero(t, s.Size())
+
+				client := &recordingClient{}
+				n, err := s.Replay(ctx, client)
+				require.NoError(t, err)
+				require.Equal(t, 2, n)
+				require.Equal(t, []insert{
+					{Body: `INSERT INTO "events" ("id","name") VALUES`, IDs: []uint64{1, 2}, Token: "a"},
+					{Body: `INSERT INTO "events" ("id","name") VALUES`, IDs: []uint64{3}},
+				}, client.Inserts())
+				require.Zero(t, s.Len())
+				require.Zero(t, s.Size())
+
+				entries, err := os.ReadDir(s.opt.Dir)
+				require.NoError(t, err)
+				require.Empty(t, entries)
+			})
+		}
+	})
+	t.Run("ReplayFailure", func(t *testing.T) {
+		s, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+		require.NoError(t, err)
+		require.NoError(t, s.Save("events", dedup("a"), eventInput(1)))
+		require.NoError(t, s.Save("events", dedup("b"), eventInput(2)))
+
+		client := &recordingClient{fail: 1}
+		n, err := s.Replay(ctx, client)
+		require.Error(t, err)
+		require.Zero(t, n)
+		require.Equal(t, 2, s.Len(), "order should be preserved")
+
+		n, err = s.Replay(ctx, client)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		var tokens []string
+		for _, in := range client.Inserts() {
+			tokens = append(tokens, in.Token)
+		}
+		require.Equal(t, []string{"a", "a", "b"}, tokens)
+	})
+	t.Run("NotInferable", func(t *testing.T) {
+		s, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+		require.NoError(t, err)
+		m := proto.NewMap[string, uint64](new(proto.ColStr), new(proto.ColUInt64))
+		m.Append(map[string]uint64{"a": 1})
+		err = s.Save("events", nil, proto.Input{{Name: "m", Data: m}})
+		require.ErrorContains(t, err, `column "m" can't be spooled`)
+		require.Zero(t, s.Len())
+	})
+	t.Run("Limits", func(t *testing.T) {
+		s, err := OpenSpool(SpoolOptions{Dir: t.TempDir(), MaxBatches: 1})
+		require.NoError(t, err)
+		require.NoError(t, s.Save("events", nil, eventInput(1)))
+		require.ErrorIs(t, s.Save("events", nil, eventInput(2)), ErrSpoolFull)
+
+		s, err = OpenSpool(SpoolOptions{Dir: t.TempDir(), MaxBytes: 100, Compression: compress.None})
+		require.NoError(t, err)
+		require.NoError(t, s.Save("events", nil, eventInput(1)))
+		require.ErrorIs(t, s.Save("events", nil, eventInput(1, 2, 3, 4, 5, 6)), ErrSpoolFull)
+		require.Equal(t, 1, s.Len())
+	})
+	t.Run("Recovery", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := OpenSpool(SpoolOptions{Dir: dir, Sync: SyncNever})
+		require.NoError(t, err)
+		require.NoError(t, s.Save("events", dedup("a"), eventInput(1)))
+		require.NoError(t, s.Save("events", dedup("b"), eventInput(2)))
+		require.NoError(t, s.Save("events", dedup("c"), eventInput(3)))
+
+		// Interrupted write.
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000003.tmp"), []byte("CHSP"), 0o600))
+		// Corrupted batch.
+		corrupted := s.path(1, spoolExt)
+		data, err := os.ReadFile(corrupted)
+		require.NoError(t, err)
+		data[len(data)-1] ^= 0xff
+		require.NoError(t, os.WriteFile(corrupted, data, 0o600))
+
+		s, err = OpenSpool(SpoolOptions{Dir: dir, Logger: zaptest.NewLogger(t)})
+		require.NoError(t, err)
+		require.Equal(t, 3, s.Len())
+		require.NoFileExists(t, filepath.Join(dir, "00000000000000000003.tmp"))
+
+		client := &recordingClient{}
+		n, err := s.Replay(ctx, client)
+		var corruptedErr *CorruptedError
+		require.ErrorAs(t, err, &corruptedErr)
+		require.Equal(t, s.path(1, corruptExt), corruptedErr.File)
+		require.Equal(t, 2, n, "corrupted batch should not be counted")
+		require.Zero(t, s.Len())
+		require.Len(t, client.Inserts(), 2)
+		require.Equal(t, "a", client.Inserts()[0].Token)
+		require.Equal(t, "c", client.Inserts()[1].Token)
+		require.FileExists(t, s.path(1, corruptExt))
+
+		// Sequence continues after recovered batches.
+		require.NoError(t, s.Save("events", dedup("d"), eventInput(4)))
+		require.FileExists(t, s.path(3, spoolExt))
+	})
+	t.Run("Settings", func(t *testing.T) {
+		s, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+		require.NoError(t, err)
+		settings := append(dedup("a"), ch.Setting{Key: "async_insert", Value: "1"})
+		require.NoError(t, s.Save("events", settings, eventInput(1)))
+
+		client := &settingsClient{}
+		n, err := s.Replay(ctx, client)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		require.Equal(t, [][]ch.Setting{settings}, client.settings)
+	})
+	t.Run("Undecodable", func(t *testing.T) {
+		s, err := OpenSpool(SpoolOptions{Dir: t.TempDir(), Logger: zaptest.NewLogger(t)})
+		require.NoError(t, err)
+		require.NoError(t, s.Save("events", nil, eventInput(1)))
+		require.NoError(t, s.Save("events", nil, eventInput(2)))
+
+		// Valid header with truncated data.
+		path := s.path(0, spoolExt)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data[:spoolHeaderSize+4], 0o600))
+
+		client := &recordingClient{}
+		n, err := s.Replay(ctx, client)
+		var corrupted *CorruptedError
+		require.ErrorAs(t, err, &corrupted)
+		require.Equal(t, 1, n)
+		require.Len(t, client.Inserts(), 1)
+		require.FileExists(t, s.path(0, corruptExt))
+		require.Zero(t, s.Len())
+		require.Zero(t, s.Size())
+	})
+	t.Run("Validate", func(t *testing.T) {
+		_, err := OpenSpool(SpoolOptions{})
+		require.ErrorContains(t, err, "dir is required")
+	})
+}
+
+func TestWriter_Spool(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	client := &recordingClient{fail: 2}
+	w, err := New(Options[event]{
+		Client:              client,
+		Table:               "events",
+		NewBatch:            newEventBatch,
+		BatchRows:           2,
+		FlushInterval:       time.Hour,
+		MaxRetries:          1,
+		RetryBackoff:        time.Millisecond,
+		Deduplicate:         true,
+		Spool:               s,
+		SpoolReplayInterval: time.Millisecond * 10,
+		OnError: func(err error, input proto.Input) {
+			t.Error("batch should be spooled")
+		},
+		Logger: zaptest.NewLogger(t),
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(ctx, event{ID: 1}))
+	require.NoError(t, w.Write(ctx, event{ID: 2}))
+
+	// Batch is spooled after two failed attempts and then replayed.
+	require.Eventually(t, func() bool {
+		return len(client.Inserts()) == 3 && s.Len() == 0
+	}, time.Second*5, time.Millisecond)
+	require.NoError(t, w.Close(ctx))
+
+	inserts := client.Inserts()
+	for _, in := range inserts {
+		require.Equal(t, []uint64{1, 2}, in.IDs)
+		require.Equal(t, inserts[0].Token, in.Token)
+	}
+}
@@ -0,0 +1,544 @@
+package chbatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/otelch"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// Client performs queries, like *chpool.Pool.
+type Client interface {
+	Do(ctx context.Context, q ch.Query) error
+}
+
+// Batch is columnar buffer of rows.
+type Batch[T any] interface {
+	// Append row to columns.
+	Append(row T)
+	// Input returns columns for insertion.
+	Input() proto.Input
+	// Reset all columns.
+	Reset()
+}
+
+// Backpressure is behavior of Write when flush queue is full.
+type Backpressure byte
+
+const (
+	// Block waits until there is free space in queue.
+	Block Backpressure = iota
+	// Drop discards row, returning ErrDropped.
+	Drop
+)
+
+func (b Backpressure) String() string {
+	switch b {
+	case Block:
+		return "Block"
+	case Drop:
+		return "Drop"
+	default:
+		return "Unknown"
+	}
+}
+
+var (
+	// ErrDropped is returned by Write if row is dropped due to backpressure.
+	ErrDropped = errors.New("queue is full, row dropped")
+	// ErrClosed is returned by Write after Close.
+	ErrClosed = errors.New("writer closed")
+)
+
+// Options for Writer.
+type Options[T any] struct {
+	// Client to perform inserts.
+	Client Client
+	// Table to insert to.
+	Table string
+	// NewBatch returns new empty batch.
+	NewBatch func() Batch[T]
+
+	// BatchRows is number of rows in batch that triggers flush.
+	BatchRows int
+	// FlushInterval is maximum time that row waits in batch before flush.
+	FlushInterval time.Duration
+	// QueueSize is maximum number of full batches waiting for flush,
+	// so at most QueueSize+Flushers+1 batches are in memory.
+	QueueSize int
+	// Flushers is number of parallel flushers.
+	Flushers int
+	// Backpressure is behavior of Write when queue is full.
+	Backpressure Backpressure
+
+	// MaxRetries is maximum number of retries of failed flush.
+	MaxRetries int
+	// RetryBackoff is initial interval between retries.
+	RetryBackoff time.Duration
+	// Deduplicate sets unique insert_deduplication_token for each batch,
+	// so retries of partially succeeded inserts are deduplicated by server.
+	Deduplicate bool
+	// Settings of insert queries.
+	Settings []ch.Setting
+	// OnError is called with input of batch that failed after retries
+	// and was not spooled, before batch is reset.
+	OnError func(err error, input proto.Input)
+
+	// Spool saves batches that failed after retries to disk, so they
+	// are replayed in background. Should be used with Deduplicate, so
+	// partially inserted batches are not duplicated on replay.
+	Spool *Spool
+	// SpoolReplayInterval is interval of spool replay attempts.
+	SpoolReplayInterval time.Duration
+
+	Logger        *zap.Logger
+	MeterProvider metric.MeterProvider
+	// MetricAttributes are added to writer metrics along with table,
+	// so multiple writers can share MeterProvider.
+	MetricAttributes []attribute.KeyValue
+}
+
+// Defaults for Writer.
+const (
+	DefaultBatchRows     = 100_000
+	DefaultFlushInterval = time.Second
+	DefaultQueueSize     = 4
+	DefaultMaxRetries    = 3
+	DefaultRetryBackoff  = time.Millisecond * 100
+
+	DefaultSpoolReplayInterval = time.Second * 10
+)
+
+func (o *Options[T]) setDefaults() {
+	if o.BatchRows == 0 {
+		o.BatchRows = DefaultBatchRows
+	}
+	if o.FlushInterval == 0 {
+		o.FlushInterval = DefaultFlushInterval
+	}
+	if o.QueueSize == 0 {
+		o.QueueSize = DefaultQueueSize
+	}
+	if o.Flushers == 0 {
+		o.Flushers = 1
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = DefaultMaxRetries
+	}
+	if o.RetryBackoff == 0 {
+		o.RetryBackoff = DefaultRetryBackoff
+	}
+	if o.SpoolReplayInterval == 0 {
+		o.SpoolReplayInterval = DefaultSpoolReplayInterval
+	}
+	if o.Logger == nil {
+		o.Logger = zap.NewNop()
+	}
+	if o.MeterProvider == nil {
+		o.MeterProvider = otel.GetMeterProvider()
+	}
+}
+
+type batch[T any] struct {
+	data    Batch[T]
+	rows    int
+	created time.Time
+}
+
+// Writer buffers rows in batches and inserts them in background.
+//
+// Batch is flushed when it has BatchRows rows or after FlushInterval.
+// Writer is safe for concurrent use.
+type Writer[T any] struct {
+	opt Options[T]
+	lg  *zap.Logger
+
+	// lock guards cur and closed, channel is used to respect
+	// context cancellation.
+	lock   chan struct{}
+	cur    *batch[T]
+	closed bool
+
+	queue chan *batch[T]
+	free  chan *batch[T]
+	// pending is number of rows that are not flushed yet.
+	pending atomic.Int64
+
+	// ctx is context of flushes, cancelled if Close deadline is exceeded.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	tickerWg sync.WaitGroup
+	wg       sync.WaitGroup
+
+	metrics      *writerMetrics
+	registration metric.Registration
+}
+
+// New returns new Writer and starts flushers.
+func New[T any](opt Options[T]) (*Writer[T], error) {
+	opt.setDefaults()
+	if opt.Client == nil {
+		return nil, errors.New("client is required")
+	}
+	if opt.Table == "" {
+		return nil, errors.New("table is required")
+	}
+	if opt.NewBatch == nil {
+		return nil, errors.New("new batch func is required")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer[T]{
+		opt:    opt,
+		lg:     opt.Logger,
+		lock:   make(chan struct{}, 1),
+		queue:  make(chan *batch[T], opt.QueueSize),
+		free:   make(chan *batch[T], opt.QueueSize+opt.Flushers+1),
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+	}
+	m, err := newWriterMetrics(opt.MeterProvider.Meter(otelch.Name),
+		append([]attribute.KeyValue{otelch.Table(opt.Table)}, opt.MetricAttributes...),
+	)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "metrics")
+	}
+	w.metrics = m
+	if w.registration, err = w.registerMetrics(); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "metrics")
+	}
+
+	for i := 0; i < opt.Flushers; i++ {
+		w.wg.Add(1)
+		go w.flusher()
+	}
+	w.tickerWg.Add(1)
+	go w.ticker()
+	if opt.Spool != nil {
+		w.tickerWg.Add(1)
+		go w.replayer()
+	}
+
+	return w, nil
+}
+
+func (w *Writer[T]) acquire(ctx context.Context) error {
+	select {
+	case w.lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Writer[T]) release() { <-w.lock }
+
+func (w *Writer[T]) newBatch() *batch[T] {
+	select {
+	case b := <-w.free:
+		return b
+	default:
+		return &batch[T]{data: w.opt.NewBatch()}
+	}
+}
+
+// tryEnqueue sends current batch to queue without blocking, requires lock.
+func (w *Writer[T]) tryEnqueue() bool {
+	select {
+	case w.queue <- w.cur:
+		w.cur = nil
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueue sends current batch to queue, requires lock.
+func (w *Writer[T]) enqueue(ctx context.Context) error {
+	select {
+	case w.queue <- w.cur:
+		w.cur = nil
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Write appends row to batch.
+//
+// If queue is full, Write blocks until row is buffered or returns
+// ErrDropped, depending on Backpressure.
+func (w *Writer[T]) Write(ctx context.Context, row T) error {
+	if err := w.acquire(ctx); err != nil {
+		return err
+	}
+	defer w.release()
+	if w.closed {
+		return ErrClosed
+	}
+	if w.cur != nil && w.cur.rows >= w.opt.BatchRows && !w.tryEnqueue() {
+		// Queue is full.
+		if w.opt.Backpressure == Drop {
+			w.metrics.rowsDropped.Add(ctx, 1, w.metrics.attrs())
+			return ErrDropped
+		}
+		if err := w.enqueue(ctx); err != nil {
+			return err
+		}
+	}
+	if w.cur == nil {
+		w.cur = w.newBatch()
+		w.cur.created = time.Now()
+	}
+	w.cur.data.Append(row)
+	w.cur.rows++
+	w.pending.Inc()
+	if w.cur.rows >= w.opt.BatchRows {
+		w.tryEnqueue()
+	}
+	return nil
+}
+
+// ticker flushes batches that are older than FlushInterval.
+func (w *Writer[T]) ticker() {
+	defer w.tickerWg.Done()
+	interval := w.opt.FlushInterval / 2
+	if interval <= 0 {
+		interval = w.opt.FlushInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-t.C:
+			select {
+			case w.lock <- struct{}{}:
+			case <-w.stop:
+				return
+			}
+			if w.cur != nil && now.Sub(w.cur.created) >= w.opt.FlushInterval {
+				// Queue can be full, retrying on next tick.
+				w.tryEnqueue()
+			}
+			w.release()
+		}
+	}
+}
+
+func (w *Writer[T]) flusher() {
+	defer w.wg.Done()
+	for b := range w.queue {
+		w.flush(b)
+		w.pending.Sub(int64(b.rows))
+		b.data.Reset()
+		b.rows = 0
+		select {
+		case w.free <- b:
+		default:
+		}
+	}
+}
+
+func (w *Writer[T]) flush(b *batch[T]) {
+	var (
+		ctx      = w.ctx
+		settings = w.opt.Settings
+	)
+	if w.opt.Deduplicate {
+		// Same token for all retries of batch.
+		settings = append(append([]ch.Setting(nil), settings...), ch.Setting{
+			Key:       "insert_deduplication_token",
+			Value:     uuid.New().String(),
+			Important: true,
+		})
+	}
+	input := b.data.Input()
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = w.opt.RetryBackoff
+
+	var attempt int
+	err := backoff.Retry(func() error {
+		attempt++
+		if attempt > 1 {
+			w.metrics.retries.Add(ctx, 1, w.metrics.attrs())
+		}
+		start := time.Now()
+		err := w.opt.Client.Do(ctx, ch.Query{
+			Body:     input.Into(w.opt.Table),
+			Input:    input,
+			Settings: settings,
+		})
+		w.metrics.flushDuration.Record(ctx, time.Since(start).Seconds(),
+			w.metrics.attrs(otelch.Outcome(outcome(ctx, err))),
+		)
+		if err != nil {
+			w.lg.Warn("Flush failed",
+				zap.Int("rows", b.rows),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+		}
+		if ctx.Err() != nil {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, backoff.WithContext(backoff.WithMaxRetries(bo, uint64(w.opt.MaxRetries)), ctx))
+
+	w.metrics.rows.Add(context.Background(), int64(b.rows),
+		w.metrics.attrs(otelch.Outcome(outcome(ctx, err))),
+	)
+	if err == nil {
+		return
+	}
+	if w.opt.Spool != nil {
+		spoolErr := w.opt.Spool.Save(w.opt.Table, settings, input)
+		if spoolErr == nil {
+			w.lg.Warn("Batch spooled", zap.Int("rows", b.rows), zap.Error(err))
+			return
+		}
+		w.lg.Error("Failed to spool batch", zap.Error(spoolErr))
+	}
+	w.reportError(b, input, err)
+}
+
+// reportError reports batch that failed with err to OnError or logs it.
+func (w *Writer[T]) reportError(b *batch[T], input proto.Input, err error) {
+	if w.opt.OnError != nil {
+		w.opt.OnError(err, input)
+		return
+	}
+	w.lg.Error("Batch dropped", zap.Int("rows", b.rows), zap.Error(err))
+}
+
+// drop reports batch that was not flushed because of err.
+func (w *Writer[T]) drop(b *batch[T], err error) {
+	w.metrics.rows.Add(context.Background(), int64(b.rows),
+		w.metrics.attrs(otelch.Outcome(otelch.OutcomeCanceled)),
+	)
+	w.reportError(b, b.data.Input(), err)
+	w.pending.Sub(int64(b.rows))
+}
+
+// replayer periodically replays spooled batches.
+func (w *Writer[T]) replayer() {
+	defer w.tickerWg.Done()
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+
+	t := time.NewTicker(w.opt.SpoolReplayInterval)
+	defer t.Stop()
+	for {
+		if w.opt.Spool.Len() > 0 {
+			n, err := w.opt.Spool.Replay(ctx, w.opt.Client)
+			if n > 0 {
+				w.lg.Info("Replayed spooled batches", zap.Int("batches", n))
+			}
+			for _, e := range multierr.Errors(err) {
+				var corrupted *CorruptedError
+				if errors.As(e, &corrupted) {
+					w.metrics.spoolCorrupted.Add(ctx, 1, w.metrics.attrs())
+				}
+			}
+			if err != nil && ctx.Err() == nil {
+				w.lg.Warn("Spool replay failed", zap.Error(err))
+			}
+		}
+		select {
+		case <-w.stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func outcome(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return otelch.OutcomeOK
+	case ctx.Err() != nil:
+		return otelch.OutcomeCanceled
+	default:
+		return otelch.OutcomeError
+	}
+}
+
+// Pending returns number of rows that are not flushed yet.
+func (w *Writer[T]) Pending() int {
+	return int(w.pending.Load())
+}
+
+// Close flushes buffered rows and stops writer, waiting for flushes
+// to complete.
+//
+// If ctx is done before that, flushes are cancelled and ctx error
+// is returned.
+func (w *Writer[T]) Close(ctx context.Context) error {
+	if err := w.acquire(ctx); err != nil {
+		return err
+	}
+	if w.closed {
+		w.release()
+		return ErrClosed
+	}
+	w.closed = true
+	close(w.stop)
+	var (
+		err     error
+		dropped *batch[T]
+	)
+	if w.cur != nil {
+		if err = w.enqueue(ctx); err != nil {
+			// Queue is full and ctx is done.
+			dropped, w.cur = w.cur, nil
+		}
+	}
+	close(w.queue)
+	w.release()
+	if dropped != nil {
+		w.drop(dropped, err)
+	}
+	w.tickerWg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		w.cancel()
+		<-done
+	}
+	w.cancel()
+	if w.registration != nil {
+		_ = w.registration.Unregister()
+	}
+	if err != nil {
+		return errors.Wrap(err, "flush")
+	}
+	return nil
+}
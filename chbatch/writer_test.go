@@ -0,0 +1,419 @@
+package chbatch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/cht"
+	"github.com/ClickHouse/ch-go/otelch"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+type event struct {
+	ID   uint64
+	Name string
+}
+
+type eventBatch struct {
+	id   proto.ColUInt64
+	name proto.ColStr
+}
+
+func (b *eventBatch) Append(e event) {
+	b.id.Append(e.ID)
+	b.name.Append(e.Name)
+}
+
+func (b *eventBatch) Input() proto.Input {
+	return proto.Input{
+		{Name: "id", Data: &b.id},
+		{Name: "name", Data: &b.name},
+	}
+}
+
+func (b *eventBatch) Reset() {
+	b.id.Reset()
+	b.name.Reset()
+}
+
+func newEventBatch() Batch[event] { return &eventBatch{} }
+
+type insert struct {
+	Body  string
+	IDs   []uint64
+	Token string
+}
+
+// recordingClient records inserted ids.
+type recordingClient struct {
+	mux     sync.Mutex
+	inserts []insert
+	// fail is number of failed attempts before success.
+	fail int
+	// hold blocks inserts until closed, if set.
+	hold chan struct{}
+}
+
+func (c *recordingClient) Do(ctx context.Context, q ch.Query) error {
+	if c.hold != nil {
+		select {
+		case <-c.hold:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	in := insert{Body: q.Body}
+	for _, s := range q.Settings {
+		if s.Key == "insert_deduplication_token" {
+			in.Token = s.Value
+		}
+	}
+	in.IDs = append(in.IDs, *q.Input[0].Data.(*proto.ColUInt64)...)
+	c.inserts = append(c.inserts, in)
+	if c.fail > 0 {
+		c.fail--
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (c *recordingClient) Inserts() []insert {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return append([]insert(nil), c.inserts...)
+}
+
+func TestWriter(t *testing.T) {
+	ctx := context.Background()
+	t.Run("BatchRows", func(t *testing.T) {
+		client := &recordingClient{}
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			BatchRows:     3,
+			FlushInterval: time.Hour,
+			Logger:        zaptest.NewLogger(t),
+		})
+		require.NoError(t, err)
+		for i := 0; i < 7; i++ {
+			require.NoError(t, w.Write(ctx, event{ID: uint64(i), Name: "foo"}))
+		}
+		require.Eventually(t, func() bool {
+			return len(client.Inserts()) == 2
+		}, time.Second, time.Millisecond)
+		require.Equal(t, 1, w.Pending())
+
+		require.NoError(t, w.Close(ctx))
+		require.Equal(t, []insert{
+			{Body: `INSERT INTO "events" ("id","name") VALUES`, IDs: []uint64{0, 1, 2}},
+			{Body: `INSERT INTO "events" ("id","name") VALUES`, IDs: []uint64{3, 4, 5}},
+			{Body: `INSERT INTO "events" ("id","name") VALUES`, IDs: []uint64{6}},
+		}, client.Inserts())
+		require.Zero(t, w.Pending())
+		require.ErrorIs(t, w.Write(ctx, event{}), ErrClosed)
+		require.ErrorIs(t, w.Close(ctx), ErrClosed)
+	})
+	t.Run("FlushInterval", func(t *testing.T) {
+		client := &recordingClient{}
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			FlushInterval: time.Millisecond * 10,
+		})
+		require.NoError(t, err)
+		defer func() { require.NoError(t, w.Close(ctx)) }()
+
+		require.NoError(t, w.Write(ctx, event{ID: 1}))
+		require.Eventually(t, func() bool {
+			return len(client.Inserts()) == 1
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("Drop", func(t *testing.T) {
+		client := &recordingClient{hold: make(chan struct{})}
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			BatchRows:     1,
+			QueueSize:     1,
+			FlushInterval: time.Hour,
+			Backpressure:  Drop,
+		})
+		require.NoError(t, err)
+
+		// First batch is taken by flusher, second is in queue,
+		// third is current one.
+		require.NoError(t, w.Write(ctx, event{ID: 1}))
+		require.Eventually(t, func() bool {
+			return len(w.queue) == 0
+		}, time.Second, time.Millisecond)
+		require.NoError(t, w.Write(ctx, event{ID: 2}))
+		require.NoError(t, w.Write(ctx, event{ID: 3}))
+		require.ErrorIs(t, w.Write(ctx, event{ID: 4}), ErrDropped)
+		require.Equal(t, 3, w.Pending())
+
+		close(client.hold)
+		require.NoError(t, w.Close(ctx))
+		var ids []uint64
+		for _, in := range client.Inserts() {
+			ids = append(ids, in.IDs...)
+		}
+		require.Equal(t, []uint64{1, 2, 3}, ids)
+	})
+	t.Run("Block", func(t *testing.T) {
+		client := &recordingClient{hold: make(chan struct{})}
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			BatchRows:     1,
+			QueueSize:     1,
+			FlushInterval: time.Hour,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, w.Write(ctx, event{ID: 1}))
+		require.Eventually(t, func() bool {
+			return len(w.queue) == 0
+		}, time.Second, time.Millisecond)
+		require.NoError(t, w.Write(ctx, event{ID: 2}))
+		require.NoError(t, w.Write(ctx, event{ID: 3}))
+
+		writeCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+		defer cancel()
+		require.ErrorIs(t, w.Write(writeCtx, event{ID: 4}), context.DeadlineExceeded)
+
+		done := make(chan error)
+		go func() { done <- w.Write(ctx, event{ID: 5}) }()
+		close(client.hold)
+		require.NoError(t, <-done)
+
+		require.NoError(t, w.Close(ctx))
+		var ids []uint64
+		for _, in := range client.Inserts() {
+			ids = append(ids, in.IDs...)
+		}
+		require.Equal(t, []uint64{1, 2, 3, 5}, ids)
+	})
+	t.Run("Retry", func(t *testing.T) {
+		client := &recordingClient{fail: 2}
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			RetryBackoff:  time.Millisecond,
+			FlushInterval: time.Hour,
+			Deduplicate:   true,
+			Logger:        zaptest.NewLogger(t),
+		})
+		require.NoError(t, err)
+		require.NoError(t, w.Write(ctx, event{ID: 1}))
+		require.NoError(t, w.Write(ctx, event{ID: 2}))
+		require.NoError(t, w.Write(ctx, event{ID: 3}))
+		require.NoError(t, w.Close(ctx))
+
+		inserts := client.Inserts()
+		require.Len(t, inserts, 3)
+		for _, in := range inserts {
+			require.NotEmpty(t, in.Token)
+			require.Equal(t, inserts[0].Token, in.Token, "token should be same for retries")
+			require.Equal(t, []uint64{1, 2, 3}, in.IDs)
+		}
+	})
+	t.Run("OnError", func(t *testing.T) {
+		var (
+			client = &recordingClient{fail: 10}
+			failed []uint64
+		)
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			MaxRetries:    1,
+			RetryBackoff:  time.Millisecond,
+			FlushInterval: time.Hour,
+			OnError: func(err error, input proto.Input) {
+				failed = append(failed, *input[0].Data.(*proto.ColUInt64)...)
+			},
+		})
+		require.NoError(t, err)
+		require.NoError(t, w.Write(ctx, event{ID: 1}))
+		require.NoError(t, w.Close(ctx))
+		require.Len(t, client.Inserts(), 2)
+		require.Equal(t, []uint64{1}, failed)
+	})
+	t.Run("CloseTimeout", func(t *testing.T) {
+		client := &recordingClient{hold: make(chan struct{})}
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			FlushInterval: time.Hour,
+		})
+		require.NoError(t, err)
+		require.NoError(t, w.Write(ctx, event{ID: 1}))
+
+		closeCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+		defer cancel()
+		require.ErrorIs(t, w.Close(closeCtx), context.DeadlineExceeded)
+	})
+	t.Run("CloseTimeoutQueueFull", func(t *testing.T) {
+		var (
+			client = &recordingClient{hold: make(chan struct{})}
+			mux    sync.Mutex
+			failed []uint64
+		)
+		w, err := New(Options[event]{
+			Client:        client,
+			Table:         "events",
+			NewBatch:      newEventBatch,
+			BatchRows:     1,
+			QueueSize:     1,
+			FlushInterval: time.Hour,
+			OnError: func(err error, input proto.Input) {
+				mux.Lock()
+				defer mux.Unlock()
+				failed = append(failed, *input[0].Data.(*proto.ColUInt64)...)
+			},
+		})
+		require.NoError(t, err)
+
+		// First batch is taken by flusher, second is in queue,
+		// third is current one and can't be enqueued on Close.
+		require.NoError(t, w.Write(ctx, event{ID: 1}))
+		require.Eventually(t, func() bool {
+			return len(w.queue) == 0
+		}, time.Second, time.Millisecond)
+		require.NoError(t, w.Write(ctx, event{ID: 2}))
+		require.NoError(t, w.Write(ctx, event{ID: 3}))
+
+		closeCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+		defer cancel()
+		require.ErrorIs(t, w.Close(closeCtx), context.DeadlineExceeded)
+		require.Zero(t, w.Pending())
+
+		mux.Lock()
+		defer mux.Unlock()
+		require.Contains(t, failed, uint64(3))
+	})
+	t.Run("Validate", func(t *testing.T) {
+		_, err := New(Options[event]{Table: "events", NewBatch: newEventBatch})
+		require.ErrorContains(t, err, "client is required")
+		_, err = New(Options[event]{Client: &recordingClient{}, NewBatch: newEventBatch})
+		require.ErrorContains(t, err, "table is required")
+		_, err = New(Options[event]{Client: &recordingClient{}, Table: "events"})
+		require.ErrorContains(t, err, "new batch func is required")
+	})
+}
+
+func TestWriter_Metrics(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	client := &recordingClient{fail: 1}
+	w, err := New(Options[event]{
+		Client:        client,
+		Table:         "events",
+		NewBatch:      newEventBatch,
+		BatchRows:     2,
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+		MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.Write(ctx, event{ID: uint64(i)}))
+	}
+	require.Eventually(t, func() bool {
+		return w.Pending() == 1
+	}, time.Second, time.Millisecond)
+
+	collect := func() map[string]int64 {
+		var rm metricdata.ResourceMetrics
+		require.NoError(t, reader.Collect(ctx, &rm))
+		got := map[string]int64{}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				switch data := m.Data.(type) {
+				case metricdata.Gauge[int64]:
+					got[m.Name] = data.DataPoints[0].Value
+					table, _ := data.DataPoints[0].Attributes.Value(otelch.TableKey)
+					assert.Equal(t, "events", table.AsString(), m.Name)
+				case metricdata.Sum[int64]:
+					got[m.Name] = data.DataPoints[0].Value
+					table, _ := data.DataPoints[0].Attributes.Value(otelch.TableKey)
+					assert.Equal(t, "events", table.AsString(), m.Name)
+				case metricdata.Histogram[float64]:
+					for _, dp := range data.DataPoints {
+						got[m.Name] += int64(dp.Count)
+					}
+				}
+			}
+		}
+		return got
+	}
+	got := collect()
+	assert.Equal(t, int64(1), got[otelch.MetricBatchQueueRows])
+	assert.Equal(t, int64(0), got[otelch.MetricBatchQueueBatches])
+	assert.Equal(t, int64(2), got[otelch.MetricBatchRows])
+	assert.Equal(t, int64(1), got[otelch.MetricBatchRetries])
+	assert.Equal(t, int64(2), got[otelch.MetricBatchFlushDuration])
+
+	require.NoError(t, w.Close(ctx))
+	got = collect()
+	assert.NotContains(t, got, otelch.MetricBatchQueueRows, "should be unregistered")
+	assert.Equal(t, int64(3), got[otelch.MetricBatchRows])
+}
+
+func TestWriter_Insert(t *testing.T) {
+	cht.Skip(t)
+	t.Parallel()
+	var (
+		ctx    = context.Background()
+		server = cht.New(t, cht.WithLog(zaptest.NewLogger(t).Named("ch")))
+	)
+	client, err := ch.Dial(ctx, ch.Options{
+		Address: server.TCP,
+		Logger:  zaptest.NewLogger(t).Named("usr"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	require.NoError(t, client.Do(ctx, ch.Query{
+		Body: "CREATE TABLE events (id UInt64, name String) ENGINE = MergeTree ORDER BY id",
+	}))
+
+	w, err := New(Options[event]{
+		Client:      client,
+		Table:       "events",
+		NewBatch:    newEventBatch,
+		BatchRows:   100,
+		Deduplicate: true,
+		Logger:      zaptest.NewLogger(t),
+	})
+	require.NoError(t, err)
+	const rows = 1000
+	for i := 0; i < rows; i++ {
+		require.NoError(t, w.Write(ctx, event{ID: uint64(i), Name: "foo"}))
+	}
+	require.NoError(t, w.Close(ctx))
+
+	var count proto.ColUInt64
+	require.NoError(t, client.Do(ctx, ch.Query{
+		Body:   "SELECT count() AS count FROM events",
+		Result: proto.Results{{Name: "count", Data: &count}},
+	}))
+	require.Equal(t, uint64(rows), count[0])
+}
@@ -0,0 +1,380 @@
+package chcluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/chpool"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// Options for Cluster.
+type Options struct {
+	// ClientOptions are used for connections to replicas and seeds,
+	// Address is overridden.
+	ClientOptions ch.Options
+	// PoolOptions are used for pool of each replica, ClientOptions
+	// field is overridden.
+	PoolOptions chpool.Options
+
+	// Cluster name for topology discovery from system.clusters.
+	Cluster string
+	// Seeds are addresses of servers for topology discovery, tried in order.
+	//
+	// Defaults to ClientOptions.Address.
+	Seeds []string
+	// Topology is static topology of cluster, discovery is not
+	// performed if set.
+	Topology *Topology
+
+	// Routing of queries to replicas, default is LeastConns.
+	Routing Routing
+	// IsLocal reports whether replica is local for LocalFirst routing,
+	// defaults to loopback or host name check.
+	IsLocal func(r Replica) bool
+	// MaxReplicaDelay enables lag-aware routing, so replicas with
+	// replication delay from system.replicas greater than MaxReplicaDelay
+	// are used only if no other replicas are available.
+	//
+	// Zero disables delay checks.
+	MaxReplicaDelay time.Duration
+
+	// HealthCheckPeriod is period of replica health checks.
+	HealthCheckPeriod time.Duration
+	// HealthCheckTimeout is timeout of single replica health check.
+	HealthCheckTimeout time.Duration
+
+	Logger *zap.Logger
+}
+
+// Defaults for cluster.
+const (
+	DefaultHealthCheckPeriod  = time.Second * 10
+	DefaultHealthCheckTimeout = time.Second * 5
+)
+
+func (o *Options) setDefaults() {
+	if len(o.Seeds) == 0 && o.ClientOptions.Address != "" {
+		o.Seeds = []string{o.ClientOptions.Address}
+	}
+	if o.IsLocal == nil {
+		o.IsLocal = defaultIsLocal()
+	}
+	if o.HealthCheckPeriod == 0 {
+		o.HealthCheckPeriod = DefaultHealthCheckPeriod
+	}
+	if o.HealthCheckTimeout == 0 {
+		o.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+	if o.Logger == nil {
+		o.Logger = zap.NewNop()
+	}
+}
+
+type replica struct {
+	Replica
+	shard int
+	local bool
+	pool  *chpool.Pool
+
+	healthy atomic.Bool
+	delay   atomic.Duration
+	err     atomic.Error
+}
+
+func (r *replica) acquired() int32 {
+	if r.pool == nil {
+		return 0
+	}
+	return r.pool.Stat().AcquiredResources()
+}
+
+// ReplicaStatus is health and load of replica.
+type ReplicaStatus struct {
+	Shard   int
+	Replica Replica
+	Local   bool
+	Healthy bool
+	// Delay is replication delay, if MaxReplicaDelay is set.
+	Delay time.Duration
+	// Err is error of last health check.
+	Err      error
+	Acquired int32
+	Total    int32
+}
+
+// Cluster routes queries to replicas of cluster shards.
+type Cluster struct {
+	opt      Options
+	lg       *zap.Logger
+	topology Topology
+	replicas []*replica
+	shards   map[int][]*replica
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New returns new Cluster, discovering topology if Options.Topology
+// is not set.
+func New(ctx context.Context, opt Options) (*Cluster, error) {
+	opt.setDefaults()
+	var topology Topology
+	if opt.Topology != nil {
+		topology = *opt.Topology
+	} else {
+		t, err := discover(ctx, opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "discover")
+		}
+		topology = t
+	}
+	topology = topology.normalize(opt.Topology != nil)
+	if err := topology.Validate(); err != nil {
+		return nil, errors.Wrap(err, "topology")
+	}
+
+	c := &Cluster{
+		opt:       opt,
+		lg:        opt.Logger,
+		topology:  topology,
+		shards:    map[int][]*replica{},
+		closeChan: make(chan struct{}),
+	}
+	for _, s := range topology.Shards {
+		for _, r := range s.Replicas {
+			poolOptions := opt.PoolOptions
+			poolOptions.ClientOptions = opt.ClientOptions
+			poolOptions.ClientOptions.Address = r.Addr()
+			if lg := poolOptions.ClientOptions.Logger; lg != nil {
+				poolOptions.ClientOptions.Logger = lg.With(zap.String("replica", r.Addr()))
+			}
+			pool, err := chpool.New(ctx, poolOptions)
+			if err != nil {
+				c.Close()
+				return nil, errors.Wrapf(err, "pool %s", r.Addr())
+			}
+			rep := &replica{
+				Replica: r,
+				shard:   s.Num,
+				local:   opt.IsLocal(r),
+				pool:    pool,
+			}
+			rep.healthy.Store(true)
+			c.replicas = append(c.replicas, rep)
+			c.shards[s.Num] = append(c.shards[s.Num], rep)
+		}
+	}
+
+	c.checkHealth(ctx)
+	c.wg.Add(1)
+	go c.backgroundHealthCheck()
+
+	return c, nil
+}
+
+// discover topology using seeds.
+func discover(ctx context.Context, opt Options) (Topology, error) {
+	if len(opt.Seeds) == 0 {
+		return Topology{}, errors.New("no seeds")
+	}
+	if opt.Cluster == "" {
+		return Topology{}, errors.New("cluster name is required for discovery")
+	}
+	var errs error
+	for _, addr := range opt.Seeds {
+		t, err := func() (Topology, error) {
+			clientOptions := opt.ClientOptions
+			clientOptions.Address = addr
+			client, err := ch.Dial(ctx, clientOptions)
+			if err != nil {
+				return Topology{}, errors.Wrap(err, "dial")
+			}
+			defer func() { _ = client.Close() }()
+			return Discover(ctx, client, opt.Cluster)
+		}()
+		if err == nil {
+			return t, nil
+		}
+		if ctx.Err() != nil {
+			return Topology{}, ctx.Err()
+		}
+		errs = multierr.Append(errs, errors.Wrapf(err, "seed %s", addr))
+	}
+	return Topology{}, errs
+}
+
+// Topology of cluster.
+func (c *Cluster) Topology() Topology {
+	return c.topology
+}
+
+// Status returns status of all replicas.
+func (c *Cluster) Status() []ReplicaStatus {
+	out := make([]ReplicaStatus, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		stat := r.pool.Stat()
+		out = append(out, ReplicaStatus{
+			Shard:    r.shard,
+			Replica:  r.Replica,
+			Local:    r.local,
+			Healthy:  r.healthy.Load(),
+			Delay:    r.delay.Load(),
+			Err:      r.err.Load(),
+			Acquired: stat.AcquiredResources(),
+			Total:    stat.TotalResources(),
+		})
+	}
+	return out
+}
+
+// candidates returns replicas of shard in order of preference, or
+// replicas of all shards if shard is zero.
+func (c *Cluster) candidates(shard int) ([]*replica, error) {
+	var list []*replica
+	if shard == 0 {
+		list = append(list, c.replicas...)
+	} else {
+		replicas, ok := c.shards[shard]
+		if !ok {
+			return nil, errors.Errorf("shard %d not found", shard)
+		}
+		list = append(list, replicas...)
+	}
+	sortReplicas(list, c.opt.Routing, c.opt.MaxReplicaDelay)
+	return list, nil
+}
+
+// Acquire connection to replica of shard, or to any replica if shard is zero.
+//
+// Replicas are tried in order of preference until connection is acquired,
+// replicas that failed are marked as unhealthy.
+func (c *Cluster) Acquire(ctx context.Context, shard int) (*chpool.Client, error) {
+	list, err := c.candidates(shard)
+	if err != nil {
+		return nil, err
+	}
+	var errs error
+	for _, r := range list {
+		client, err := r.pool.Acquire(ctx)
+		if err == nil {
+			return client, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.lg.Warn("Failed to acquire connection",
+			zap.Int("shard", r.shard),
+			zap.String("replica", r.Addr()),
+			zap.Error(err),
+		)
+		r.healthy.Store(false)
+		r.err.Store(err)
+		errs = multierr.Append(errs, errors.Wrapf(err, "replica %s", r.Addr()))
+	}
+	return nil, errors.Wrap(errs, "no replicas available")
+}
+
+// Do performs query on any replica, e.g. query to Distributed table.
+func (c *Cluster) Do(ctx context.Context, q ch.Query) error {
+	return c.DoShard(ctx, 0, q)
+}
+
+// DoShard performs query on replica of shard.
+//
+// Query is not retried on other replica after it was sent.
+func (c *Cluster) DoShard(ctx context.Context, shard int, q ch.Query) error {
+	client, err := c.Acquire(ctx, shard)
+	if err != nil {
+		return errors.Wrap(err, "acquire")
+	}
+	defer client.Release()
+
+	return client.Do(ctx, q)
+}
+
+func (c *Cluster) backgroundHealthCheck() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.opt.HealthCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closeChan:
+			return
+		case <-ticker.C:
+			c.checkHealth(context.Background())
+		}
+	}
+}
+
+// checkHealth checks all replicas concurrently.
+func (c *Cluster) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range c.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			c.checkReplica(ctx, r)
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (c *Cluster) checkReplica(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, c.opt.HealthCheckTimeout)
+	defer cancel()
+
+	err := r.pool.Ping(ctx)
+	if err == nil && c.opt.MaxReplicaDelay > 0 {
+		var delay time.Duration
+		delay, err = replicaDelay(ctx, r.pool)
+		if err == nil {
+			r.delay.Store(delay)
+		}
+	}
+	if healthy := err == nil; healthy != r.healthy.Load() {
+		c.lg.Info("Replica health changed",
+			zap.Int("shard", r.shard),
+			zap.String("replica", r.Addr()),
+			zap.Bool("healthy", healthy),
+			zap.Error(err),
+		)
+	}
+	r.healthy.Store(err == nil)
+	r.err.Store(err)
+}
+
+// replicaDelay returns maximum replication delay of replicated tables.
+func replicaDelay(ctx context.Context, p *chpool.Pool) (time.Duration, error) {
+	var delay proto.ColUInt64
+	if err := p.Do(ctx, ch.Query{
+		Body:   "SELECT toUInt64(max(absolute_delay)) AS delay FROM system.replicas",
+		Result: proto.Results{{Name: "delay", Data: &delay}},
+	}); err != nil {
+		return 0, errors.Wrap(err, "replicas delay")
+	}
+	if len(delay) == 0 {
+		return 0, nil
+	}
+	return time.Duration(delay[0]) * time.Second, nil
+}
+
+// Close all pools.
+func (c *Cluster) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+		c.wg.Wait()
+		for _, r := range c.replicas {
+			r.pool.Close()
+		}
+	})
+}
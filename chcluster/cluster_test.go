@@ -0,0 +1,252 @@
+package chcluster
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/cht"
+	"github.com/ClickHouse/ch-go/internal/chtest"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func testServer(t *testing.T) (host string, port int) {
+	t.Helper()
+	addr := chtest.Server(t, ch.ServerOptions{})
+	return addr.IP.String(), addr.Port
+}
+
+// closedPort returns port that is not listened.
+func closedPort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+	return port
+}
+
+func TestTopology(t *testing.T) {
+	require.Error(t, Topology{}.Validate())
+	require.Error(t, Topology{Shards: []Shard{{Num: 1}}}.Validate())
+	require.Error(t, Topology{Shards: []Shard{
+		{Num: 1, Replicas: []Replica{{Host: "a"}}},
+		{Num: 1, Replicas: []Replica{{Host: "b"}}},
+	}}.Validate())
+
+	topology := Topology{Shards: []Shard{
+		{Num: 3, Weight: 2, Replicas: []Replica{{Host: "c", Port: 9000}}},
+		{Replicas: []Replica{{Num: 2, Host: "b"}, {Num: 1, Host: "a"}}},
+	}}.normalize(true)
+	require.NoError(t, topology.Validate())
+	require.Equal(t, Topology{Shards: []Shard{
+		{Num: 2, Weight: 1, Replicas: []Replica{{Num: 1, Host: "a"}, {Num: 2, Host: "b"}}},
+		{Num: 3, Weight: 2, Replicas: []Replica{{Num: 1, Host: "c", Port: 9000}}},
+	}}, topology, "shard without number is numbered by position")
+	require.Equal(t, "c:9000", topology.Shards[1].Replicas[0].Addr())
+
+	discovered := Topology{Shards: []Shard{
+		{Num: 1, Weight: 0, Replicas: []Replica{{Num: 1, Host: "a"}}},
+		{Num: 2, Weight: 3, Replicas: []Replica{{Num: 1, Host: "b"}}},
+	}}.normalize(false)
+	require.Equal(t, 0, discovered.Shards[0].Weight, "zero weight of discovered shard is kept")
+	require.Equal(t, 3, discovered.Shards[1].Weight)
+}
+
+func TestSortReplicas(t *testing.T) {
+	newReplica := func(num, priority int, local, healthy bool, delay time.Duration) *replica {
+		r := &replica{
+			Replica: Replica{Num: num, Priority: priority},
+			shard:   1,
+			local:   local,
+		}
+		r.healthy.Store(healthy)
+		r.delay.Store(delay)
+		return r
+	}
+	nums := func(list []*replica) []int {
+		var out []int
+		for _, r := range list {
+			out = append(out, r.Num)
+		}
+		return out
+	}
+	list := []*replica{
+		newReplica(1, 2, false, true, 0),
+		newReplica(2, 1, false, false, 0),
+		newReplica(3, 3, true, true, time.Minute),
+		newReplica(4, 1, true, true, 0),
+	}
+
+	sortReplicas(list, InOrder, 0)
+	require.Equal(t, []int{4, 1, 3, 2}, nums(list), "unhealthy last")
+
+	sortReplicas(list, InOrder, time.Second)
+	require.Equal(t, []int{4, 1, 3, 2}, nums(list), "lagging after healthy")
+
+	sortReplicas(list, LocalFirst, time.Second)
+	require.Equal(t, []int{4, 1, 3, 2}, nums(list), "lagging after not lagging")
+
+	for i := 0; i < 10; i++ {
+		sortReplicas(list, LocalFirst, 0)
+		require.ElementsMatch(t, []int{3, 4}, nums(list)[:2])
+		require.Equal(t, []int{1, 2}, nums(list)[2:])
+	}
+
+	sortReplicas(list, LeastConns, 0)
+	require.Equal(t, 2, list[3].Num, "unhealthy last")
+}
+
+func TestRouting_String(t *testing.T) {
+	for _, r := range []Routing{LeastConns, LocalFirst, InOrder} {
+		require.NotContains(t, r.String(), "Routing(")
+	}
+	require.Equal(t, "Routing(10)", Routing(10).String())
+}
+
+func TestCluster_Static(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx        = context.Background()
+		host, a    = testServer(t)
+		_, b       = testServer(t)
+		brokenPort = closedPort(t)
+	)
+	c, err := New(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:      zaptest.NewLogger(t).Named("usr"),
+			DialTimeout: time.Second,
+		},
+		Logger: zaptest.NewLogger(t).Named("cluster"),
+		Topology: &Topology{Shards: []Shard{
+			{Replicas: []Replica{{Host: host, Port: brokenPort}, {Host: host, Port: a}}},
+			{Replicas: []Replica{{Host: host, Port: b}}},
+		}},
+		Routing: InOrder,
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	status := c.Status()
+	require.Len(t, status, 3)
+	require.False(t, status[0].Healthy, "closed port should be unhealthy")
+	require.Error(t, status[0].Err)
+	require.True(t, status[1].Healthy)
+	require.True(t, status[2].Healthy)
+	require.True(t, status[1].Local, "loopback is local")
+
+	for _, shard := range []int{0, 1, 2} {
+		require.NoError(t, c.DoShard(ctx, shard, ch.Query{Body: "SELECT 1"}), "shard %d", shard)
+	}
+	require.NoError(t, c.Do(ctx, ch.Query{Body: "SELECT 1"}))
+	require.ErrorContains(t, c.DoShard(ctx, 3, ch.Query{Body: "SELECT 1"}), "shard 3 not found")
+
+	conn, err := c.Acquire(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), c.Status()[1].Acquired, "healthy replica should be acquired")
+	conn.Release()
+}
+
+func TestCluster_Failover(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx     = context.Background()
+		host, a = testServer(t)
+		broken  = closedPort(t)
+	)
+	c, err := New(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:      zaptest.NewLogger(t).Named("usr"),
+			DialTimeout: time.Second,
+		},
+		Topology: &Topology{Shards: []Shard{
+			{Replicas: []Replica{{Host: host, Port: broken}, {Host: host, Port: a}}},
+		}},
+		Routing: InOrder,
+		// No health checks during test.
+		HealthCheckPeriod: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	// Pretend that broken replica is healthy, so it is tried first.
+	c.replicas[0].healthy.Store(true)
+	require.NoError(t, c.Do(ctx, ch.Query{Body: "SELECT 1"}))
+	require.False(t, c.Status()[0].Healthy, "should be marked as unhealthy")
+}
+
+func TestNew_Discover(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	host, port := testServer(t)
+	_, err := New(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger: zaptest.NewLogger(t).Named("usr"),
+		},
+		Seeds:   []string{net.JoinHostPort(host, strconv.Itoa(closedPort(t))), net.JoinHostPort(host, strconv.Itoa(port))},
+		Cluster: "nexus",
+	})
+	require.ErrorContains(t, err, `cluster "nexus" not found`)
+
+	_, err = New(ctx, Options{ClientOptions: ch.Options{Address: "127.0.0.1:9000"}})
+	require.ErrorContains(t, err, "cluster name is required")
+}
+
+func TestCluster_Discover(t *testing.T) {
+	cht.Skip(t)
+	t.Parallel()
+	const host = "127.0.0.1"
+	var (
+		ports    = cht.Ports(t, 2)
+		clusters = cht.Clusters{
+			"nexus": cht.Cluster{
+				Shards: []cht.Shard{
+					{Replicas: []cht.Replica{{Host: host, Port: ports[0]}}},
+					{Replicas: []cht.Replica{{Host: host, Port: ports[1]}}},
+				},
+			},
+		}
+		lg = zaptest.NewLogger(t)
+	)
+	servers := cht.Many(t,
+		cht.With(cht.WithClusters(clusters), cht.WithTCP(ports[0]), cht.WithLog(lg.Named("alpha"))),
+		cht.With(cht.WithClusters(clusters), cht.WithTCP(ports[1]), cht.WithLog(lg.Named("beta"))),
+	)
+
+	ctx := context.Background()
+	c, err := New(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:  lg.Named("usr"),
+			Address: servers[1].TCP,
+		},
+		Logger:          lg.Named("cluster"),
+		Cluster:         "nexus",
+		MaxReplicaDelay: time.Minute,
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	topology := c.Topology()
+	require.Len(t, topology.Shards, 2)
+	for i, s := range topology.Shards {
+		require.Equal(t, i+1, s.Num)
+		require.Len(t, s.Replicas, 1)
+		require.Equal(t, ports[i], s.Replicas[0].Port)
+
+		var port proto.ColUInt16
+		require.NoError(t, c.DoShard(ctx, s.Num, ch.Query{
+			Body:   "SELECT tcpPort() AS port",
+			Result: proto.Results{{Name: "port", Data: &port}},
+		}))
+		require.Equal(t, uint16(ports[i]), port[0], "query should be routed to shard")
+	}
+	for _, s := range c.Status() {
+		require.True(t, s.Healthy)
+	}
+}
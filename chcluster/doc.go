@@ -0,0 +1,2 @@
+// Package chcluster is a cluster-aware client with connection pool per replica.
+package chcluster
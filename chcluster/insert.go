@@ -0,0 +1,410 @@
+package chcluster
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-faster/city"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// ShardingKey returns sharding key of input row, like sharding
+// expression of Distributed table.
+type ShardingKey func(input proto.Input, row int) (uint64, error)
+
+// Rand returns ShardingKey that distributes rows randomly, like rand().
+func Rand() ShardingKey {
+	return func(input proto.Input, row int) (uint64, error) {
+		return rand.Uint64(), nil // #nosec G404
+	}
+}
+
+// CityHash64 returns ShardingKey that hashes values of columns, like
+// cityHash64(columns...) of server.
+//
+// Strings, integers, floats and time values are supported, integers
+// and floats are hashed by their binary representation. Date, Date32,
+// DateTime and DateTime64 are hashed by raw column value (days or ticks
+// with column precision), as server does.
+func CityHash64(columns ...string) ShardingKey {
+	return func(input proto.Input, row int) (uint64, error) {
+		var h uint64
+		for i, name := range columns {
+			col, ok := findColumn(input, name)
+			if !ok {
+				return 0, errors.Errorf("column %q not found", name)
+			}
+			var err error
+			v, ok := rawValue(col, row)
+			if !ok {
+				v, err = rowValue(col, row)
+			}
+			if err != nil {
+				return 0, errors.Wrapf(err, "column %q", name)
+			}
+			vh, err := hashValue(v)
+			if err != nil {
+				return 0, errors.Wrapf(err, "column %q", name)
+			}
+			if i == 0 {
+				h = vh
+			} else {
+				h = hash128to64(h, vh)
+			}
+		}
+		return h, nil
+	}
+}
+
+func findColumn(input proto.Input, name string) (proto.ColInput, bool) {
+	for _, c := range input {
+		if c.Name == name {
+			return c.Data, true
+		}
+	}
+	return nil, false
+}
+
+// intHash64 is hash of integer, same as IntHash64Impl of server.
+func intHash64(x uint64) uint64 {
+	x ^= 0x4CF2D2BAAE6DA887
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// hash128to64 combines hashes, same as Hash128to64 of CityHash v1.0.2.
+func hash128to64(low, high uint64) uint64 {
+	const mul = 0x9ddfea08eb382d69
+	a := (low ^ high) * mul
+	a ^= a >> 47
+	b := (high ^ a) * mul
+	b ^= b >> 47
+	return b * mul
+}
+
+func hashValue(v any) (uint64, error) {
+	switch v := v.(type) {
+	case string:
+		return city.CH64([]byte(v)), nil
+	case []byte:
+		return city.CH64(v), nil
+	case uint8:
+		return intHash64(uint64(v)), nil
+	case uint16:
+		return intHash64(uint64(v)), nil
+	case uint32:
+		return intHash64(uint64(v)), nil
+	case uint64:
+		return intHash64(v), nil
+	case int8:
+		return intHash64(uint64(uint8(v))), nil
+	case int16:
+		return intHash64(uint64(uint16(v))), nil
+	case int32:
+		return intHash64(uint64(uint32(v))), nil
+	case int64:
+		return intHash64(uint64(v)), nil
+	case float32:
+		return intHash64(uint64(math.Float32bits(v))), nil
+	case float64:
+		return intHash64(math.Float64bits(v)), nil
+	default:
+		return 0, errors.Errorf("unsupported type %T", v)
+	}
+}
+
+// rawValue returns underlying value of row i of date and time column,
+// which is hashed by server instead of time.Time.
+func rawValue(col proto.ColInput, i int) (any, bool) {
+	switch c := col.(type) {
+	case *proto.ColDate:
+		return uint16((*c)[i]), true
+	case proto.ColDate:
+		return uint16(c[i]), true
+	case *proto.ColDate32:
+		return int32((*c)[i]), true
+	case proto.ColDate32:
+		return int32(c[i]), true
+	case *proto.ColDateTime:
+		return uint32(c.Data[i]), true
+	case *proto.ColDateTime64:
+		return int64(c.Data[i]), true
+	default:
+		return nil, false
+	}
+}
+
+// rowValue returns value of row i of column via Row method,
+// see proto.ColumnOf.
+func rowValue(col proto.ColInput, i int) (any, error) {
+	m := reflect.ValueOf(col).MethodByName("Row")
+	if !m.IsValid() {
+		return nil, errors.Errorf("%T does not implement Row", col)
+	}
+	return m.Call([]reflect.Value{reflect.ValueOf(i)})[0].Interface(), nil
+}
+
+// rowColumn is proto.ColumnOf accessed via reflection.
+type rowColumn struct {
+	col    interface{ Rows() int }
+	reset  func()
+	row    reflect.Value
+	append reflect.Value
+}
+
+func newRowColumn(name string, col interface{ Rows() int }) (*rowColumn, error) {
+	resetter, ok := col.(interface{ Reset() })
+	v := reflect.ValueOf(col)
+	c := &rowColumn{
+		col:    col,
+		row:    v.MethodByName("Row"),
+		append: v.MethodByName("Append"),
+	}
+	if !ok || !c.row.IsValid() || !c.append.IsValid() || v.Kind() != reflect.Ptr {
+		return nil, errors.Errorf("column %q: %T should be pointer to proto.ColumnOf", name, col)
+	}
+	c.reset = resetter.Reset
+	return c, nil
+}
+
+// values returns all rows of column, copying byte slices that
+// can reference column buffer.
+func (c *rowColumn) values() []reflect.Value {
+	out := make([]reflect.Value, c.col.Rows())
+	for i := range out {
+		v := c.row.Call([]reflect.Value{reflect.ValueOf(i)})[0]
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			reflect.Copy(cp, v)
+			v = cp
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// fill resets column and appends rows with provided indexes.
+func (c *rowColumn) fill(values []reflect.Value, rows []int) {
+	c.reset()
+	for _, i := range rows {
+		c.append.Call([]reflect.Value{values[i]})
+	}
+}
+
+// ShardError is error of query or insertion to shard.
+type ShardError struct {
+	Shard int
+	Err   error
+}
+
+func (e *ShardError) Error() string {
+	return "shard " + strconv.Itoa(e.Shard) + ": " + e.Err.Error()
+}
+
+func (e *ShardError) Unwrap() error { return e.Err }
+
+// InsertOptions for Cluster.Insert.
+type InsertOptions struct {
+	// Table is local table on shards, like "db.events_local".
+	Table string
+	// Key is sharding key of rows, defaults to Rand.
+	Key ShardingKey
+	// MaxRetries of insertion to single shard, default is 3.
+	MaxRetries int
+	// RetryBackoff is initial backoff between retries, default is 100ms.
+	RetryBackoff time.Duration
+	// DeduplicationToken is optional base of insert_deduplication_token
+	// setting, so retries of same data are deduplicated by replicated
+	// tables. Shard number is appended to token.
+	//
+	// Without token, retry of insertion that partially succeeded on
+	// server, e.g. failed after some blocks were written or with lost
+	// connection, can insert duplicate rows.
+	DeduplicationToken string
+	// Settings of insert queries.
+	Settings []ch.Setting
+}
+
+func (o *InsertOptions) setDefaults() {
+	if o.Key == nil {
+		o.Key = Rand()
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+	if o.RetryBackoff == 0 {
+		o.RetryBackoff = time.Millisecond * 100
+	}
+}
+
+// shardOf returns index of shard for key, like Distributed table does:
+// key modulo total weight selects shard by weight ranges.
+func shardOf(key uint64, weights []uint64, total uint64) int {
+	slot := key % total
+	for i, w := range weights {
+		if slot < w {
+			return i
+		}
+		slot -= w
+	}
+	return len(weights) - 1
+}
+
+// Insert splits input by sharding key and inserts rows of each shard
+// directly to local table on healthy replica of shard, retrying on errors.
+//
+// Input columns must be pointers to proto.ColumnOf, they are reused for
+// blocks of shards and restored after insertion. Shards are inserted
+// sequentially, and failure of one shard does not stop insertion to others,
+// failed shards are reported as ShardError.
+func (c *Cluster) Insert(ctx context.Context, input proto.Input, opt InsertOptions) error {
+	opt.setDefaults()
+	if opt.Table == "" {
+		return errors.New("table is required")
+	}
+	if len(input) == 0 {
+		return errors.New("no input")
+	}
+	rows := input[0].Data.Rows()
+
+	// Assigning rows to shards.
+	shards := c.topology.Shards
+	weights := make([]uint64, len(shards))
+	var total uint64
+	for i, s := range shards {
+		weights[i] = uint64(s.Weight)
+		total += uint64(s.Weight)
+	}
+	if total == 0 {
+		return errors.New("all shards have zero weight")
+	}
+	shardRows := make([][]int, len(shards))
+	for i := 0; i < rows; i++ {
+		key, err := opt.Key(input, i)
+		if err != nil {
+			return errors.Wrapf(err, "sharding key of row %d", i)
+		}
+		idx := shardOf(key, weights, total)
+		shardRows[idx] = append(shardRows[idx], i)
+	}
+
+	// Saving original rows to fill columns for each shard.
+	columns := make([]*rowColumn, len(input))
+	values := make([][]reflect.Value, len(input))
+	for i, col := range input {
+		rc, err := newRowColumn(col.Name, col.Data)
+		if err != nil {
+			return err
+		}
+		if n := col.Data.Rows(); n != rows {
+			return errors.Errorf("column %q: %d rows, expected %d", col.Name, n, rows)
+		}
+		columns[i] = rc
+		values[i] = rc.values()
+	}
+	all := make([]int, rows)
+	for i := range all {
+		all[i] = i
+	}
+	defer func() {
+		for i, col := range columns {
+			col.fill(values[i], all)
+		}
+	}()
+
+	var errs error
+	for idx, s := range shards {
+		if len(shardRows[idx]) == 0 {
+			continue
+		}
+		for i, col := range columns {
+			col.fill(values[i], shardRows[idx])
+		}
+		if err := c.insertShard(ctx, s.Num, input, opt); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = multierr.Append(errs, &ShardError{Shard: s.Num, Err: err})
+		}
+	}
+	return errs
+}
+
+func (c *Cluster) insertShard(ctx context.Context, shard int, input proto.Input, opt InsertOptions) error {
+	settings := opt.Settings
+	if opt.DeduplicationToken != "" {
+		settings = append(append([]ch.Setting(nil), settings...), ch.Setting{
+			Key:       "insert_deduplication_token",
+			Value:     fmt.Sprintf("%s-%d", opt.DeduplicationToken, shard),
+			Important: true,
+		})
+	}
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = opt.RetryBackoff
+	var attempt int
+	return backoff.Retry(func() error {
+		attempt++
+		err := c.DoShard(ctx, shard, ch.Query{
+			Body:     input.Into(opt.Table),
+			Input:    input,
+			Settings: settings,
+		})
+		if err != nil {
+			c.lg.Warn("Insert to shard failed",
+				zap.Int("shard", shard),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+		}
+		if ctx.Err() != nil || !retryable(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, backoff.WithContext(backoff.WithMaxRetries(b, uint64(opt.MaxRetries)), ctx))
+}
+
+// permanentErrors are server exception codes that are not fixed by
+// retrying the same insertion.
+var permanentErrors = []proto.Error{
+	proto.ErrUnknownTable,
+	proto.ErrUnknownDatabase,
+	proto.ErrSyntaxError,
+	proto.ErrTypeMismatch,
+	proto.ErrNoSuchColumnInTable,
+	proto.ErrThereIsNoColumn,
+	proto.ErrIncorrectNumberOfColumns,
+	proto.ErrSizesOfColumnsDoesntMatch,
+	proto.ErrIllegalColumn,
+	proto.ErrIllegalTypeOfArgument,
+	proto.ErrUnknownIdentifier,
+	proto.ErrUnknownType,
+	proto.ErrUnknownSetting,
+	proto.ErrCannotConvertType,
+	proto.ErrCannotInsertNullInOrdinaryColumn,
+	proto.ErrValueIsOutOfRangeOfDataType,
+	proto.ErrIncorrectData,
+	proto.ErrReadonly,
+	proto.ErrUnknownUser,
+	proto.ErrAuthenticationFailed,
+	proto.ErrDatabaseAccessDenied,
+}
+
+// retryable reports whether insertion that failed with err can be retried.
+func retryable(err error) bool {
+	return err == nil || !ch.IsErr(err, permanentErrors...)
+}
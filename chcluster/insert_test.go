@@ -0,0 +1,304 @@
+package chcluster
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/cht"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func TestShardOf(t *testing.T) {
+	weights := []uint64{1, 3}
+	counts := make([]int, len(weights))
+	for key := uint64(0); key < 100; key++ {
+		counts[shardOf(key, weights, 4)]++
+	}
+	require.Equal(t, []int{25, 75}, counts)
+	require.Equal(t, 0, shardOf(4, weights, 4))
+	require.Equal(t, 1, shardOf(7, weights, 4))
+
+	// Shard with zero weight receives no rows.
+	for key := uint64(0); key < 10; key++ {
+		require.Equal(t, 1, shardOf(key, []uint64{0, 2}, 2))
+	}
+}
+
+func TestCityHash64(t *testing.T) {
+	var (
+		s   proto.ColStr
+		n   proto.ColUInt64
+		i32 proto.ColInt32
+	)
+	s.Append("")
+	n.Append(1)
+	i32.Append(-1)
+	input := proto.Input{
+		{Name: "s", Data: &s},
+		{Name: "n", Data: &n},
+		{Name: "i", Data: &i32},
+	}
+
+	h, err := CityHash64("s")(input, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(11160318154034397263), h, "cityHash64('')")
+
+	h, err = CityHash64("n")(input, 0)
+	require.NoError(t, err)
+	require.Equal(t, intHash64(1), h)
+
+	h, err = CityHash64("i")(input, 0)
+	require.NoError(t, err)
+	require.Equal(t, intHash64(0xffffffff), h, "bits should not be sign-extended")
+
+	h, err = CityHash64("s", "n")(input, 0)
+	require.NoError(t, err)
+	require.Equal(t, hash128to64(11160318154034397263, intHash64(1)), h)
+
+	t.Run("Time", func(t *testing.T) {
+		// Raw column values are hashed as server does, not unix time.
+		v := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		var (
+			date   proto.ColDate
+			date32 proto.ColDate32
+			dt     proto.ColDateTime
+			dt64   = new(proto.ColDateTime64).WithPrecision(proto.PrecisionMilli)
+		)
+		date.Append(v)
+		date32.Append(v)
+		dt.Append(v)
+		dt64.Append(v)
+		input := proto.Input{
+			{Name: "date", Data: &date},
+			{Name: "date32", Data: &date32},
+			{Name: "dt", Data: &dt},
+			{Name: "dt64", Data: dt64},
+		}
+		for _, tt := range []struct {
+			Column string
+			Value  uint64
+		}{
+			// cityHash64(toDate('2020-01-01')), 18262 days.
+			{Column: "date", Value: intHash64(18262)},
+			// cityHash64(toDate32('2020-01-01')), 18262 days.
+			{Column: "date32", Value: intHash64(18262)},
+			// cityHash64(toDateTime('2020-01-01 00:00:00', 'UTC')).
+			{Column: "dt", Value: intHash64(1577836800)},
+			// cityHash64(toDateTime64('2020-01-01 00:00:00', 3, 'UTC')), milliseconds.
+			{Column: "dt64", Value: intHash64(1577836800000)},
+		} {
+			h, err := CityHash64(tt.Column)(input, 0)
+			require.NoError(t, err)
+			require.Equal(t, tt.Value, h, tt.Column)
+		}
+
+		// Negative Date32 bits are not sign-extended.
+		date32.Reset()
+		date32.Append(time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC))
+		h, err := CityHash64("date32")(input, 0)
+		require.NoError(t, err)
+		require.Equal(t, intHash64(0xffffffff), h)
+	})
+
+	_, err = CityHash64("missing")(input, 0)
+	require.ErrorContains(t, err, `column "missing" not found`)
+
+	m := proto.NewMap[string, string](new(proto.ColStr), new(proto.ColStr))
+	m.Append(map[string]string{"k": "v"})
+	_, err = CityHash64("m")(proto.Input{{Name: "m", Data: m}}, 0)
+	require.ErrorContains(t, err, "unsupported type")
+}
+
+func TestRetryable(t *testing.T) {
+	require.True(t, retryable(errors.New("connection reset")))
+	require.True(t, retryable(&ch.Exception{Code: proto.ErrTimeoutExceeded}))
+	require.False(t, retryable(&ch.Exception{Code: proto.ErrUnknownTable}))
+	require.False(t, retryable(errors.Join(errors.New("query"), &ch.Exception{Code: proto.ErrTypeMismatch})))
+}
+
+func TestRowColumn(t *testing.T) {
+	var (
+		str   proto.ColStr
+		bytes proto.ColBytes
+		arr   = new(proto.ColStr).Array()
+		lc    = new(proto.ColStr).LowCardinality()
+		null  = new(proto.ColStr).Nullable()
+		dt    = &proto.ColDateTime64{Precision: proto.PrecisionMilli, PrecisionSet: true}
+		start = time.Unix(1700000000, 0)
+	)
+	for i := 0; i < 5; i++ {
+		v := strconv.Itoa(i)
+		str.Append(v)
+		bytes.Append([]byte(v))
+		arr.Append([]string{v, v})
+		lc.Append(v)
+		if i%2 == 0 {
+			null.Append(proto.Null[string]())
+		} else {
+			null.Append(proto.NewNullable(v))
+		}
+		dt.Append(start.Add(time.Duration(i) * time.Millisecond))
+	}
+	input := proto.Input{
+		{Name: "str", Data: &str},
+		{Name: "bytes", Data: &bytes},
+		{Name: "arr", Data: arr},
+		{Name: "lc", Data: lc},
+		{Name: "null", Data: null},
+		{Name: "dt", Data: dt},
+	}
+	for _, col := range input {
+		rc, err := newRowColumn(col.Name, col.Data)
+		require.NoError(t, err)
+		values := rc.values()
+		before, err := rowValue(col.Data, 3)
+		require.NoError(t, err)
+
+		rc.fill(values, []int{3, 1})
+		require.Equal(t, 2, col.Data.Rows(), col.Name)
+		v, err := rowValue(col.Data, 0)
+		require.NoError(t, err)
+		require.Equal(t, before, v, col.Name)
+
+		rc.fill(values, []int{0, 1, 2, 3, 4})
+		require.Equal(t, 5, col.Data.Rows(), col.Name)
+		v, err = rowValue(col.Data, 3)
+		require.NoError(t, err)
+		require.Equal(t, before, v, col.Name)
+	}
+	require.Equal(t, proto.PrecisionMilli, dt.Precision, "parameters should be kept")
+
+	_, err := newRowColumn("raw", proto.ColUInt64{1})
+	require.ErrorContains(t, err, "should be pointer")
+}
+
+func TestCluster_Insert(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx     = context.Background()
+		host, a = testServer(t)
+		broken  = closedPort(t)
+	)
+	c, err := New(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:      zaptest.NewLogger(t).Named("usr"),
+			DialTimeout: time.Second,
+		},
+		Topology: &Topology{Shards: []Shard{
+			{Replicas: []Replica{{Host: host, Port: a}}},
+			{Replicas: []Replica{{Host: host, Port: broken}}},
+		}},
+		HealthCheckPeriod: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	var ids proto.ColUInt64
+	for i := 0; i < 10; i++ {
+		ids.Append(uint64(i))
+	}
+	input := proto.Input{{Name: "id", Data: &ids}}
+
+	// Only second shard is unavailable.
+	err = c.Insert(ctx, input, InsertOptions{
+		Table:        "events",
+		Key:          CityHash64("id"),
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+	})
+	require.Error(t, err)
+	errs := multierr.Errors(err)
+	require.Len(t, errs, 1)
+	var shardErr *ShardError
+	require.True(t, errors.As(errs[0], &shardErr))
+	require.Equal(t, 2, shardErr.Shard)
+	require.Equal(t, 10, ids.Rows(), "input should be restored")
+	for i := 0; i < 10; i++ {
+		require.Equal(t, uint64(i), ids.Row(i))
+	}
+
+	require.ErrorContains(t, c.Insert(ctx, input, InsertOptions{}), "table is required")
+}
+
+func TestCluster_InsertSharded(t *testing.T) {
+	cht.Skip(t)
+	t.Parallel()
+	const host = "127.0.0.1"
+	var (
+		ports    = cht.Ports(t, 2)
+		clusters = cht.Clusters{
+			"nexus": cht.Cluster{
+				Shards: []cht.Shard{
+					{Replicas: []cht.Replica{{Host: host, Port: ports[0]}}},
+					{Weight: 2, Replicas: []cht.Replica{{Host: host, Port: ports[1]}}},
+				},
+			},
+		}
+		lg = zaptest.NewLogger(t)
+	)
+	servers := cht.Many(t,
+		cht.With(cht.WithClusters(clusters), cht.WithTCP(ports[0]), cht.WithLog(lg.Named("alpha"))),
+		cht.With(cht.WithClusters(clusters), cht.WithTCP(ports[1]), cht.WithLog(lg.Named("beta"))),
+	)
+
+	ctx := context.Background()
+	c, err := New(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:  lg.Named("usr"),
+			Address: servers[0].TCP,
+		},
+		Cluster: "nexus",
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	for _, s := range c.Topology().Shards {
+		require.NoError(t, c.DoShard(ctx, s.Num, ch.Query{
+			Body: "CREATE TABLE events_local (id UInt64, name String) ENGINE = MergeTree ORDER BY id",
+		}))
+	}
+
+	var (
+		ids   proto.ColUInt64
+		names proto.ColStr
+	)
+	const rows = 1000
+	for i := 0; i < rows; i++ {
+		ids.Append(uint64(i))
+		names.Append("name" + strconv.Itoa(i))
+	}
+	require.NoError(t, c.Insert(ctx, proto.Input{
+		{Name: "id", Data: &ids},
+		{Name: "name", Data: &names},
+	}, InsertOptions{
+		Table:              "events_local",
+		Key:                CityHash64("id", "name"),
+		DeduplicationToken: "test",
+	}))
+
+	var total uint64
+	for i, s := range c.Topology().Shards {
+		var count, misplaced proto.ColUInt64
+		// Same as Distributed table with cityHash64(id, name) sharding key,
+		// where shard 1 has weight 1 and shard 2 has weight 2.
+		require.NoError(t, c.DoShard(ctx, s.Num, ch.Query{
+			Body: "SELECT count() AS count, countIf((cityHash64(id, name) % 3 = 0) != " +
+				strconv.Itoa(1-i) + ") AS misplaced FROM events_local",
+			Result: proto.Results{
+				{Name: "count", Data: &count},
+				{Name: "misplaced", Data: &misplaced},
+			},
+		}))
+		require.NotZero(t, count[0])
+		require.Zero(t, misplaced[0], "shard %d", s.Num)
+		total += count[0]
+	}
+	require.Equal(t, uint64(rows), total)
+}
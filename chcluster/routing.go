@@ -0,0 +1,92 @@
+package chcluster
+
+import (
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Routing is strategy of replica selection within shard.
+//
+// Healthy replicas are always preferred, then replicas with replication
+// delay not exceeding Options.MaxReplicaDelay.
+type Routing byte
+
+const (
+	// LeastConns prefers replicas with the least acquired connections.
+	LeastConns Routing = iota
+	// LocalFirst prefers local replicas, see Options.IsLocal, then
+	// replicas with the least acquired connections.
+	LocalFirst
+	// InOrder prefers replicas by Priority, then by number, like
+	// "in_order" load balancing of server.
+	InOrder
+)
+
+// String implements fmt.Stringer.
+func (r Routing) String() string {
+	switch r {
+	case LeastConns:
+		return "LeastConns"
+	case LocalFirst:
+		return "LocalFirst"
+	case InOrder:
+		return "InOrder"
+	default:
+		return "Routing(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// defaultIsLocal reports whether replica host is loopback or host name.
+func defaultIsLocal() func(r Replica) bool {
+	hostname, _ := os.Hostname()
+	return func(r Replica) bool {
+		if r.Host == "localhost" || (hostname != "" && r.Host == hostname) {
+			return true
+		}
+		ip := net.ParseIP(r.Host)
+		return ip != nil && ip.IsLoopback()
+	}
+}
+
+// sortReplicas sorts replicas by preference of routing.
+func sortReplicas(list []*replica, routing Routing, maxDelay time.Duration) {
+	if routing != InOrder {
+		// Spreading load between equal replicas.
+		rand.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+	}
+	lagging := func(r *replica) bool {
+		return maxDelay > 0 && r.delay.Load() > maxDelay
+	}
+	acquired := make(map[*replica]int32, len(list))
+	for _, r := range list {
+		acquired[r] = r.acquired()
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if ah, bh := a.healthy.Load(), b.healthy.Load(); ah != bh {
+			return ah
+		}
+		if al, bl := lagging(a), lagging(b); al != bl {
+			return bl
+		}
+		switch routing {
+		case InOrder:
+			if a.Priority != b.Priority {
+				return a.Priority < b.Priority
+			}
+			if a.shard != b.shard {
+				return a.shard < b.shard
+			}
+			return a.Num < b.Num
+		case LocalFirst:
+			if a.local != b.local {
+				return a.local
+			}
+		}
+		return acquired[a] < acquired[b]
+	})
+}
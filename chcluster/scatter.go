@@ -0,0 +1,486 @@
+package chcluster
+
+import (
+	"bytes"
+	"cmp"
+	"container/heap"
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// Target of scatter-gather query, like *chpool.Pool or *ch.Client.
+type Target interface {
+	Do(ctx context.Context, q ch.Query) error
+}
+
+// SortKey is column of ORDER BY clause.
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// ScatterOptions for Scatter.
+type ScatterOptions struct {
+	// OrderBy enables k-way merge of results that are sorted by OrderBy
+	// on each target, otherwise blocks are passed in order of arrival.
+	OrderBy []SortKey
+	// Limit is maximum total number of rows, zero means no limit.
+	//
+	// Queries are cancelled when limit is reached.
+	Limit int
+	// BlockRows is maximum number of rows in merged block if OrderBy
+	// is set, default is DefaultBlockRows.
+	BlockRows int
+
+	// Partial enables partial results, so failure of target does not
+	// cancel query on other targets and is reported to OnError.
+	// Query fails only if all targets failed.
+	//
+	// Otherwise, first failure cancels query on all targets.
+	Partial bool
+	// OnError is called sequentially for each failed target if Partial
+	// is set.
+	OnError func(err *ShardError)
+}
+
+// DefaultBlockRows is default number of rows in merged block.
+const DefaultBlockRows = 65536
+
+func (o *ScatterOptions) setDefaults() {
+	if o.BlockRows == 0 {
+		o.BlockRows = DefaultBlockRows
+	}
+}
+
+type scatterTarget struct {
+	shard int
+	do    func(ctx context.Context, q ch.Query) error
+}
+
+// Scatter performs query on all targets in parallel and passes merged
+// blocks to q.OnResult.
+//
+// Result of query should be proto.Results with columns that implement
+// proto.ColumnOf. Each target decodes blocks to own columns of inferred
+// type (see proto.ColAuto), rows are copied to q.Result and OnResult
+// is called sequentially. If OnResult is nil, all rows are accumulated
+// in q.Result.
+//
+// Failures are reported as *ShardError with 1-based index of target.
+func Scatter(ctx context.Context, targets []Target, q ch.Query, opt ScatterOptions) error {
+	list := make([]scatterTarget, len(targets))
+	for i, t := range targets {
+		list[i] = scatterTarget{shard: i + 1, do: t.Do}
+	}
+	return scatter(ctx, list, q, opt)
+}
+
+// Scatter performs query on replica of each shard in parallel, like
+// Scatter function.
+//
+// Failures are reported as *ShardError with shard number.
+func (c *Cluster) Scatter(ctx context.Context, q ch.Query, opt ScatterOptions) error {
+	list := make([]scatterTarget, 0, len(c.topology.Shards))
+	for _, s := range c.topology.Shards {
+		shard := s.Num
+		list = append(list, scatterTarget{
+			shard: shard,
+			do: func(ctx context.Context, q ch.Query) error {
+				return c.DoShard(ctx, shard, q)
+			},
+		})
+	}
+	return scatter(ctx, list, q, opt)
+}
+
+// stream is result of query on single target.
+type stream struct {
+	shard  int
+	result proto.Results
+	// ready receives stream when block is decoded to result.
+	ready chan *stream
+	// ack is sent when block is consumed.
+	ack chan struct{}
+
+	rows int // rows in current block
+	pos  int // current row
+	row  []reflect.Value
+}
+
+// setBlock prepares decoded block for copying to out.
+func (s *stream) setBlock(out []*rowColumn) error {
+	s.pos = 0
+	s.row = s.row[:0]
+	for i, col := range s.result {
+		auto := col.Data.(*proto.ColAuto)
+		row := reflect.ValueOf(auto.Data).MethodByName("Row")
+		if !row.IsValid() {
+			return errors.Errorf("column %q: %T does not implement Row", col.Name, auto.Data)
+		}
+		if got, expected := row.Type().Out(0), out[i].append.Type().In(0); !got.AssignableTo(expected) {
+			return errors.Errorf("column %q: got %s (%s) instead of %s", col.Name, got, auto.Type(), expected)
+		}
+		s.row = append(s.row, row)
+	}
+	return nil
+}
+
+func (s *stream) value(col, row int) reflect.Value {
+	return s.row[col].Call([]reflect.Value{reflect.ValueOf(row)})[0]
+}
+
+type gather struct {
+	opt      ScatterOptions
+	out      []*rowColumn
+	keys     []int // indexes of OrderBy columns
+	onResult func(ctx context.Context, block proto.Block) error
+	rows     int // rows in current merged block
+	total    int
+}
+
+func (g *gather) copyRow(s *stream, row int) {
+	for i, col := range g.out {
+		col.append.Call([]reflect.Value{s.value(i, row)})
+	}
+	g.rows++
+	g.total++
+}
+
+func (g *gather) limited() bool {
+	return g.opt.Limit > 0 && g.total >= g.opt.Limit
+}
+
+func (g *gather) flush(ctx context.Context) error {
+	if g.rows == 0 || g.onResult == nil {
+		return nil
+	}
+	if err := g.onResult(ctx, proto.Block{
+		Rows:    g.rows,
+		Columns: len(g.out),
+	}); err != nil {
+		return errors.Wrap(err, "on result")
+	}
+	for _, col := range g.out {
+		col.reset()
+	}
+	g.rows = 0
+	return nil
+}
+
+// consume passes blocks in order of arrival.
+func (g *gather) consume(ctx context.Context, ready <-chan *stream) error {
+	for s := range ready {
+		if err := s.setBlock(g.out); err != nil {
+			return errors.Wrapf(err, "shard %d", s.shard)
+		}
+		for i := 0; i < s.rows && !g.limited(); i++ {
+			g.copyRow(s, i)
+		}
+		s.ack <- struct{}{}
+		if err := g.flush(ctx); err != nil {
+			return err
+		}
+		if g.limited() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// next waits for next block of stream, returning false if there
+// are no more blocks.
+func (g *gather) next(s *stream) (bool, error) {
+	if _, ok := <-s.ready; !ok {
+		return false, nil
+	}
+	if err := s.setBlock(g.out); err != nil {
+		return false, errors.Wrapf(err, "shard %d", s.shard)
+	}
+	for n, k := range g.keys {
+		if t := s.row[k].Type().Out(0); !sortable(t) {
+			return false, errors.Errorf("shard %d: column %q: %s is not sortable", s.shard, g.opt.OrderBy[n].Column, t)
+		}
+	}
+	return true, nil
+}
+
+// merge performs k-way merge of sorted streams.
+func (g *gather) merge(ctx context.Context, streams []*stream) error {
+	h := &streamHeap{g: g}
+	for _, s := range streams {
+		ok, err := g.next(s)
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.list = append(h.list, s)
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		s := h.list[0]
+		g.copyRow(s, s.pos)
+		s.pos++
+		if g.limited() {
+			return g.flush(ctx)
+		}
+		if g.rows >= g.opt.BlockRows {
+			if err := g.flush(ctx); err != nil {
+				return err
+			}
+		}
+		if s.pos < s.rows {
+			heap.Fix(h, 0)
+			continue
+		}
+		s.ack <- struct{}{}
+		ok, err := g.next(s)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return g.flush(ctx)
+}
+
+type streamHeap struct {
+	g    *gather
+	list []*stream
+}
+
+func (h *streamHeap) Len() int { return len(h.list) }
+
+func (h *streamHeap) Less(i, j int) bool {
+	a, b := h.list[i], h.list[j]
+	for n, k := range h.g.keys {
+		c := compareValues(a.value(k, a.pos), b.value(k, b.pos))
+		if h.g.opt.OrderBy[n].Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	// Stable order for equal keys.
+	return a.shard < b.shard
+}
+
+func (h *streamHeap) Swap(i, j int) { h.list[i], h.list[j] = h.list[j], h.list[i] }
+
+func (h *streamHeap) Push(x any) { h.list = append(h.list, x.(*stream)) }
+
+func (h *streamHeap) Pop() any {
+	n := len(h.list)
+	s := h.list[n-1]
+	h.list = h.list[:n-1]
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func sortable(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	default:
+		return false
+	}
+}
+
+// compareValues compares values of same sortable type.
+func compareValues(a, b reflect.Value) int {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		return cmp.Compare(boolInt(a.Bool()), boolInt(b.Bool()))
+	default:
+		return bytes.Compare(a.Bytes(), b.Bytes())
+	}
+}
+
+func boolInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func scatter(ctx context.Context, targets []scatterTarget, q ch.Query, opt ScatterOptions) error {
+	opt.setDefaults()
+	if len(targets) == 0 {
+		return errors.New("no targets")
+	}
+	result, ok := q.Result.(proto.Results)
+	if !ok || len(result) == 0 {
+		return errors.Errorf("result should be non-empty proto.Results, got %T", q.Result)
+	}
+	g := &gather{
+		opt:      opt,
+		onResult: q.OnResult,
+	}
+	for _, col := range result {
+		rc, err := newRowColumn(col.Name, col.Data)
+		if err != nil {
+			return errors.Wrap(err, "result")
+		}
+		rc.reset()
+		g.out = append(g.out, rc)
+	}
+	for _, key := range opt.OrderBy {
+		idx := -1
+		for i, col := range result {
+			if col.Name == key.Column {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return errors.Errorf("order by column %q not found in result", key.Column)
+		}
+		g.keys = append(g.keys, idx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mux     sync.Mutex
+		errs    []*ShardError
+		stopped atomic.Bool
+		ordered = len(opt.OrderBy) > 0
+		ready   = make(chan *stream)
+		streams = make([]*stream, len(targets))
+	)
+	for i, t := range targets {
+		s := &stream{
+			shard: t.shard,
+			ready: ready,
+			ack:   make(chan struct{}, 1),
+		}
+		if ordered {
+			s.ready = make(chan *stream)
+		}
+		for _, col := range result {
+			s.result = append(s.result, proto.ResultColumn{Name: col.Name, Data: &proto.ColAuto{}})
+		}
+		streams[i] = s
+
+		sq := q
+		sq.Result = s.result
+		if q.QueryID != "" {
+			sq.QueryID = q.QueryID + "-" + strconv.Itoa(t.shard)
+		}
+		sq.OnResult = func(ctx context.Context, b proto.Block) error {
+			if b.Rows == 0 {
+				return nil
+			}
+			s.rows = b.Rows
+			select {
+			case s.ready <- s:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case <-s.ack:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		wg.Add(1)
+		go func(t scatterTarget) {
+			defer wg.Done()
+			if ordered {
+				defer close(s.ready)
+			}
+			err := t.do(ctx, sq)
+			if err == nil || stopped.Load() {
+				return
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			if !opt.Partial && len(errs) > 0 {
+				// Cancelled by first failure.
+				return
+			}
+			errs = append(errs, &ShardError{Shard: t.shard, Err: err})
+			if !opt.Partial {
+				cancel()
+			}
+		}(t)
+	}
+
+	var err error
+	if ordered {
+		err = g.merge(ctx, streams)
+	} else {
+		go func() {
+			wg.Wait()
+			close(ready)
+		}()
+		err = g.consume(ctx, ready)
+	}
+
+	// Stop queries that are still running, e.g. if limit is reached.
+	mux.Lock()
+	failed := len(errs) > 0 && !opt.Partial
+	mux.Unlock()
+	if err != nil || !failed {
+		stopped.Store(true)
+	}
+	cancel()
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	if !opt.Partial || len(errs) == len(targets) {
+		var out error
+		for _, e := range errs {
+			out = multierr.Append(out, e)
+		}
+		return out
+	}
+	if opt.OnError != nil {
+		for _, e := range errs {
+			opt.OnError(e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package chcluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/cht"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// blockTarget emulates server that returns blocks of (id UInt64, name String).
+type blockTarget struct {
+	blocks [][]uint64
+	// infinite target repeats blocks with values increased by 100
+	// until query is cancelled.
+	infinite bool
+	err      error
+}
+
+func (b blockTarget) Do(ctx context.Context, q ch.Query) error {
+	result := q.Result.(proto.Results)
+	for _, col := range result {
+		t := proto.ColumnTypeUInt64
+		if col.Name == "name" {
+			t = proto.ColumnTypeString
+		}
+		if err := col.Data.(proto.Inferable).Infer(t); err != nil {
+			return err
+		}
+	}
+	for offset := uint64(0); ; offset += 100 {
+		for _, block := range b.blocks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			for _, col := range result {
+				col.Data.Reset()
+				for _, v := range block {
+					v += offset
+					switch data := col.Data.(*proto.ColAuto).Data.(type) {
+					case *proto.ColUInt64:
+						data.Append(v)
+					case *proto.ColStr:
+						data.Append("name" + strconv.FormatUint(v, 10))
+					}
+				}
+			}
+			if err := q.OnResult(ctx, proto.Block{Rows: len(block), Columns: len(result)}); err != nil {
+				return err
+			}
+		}
+		if !b.infinite {
+			break
+		}
+	}
+	return b.err
+}
+
+func TestScatter(t *testing.T) {
+	ctx := context.Background()
+	targets := []Target{
+		blockTarget{blocks: [][]uint64{{1, 4, 7}, {10, 13}}},
+		blockTarget{blocks: [][]uint64{{2, 5}, {8, 11, 14}}},
+		blockTarget{blocks: [][]uint64{{}, {3, 6, 9, 12, 15}}},
+	}
+	t.Run("Unordered", func(t *testing.T) {
+		var (
+			ids   proto.ColUInt64
+			names proto.ColStr
+		)
+		require.NoError(t, Scatter(ctx, targets, ch.Query{
+			Body: "SELECT id, name FROM events",
+			Result: proto.Results{
+				{Name: "id", Data: &ids},
+				{Name: "name", Data: &names},
+			},
+		}, ScatterOptions{}))
+		require.Equal(t, 15, ids.Rows())
+		require.Equal(t, 15, names.Rows())
+		for i, id := range ids {
+			require.Equal(t, "name"+strconv.FormatUint(id, 10), names.Row(i))
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for i, id := range ids {
+			require.Equal(t, uint64(i+1), id)
+		}
+	})
+	t.Run("OrderBy", func(t *testing.T) {
+		var (
+			ids    proto.ColUInt64
+			names  proto.ColStr
+			got    []uint64
+			blocks int
+		)
+		require.NoError(t, Scatter(ctx, targets, ch.Query{
+			Body: "SELECT id, name FROM events ORDER BY id",
+			Result: proto.Results{
+				{Name: "id", Data: &ids},
+				{Name: "name", Data: &names},
+			},
+			OnResult: func(ctx context.Context, block proto.Block) error {
+				require.LessOrEqual(t, block.Rows, 4)
+				require.Equal(t, block.Rows, names.Rows())
+				blocks++
+				got = append(got, ids...)
+				return nil
+			},
+		}, ScatterOptions{
+			OrderBy:   []SortKey{{Column: "id"}},
+			BlockRows: 4,
+		}))
+		require.Equal(t, 4, blocks)
+		require.Len(t, got, 15)
+		for i, id := range got {
+			require.Equal(t, uint64(i+1), id)
+		}
+	})
+	t.Run("OrderByDesc", func(t *testing.T) {
+		var ids proto.ColUInt64
+		require.NoError(t, Scatter(ctx, []Target{
+			blockTarget{blocks: [][]uint64{{9, 5}, {1}}},
+			blockTarget{blocks: [][]uint64{{8, 7, 2}}},
+		}, ch.Query{
+			Body:   "SELECT id FROM events ORDER BY id DESC",
+			Result: proto.Results{{Name: "id", Data: &ids}},
+		}, ScatterOptions{
+			OrderBy: []SortKey{{Column: "id", Desc: true}},
+		}))
+		require.Equal(t, proto.ColUInt64{9, 8, 7, 5, 2, 1}, ids)
+	})
+	t.Run("Limit", func(t *testing.T) {
+		for _, orderBy := range [][]SortKey{nil, {{Column: "id"}}} {
+			var ids proto.ColUInt64
+			require.NoError(t, Scatter(ctx, []Target{
+				blockTarget{blocks: [][]uint64{{1, 3}, {5}}, infinite: true},
+				blockTarget{blocks: [][]uint64{{2, 4}}, infinite: true},
+			}, ch.Query{
+				Body:   "SELECT id FROM events ORDER BY id",
+				Result: proto.Results{{Name: "id", Data: &ids}},
+			}, ScatterOptions{
+				OrderBy: orderBy,
+				Limit:   5,
+			}))
+			require.Equal(t, 5, ids.Rows())
+			if orderBy != nil {
+				require.Equal(t, proto.ColUInt64{1, 2, 3, 4, 5}, ids)
+			}
+		}
+	})
+	t.Run("FailFast", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		var ids proto.ColUInt64
+		err := Scatter(ctx, []Target{
+			blockTarget{blocks: [][]uint64{{1}}, infinite: true},
+			blockTarget{blocks: [][]uint64{{2}}, err: errFailed},
+		}, ch.Query{
+			Body:   "SELECT id FROM events",
+			Result: proto.Results{{Name: "id", Data: &ids}},
+		}, ScatterOptions{})
+		require.ErrorIs(t, err, errFailed)
+		var shardErr *ShardError
+		require.True(t, errors.As(err, &shardErr))
+		require.Equal(t, 2, shardErr.Shard)
+	})
+	t.Run("Partial", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		var (
+			ids    proto.ColUInt64
+			failed []int
+		)
+		require.NoError(t, Scatter(ctx, []Target{
+			blockTarget{blocks: [][]uint64{{1}}},
+			blockTarget{err: errFailed},
+			blockTarget{blocks: [][]uint64{{3}}},
+		}, ch.Query{
+			Body:   "SELECT id FROM events",
+			Result: proto.Results{{Name: "id", Data: &ids}},
+		}, ScatterOptions{
+			OrderBy: []SortKey{{Column: "id"}},
+			Partial: true,
+			OnError: func(err *ShardError) {
+				require.ErrorIs(t, err, errFailed)
+				failed = append(failed, err.Shard)
+			},
+		}))
+		require.Equal(t, proto.ColUInt64{1, 3}, ids)
+		require.Equal(t, []int{2}, failed)
+
+		err := Scatter(ctx, []Target{
+			blockTarget{err: errFailed},
+			blockTarget{err: errFailed},
+		}, ch.Query{
+			Body:   "SELECT id FROM events",
+			Result: proto.Results{{Name: "id", Data: &ids}},
+		}, ScatterOptions{Partial: true})
+		require.ErrorIs(t, err, errFailed)
+	})
+	t.Run("OnResultError", func(t *testing.T) {
+		errStop := errors.New("stop")
+		var ids proto.ColUInt64
+		err := Scatter(ctx, []Target{
+			blockTarget{blocks: [][]uint64{{1}}, infinite: true},
+		}, ch.Query{
+			Body:   "SELECT id FROM events",
+			Result: proto.Results{{Name: "id", Data: &ids}},
+			OnResult: func(ctx context.Context, block proto.Block) error {
+				return errStop
+			},
+		}, ScatterOptions{})
+		require.ErrorIs(t, err, errStop)
+	})
+	t.Run("TypeMismatch", func(t *testing.T) {
+		var ids proto.ColStr
+		err := Scatter(ctx, []Target{blockTarget{blocks: [][]uint64{{1}}}}, ch.Query{
+			Body:   "SELECT id FROM events",
+			Result: proto.Results{{Name: "id", Data: &ids}},
+		}, ScatterOptions{})
+		require.ErrorContains(t, err, `shard 1: column "id": got uint64 (UInt64) instead of string`)
+	})
+	t.Run("Validate", func(t *testing.T) {
+		var ids proto.ColUInt64
+		q := ch.Query{
+			Body:   "SELECT id FROM events",
+			Result: proto.Results{{Name: "id", Data: &ids}},
+		}
+		require.ErrorContains(t, Scatter(ctx, nil, q, ScatterOptions{}), "no targets")
+		require.ErrorContains(t, Scatter(ctx, targets, q, ScatterOptions{
+			OrderBy: []SortKey{{Column: "ts"}},
+		}), `order by column "ts" not found`)
+		q.Result = proto.ResultColumn{Name: "id", Data: &ids}
+		require.ErrorContains(t, Scatter(ctx, targets, q, ScatterOptions{}), "should be non-empty proto.Results")
+	})
+}
+
+func TestCompareValues(t *testing.T) {
+	for _, tt := range []struct {
+		a, b any
+		want int
+	}{
+		{int8(-1), int8(1), -1},
+		{uint32(2), uint32(1), 1},
+		{1.5, 1.5, 0},
+		{"a", "b", -1},
+		{[]byte("b"), []byte("a"), 1},
+		{false, true, -1},
+	} {
+		a, b := reflect.ValueOf(tt.a), reflect.ValueOf(tt.b)
+		require.True(t, sortable(a.Type()))
+		require.Equal(t, tt.want, compareValues(a, b), "%v %v", tt.a, tt.b)
+	}
+	require.False(t, sortable(reflect.TypeOf([]string{})))
+}
+
+func TestCluster_Scatter(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx     = context.Background()
+		host, a = testServer(t)
+		_, b    = testServer(t)
+	)
+	c, err := New(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger: zaptest.NewLogger(t).Named("usr"),
+		},
+		Topology: &Topology{Shards: []Shard{
+			{Replicas: []Replica{{Host: host, Port: a}}},
+			{Replicas: []Replica{{Host: host, Port: b}}},
+		}},
+		HealthCheckPeriod: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	// Test server returns no data.
+	var ids proto.ColUInt64
+	require.NoError(t, c.Scatter(ctx, ch.Query{
+		Body:   "SELECT id FROM events_local",
+		Result: proto.Results{{Name: "id", Data: &ids}},
+	}, ScatterOptions{OrderBy: []SortKey{{Column: "id"}}}))
+	require.Zero(t, ids.Rows())
+}
+
+func TestCluster_ScatterSorted(t *testing.T) {
+	cht.Skip(t)
+	t.Parallel()
+	var (
+		lg      = zaptest.NewLogger(t)
+		servers = cht.Many(t,
+			cht.With(cht.WithLog(lg.Named("alpha"))),
+			cht.With(cht.WithLog(lg.Named("beta"))),
+		)
+		ctx    = context.Background()
+		shards []Shard
+	)
+	for _, s := range servers {
+		host, port, err := net.SplitHostPort(s.TCP)
+		require.NoError(t, err)
+		p, err := strconv.Atoi(port)
+		require.NoError(t, err)
+		shards = append(shards, Shard{Replicas: []Replica{{Host: host, Port: p}}})
+	}
+	c, err := New(ctx, Options{
+		ClientOptions: ch.Options{Logger: lg.Named("usr")},
+		Topology:      &Topology{Shards: shards},
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	for i, s := range c.Topology().Shards {
+		require.NoError(t, c.DoShard(ctx, s.Num, ch.Query{
+			Body: "CREATE TABLE events_local (id UInt64) ENGINE = MergeTree ORDER BY id",
+		}))
+		// Even numbers on first shard, odd on second.
+		require.NoError(t, c.DoShard(ctx, s.Num, ch.Query{
+			Body: "INSERT INTO events_local SELECT number * 2 + " + strconv.Itoa(i) + " FROM numbers(1000)",
+		}))
+	}
+
+	var (
+		ids proto.ColUInt64
+		got []uint64
+	)
+	require.NoError(t, c.Scatter(ctx, ch.Query{
+		Body:   "SELECT id FROM events_local ORDER BY id",
+		Result: proto.Results{{Name: "id", Data: &ids}},
+		OnResult: func(ctx context.Context, block proto.Block) error {
+			got = append(got, ids...)
+			return nil
+		},
+	}, ScatterOptions{
+		OrderBy:   []SortKey{{Column: "id"}},
+		Limit:     1500,
+		BlockRows: 100,
+	}))
+	require.Len(t, got, 1500)
+	for i, id := range got {
+		require.Equal(t, uint64(i), id)
+	}
+}
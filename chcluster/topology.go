@@ -0,0 +1,138 @@
+package chcluster
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/go-faster/errors"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// Replica of shard.
+type Replica struct {
+	// Num is number of replica in shard, starting from 1.
+	Num      int
+	Host     string
+	Port     int
+	Priority int
+}
+
+// Addr returns address of replica, like "host:9000".
+func (r Replica) Addr() string {
+	return net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+}
+
+// Shard of cluster.
+type Shard struct {
+	// Num is number of shard in cluster, starting from 1.
+	Num int
+	// Weight of shard for Insert, shard with zero weight receives no rows.
+	// Zero weight in static topology (Options.Topology) is treated as 1.
+	Weight              int
+	InternalReplication bool
+	Replicas            []Replica
+}
+
+// Topology of cluster, like "remote_servers" configuration section.
+type Topology struct {
+	Shards []Shard
+}
+
+// Validate topology.
+func (t Topology) Validate() error {
+	if len(t.Shards) == 0 {
+		return errors.New("no shards")
+	}
+	seen := map[int]struct{}{}
+	for _, s := range t.Shards {
+		if _, ok := seen[s.Num]; ok {
+			return errors.Errorf("duplicate shard %d", s.Num)
+		}
+		seen[s.Num] = struct{}{}
+		if len(s.Replicas) == 0 {
+			return errors.Errorf("shard %d: no replicas", s.Num)
+		}
+	}
+	return nil
+}
+
+// normalize sets defaults and sorts shards and replicas by number.
+//
+// Zero weights are set to 1 only if defaultWeight is true, because
+// zero shard_weight of discovered topology disables writes to shard.
+func (t Topology) normalize(defaultWeight bool) Topology {
+	out := Topology{Shards: make([]Shard, len(t.Shards))}
+	for i, s := range t.Shards {
+		if s.Num == 0 {
+			s.Num = i + 1
+		}
+		if s.Weight == 0 && defaultWeight {
+			s.Weight = 1
+		}
+		replicas := make([]Replica, len(s.Replicas))
+		for j, r := range s.Replicas {
+			if r.Num == 0 {
+				r.Num = j + 1
+			}
+			replicas[j] = r
+		}
+		sort.SliceStable(replicas, func(a, b int) bool { return replicas[a].Num < replicas[b].Num })
+		s.Replicas = replicas
+		out.Shards[i] = s
+	}
+	sort.SliceStable(out.Shards, func(a, b int) bool { return out.Shards[a].Num < out.Shards[b].Num })
+	return out
+}
+
+// Discover reads topology of cluster from system.clusters.
+func Discover(ctx context.Context, c *ch.Client, cluster string) (Topology, error) {
+	var (
+		shardNum    proto.ColUInt32
+		shardWeight proto.ColUInt32
+		replicaNum  proto.ColUInt32
+		host        proto.ColStr
+		port        proto.ColUInt16
+		shards      []Shard
+	)
+	if err := c.Do(ctx, ch.Query{
+		Body: "SELECT shard_num, shard_weight, replica_num, host_name, port " +
+			"FROM system.clusters WHERE cluster = {cluster:String} " +
+			"ORDER BY shard_num, replica_num",
+		Parameters: ch.Parameters(map[string]any{"cluster": cluster}),
+		Result: proto.Results{
+			{Name: "shard_num", Data: &shardNum},
+			{Name: "shard_weight", Data: &shardWeight},
+			{Name: "replica_num", Data: &replicaNum},
+			{Name: "host_name", Data: &host},
+			{Name: "port", Data: &port},
+		},
+		OnResult: func(ctx context.Context, block proto.Block) error {
+			for i := 0; i < block.Rows; i++ {
+				num := int(shardNum[i])
+				if len(shards) == 0 || shards[len(shards)-1].Num != num {
+					shards = append(shards, Shard{
+						Num:    num,
+						Weight: int(shardWeight[i]),
+					})
+				}
+				s := &shards[len(shards)-1]
+				s.Replicas = append(s.Replicas, Replica{
+					Num:  int(replicaNum[i]),
+					Host: host.Row(i),
+					Port: int(port[i]),
+				})
+			}
+			return nil
+		},
+	}); err != nil {
+		return Topology{}, errors.Wrap(err, "query")
+	}
+	if len(shards) == 0 {
+		return Topology{}, errors.Errorf("cluster %q not found", cluster)
+	}
+	return Topology{Shards: shards}, nil
+}
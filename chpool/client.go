@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/puddle/v2"
 
 	"github.com/ClickHouse/ch-go"
@@ -23,15 +24,29 @@ func (c *Client) Release() {
 
 	client := c.client()
 
-	if client.IsClosed() || time.Since(c.res.CreationTime()) > c.p.options.MaxConnLifetime {
+	if client.IsClosed() || c.res.Value().expired(time.Now()) {
 		c.res.Destroy()
 		return
 	}
+	if f := c.p.options.AfterRelease; f != nil {
+		if err := f(client); err != nil {
+			c.p.rejected("after release", err)
+			c.res.Destroy()
+			return
+		}
+	}
 
 	c.res.Release()
 }
 
+// Do performs Query, calling ClientOptions.Interceptors of pool.
 func (c *Client) Do(ctx context.Context, q ch.Query) (err error) {
+	if interceptors := c.p.options.ClientOptions.Interceptors; len(interceptors) > 0 {
+		if q.QueryID == "" {
+			q.QueryID = uuid.New().String()
+		}
+		return ch.ChainInterceptors(c.client().Do, interceptors...)(ctx, q)
+	}
 	return c.client().Do(ctx, q)
 }
 
@@ -1,6 +1,8 @@
 package chpool
 
 import (
+	"time"
+
 	"github.com/jackc/puddle/v2"
 
 	"github.com/ClickHouse/ch-go"
@@ -9,6 +11,12 @@ import (
 type connResource struct {
 	client  *ch.Client
 	clients []Client
+	// expiresAt is time when connection exceeds its lifetime.
+	expiresAt time.Time
+}
+
+func (cr *connResource) expired(now time.Time) bool {
+	return now.After(cr.expiresAt)
 }
 
 func (cr *connResource) getConn(p *Pool, res *puddle.Resource[*connResource]) *Client {
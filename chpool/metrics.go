@@ -0,0 +1,102 @@
+package chpool
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/go-faster/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/otelch"
+)
+
+// registerMetrics registers observable instruments reporting pool statistics.
+func (p *Pool) registerMetrics() (metric.Registration, error) {
+	mp := p.options.ClientOptions.MeterProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	m := mp.Meter(otelch.Name)
+
+	acquired, err := m.Int64ObservableGauge(otelch.MetricPoolAcquired,
+		metric.WithDescription("Number of currently acquired connections"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquired")
+	}
+	idle, err := m.Int64ObservableGauge(otelch.MetricPoolIdle,
+		metric.WithDescription("Number of currently idle connections"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "idle")
+	}
+	total, err := m.Int64ObservableGauge(otelch.MetricPoolTotal,
+		metric.WithDescription("Total number of connections, including constructing ones"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "total")
+	}
+	maxConns, err := m.Int64ObservableGauge(otelch.MetricPoolMax,
+		metric.WithDescription("Maximum size of pool"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "max")
+	}
+	acquires, err := m.Int64ObservableCounter(otelch.MetricPoolAcquires,
+		metric.WithDescription("Cumulative count of successful acquires"),
+		metric.WithUnit("{acquire}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquires")
+	}
+	acquireDuration, err := m.Float64ObservableCounter(otelch.MetricPoolAcquireDuration,
+		metric.WithDescription("Total time spent waiting in successful acquires"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquire duration")
+	}
+	emptyAcquires, err := m.Int64ObservableCounter(otelch.MetricPoolEmptyAcquires,
+		metric.WithDescription("Cumulative count of acquires that waited for a connection"),
+		metric.WithUnit("{acquire}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "empty acquires")
+	}
+	canceledAcquires, err := m.Int64ObservableCounter(otelch.MetricPoolCanceledAcquire,
+		metric.WithDescription("Cumulative count of acquires canceled by context"),
+		metric.WithUnit("{acquire}"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "canceled acquires")
+	}
+
+	addr := p.options.ClientOptions.Address
+	if addr == "" {
+		addr = net.JoinHostPort(ch.DefaultHost, strconv.Itoa(ch.DefaultPort))
+	}
+	attrs := metric.WithAttributes(append([]attribute.KeyValue{
+		otelch.ServerAddress(addr),
+	}, p.options.MetricAttributes...)...)
+
+	return m.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		s := p.pool.Stat()
+		o.ObserveInt64(acquired, int64(s.AcquiredResources()), attrs)
+		o.ObserveInt64(idle, int64(s.IdleResources()), attrs)
+		o.ObserveInt64(total, int64(s.TotalResources()), attrs)
+		o.ObserveInt64(maxConns, int64(s.MaxResources()), attrs)
+		o.ObserveInt64(acquires, s.AcquireCount(), attrs)
+		o.ObserveFloat64(acquireDuration, s.AcquireDuration().Seconds(), attrs)
+		o.ObserveInt64(emptyAcquires, s.EmptyAcquireCount(), attrs)
+		o.ObserveInt64(canceledAcquires, s.CanceledAcquireCount(), attrs)
+		return nil
+	}, acquired, idle, total, maxConns, acquires, acquireDuration, emptyAcquires, canceledAcquires)
+}
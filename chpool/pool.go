@@ -2,19 +2,28 @@ package chpool
 
 import (
 	"context"
+	"math/rand"
 	"runtime"
 	"sync"
 	"time"
 
 	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/internal/zslog"
 
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
 	"github.com/jackc/puddle/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
 )
 
 // Pool of connections to ClickHouse.
 type Pool struct {
 	pool    *puddle.Pool[*connResource]
 	options Options
+	metrics metric.Registration
+	lg      *zap.Logger
 
 	closeOnce sync.Once
 	closeChan chan struct{}
@@ -22,12 +31,43 @@ type Pool struct {
 
 // Options for Pool.
 type Options struct {
-	ClientOptions     ch.Options
-	MaxConnLifetime   time.Duration
-	MaxConnIdleTime   time.Duration
-	MaxConns          int32
-	MinConns          int32
-	HealthCheckPeriod time.Duration
+	ClientOptions   ch.Options
+	MaxConnLifetime time.Duration
+	// MaxConnLifetimeJitter is maximum random duration that is added to
+	// MaxConnLifetime of each connection, so connections are not recycled
+	// at the same moment.
+	MaxConnLifetimeJitter time.Duration
+	MaxConnIdleTime       time.Duration
+	MaxConns              int32
+	MinConns              int32
+	HealthCheckPeriod     time.Duration
+	// HealthCheckPing enables pinging idle connections on health check,
+	// so broken connections are destroyed and replaced.
+	HealthCheckPing bool
+	// ValidateIdleTime enables pinging connection on Acquire if it was idle
+	// for longer than ValidateIdleTime, so broken connection is replaced
+	// instead of being returned. Zero disables validation.
+	ValidateIdleTime time.Duration
+	// PingTimeout is timeout of ping for validation and health checks.
+	PingTimeout time.Duration
+	// MetricAttributes are added to pool metrics along with server
+	// address, so multiple pools can share MeterProvider.
+	MetricAttributes []attribute.KeyValue
+
+	// AfterConnect is called after connection is established, e.g. to
+	// check ServerInfo or create temporary tables. Connection is closed
+	// if error is returned.
+	AfterConnect func(ctx context.Context, c *ch.Client) error
+	// BeforeAcquire is called before connection is acquired from pool.
+	// Connection is destroyed if error is returned, and another one
+	// is acquired. Error is logged with ClientOptions.Logger.
+	BeforeAcquire func(ctx context.Context, c *ch.Client) error
+	// AfterRelease is called after connection is released, before it
+	// is returned to pool. Connection is destroyed if error is returned.
+	// Error is logged with ClientOptions.Logger.
+	AfterRelease func(c *ch.Client) error
+	// BeforeClose is called before connection is closed.
+	BeforeClose func(c *ch.Client)
 }
 
 // Defaults for pool.
@@ -35,6 +75,7 @@ const (
 	DefaultMaxConnLifetime   = time.Hour
 	DefaultMaxConnIdleTime   = time.Minute * 30
 	DefaultHealthCheckPeriod = time.Minute
+	DefaultPingTimeout       = time.Second * 5
 )
 
 func (o *Options) setDefaults() {
@@ -50,6 +91,21 @@ func (o *Options) setDefaults() {
 	if o.HealthCheckPeriod == 0 {
 		o.HealthCheckPeriod = DefaultHealthCheckPeriod
 	}
+	if o.PingTimeout == 0 {
+		o.PingTimeout = DefaultPingTimeout
+	}
+	if o.ClientOptions.Logger == nil && o.ClientOptions.LogHandler != nil {
+		// Same as ch.Dial, but shared by pool and its connections.
+		o.ClientOptions.Logger = zslog.New(o.ClientOptions.LogHandler)
+	}
+}
+
+// connLifetime returns MaxConnLifetime with random jitter.
+func (o *Options) connLifetime() time.Duration {
+	if o.MaxConnLifetimeJitter <= 0 {
+		return o.MaxConnLifetime
+	}
+	return o.MaxConnLifetime + time.Duration(rand.Int63n(int64(o.MaxConnLifetimeJitter)))
 }
 
 // Dial returns a pool of connections to ClickHouse.
@@ -67,21 +123,38 @@ func newPool(ctx context.Context, opt Options, dial bool) (*Pool, error) {
 	opt.setDefaults()
 	p := &Pool{
 		options:   opt,
+		lg:        opt.ClientOptions.Logger,
 		closeChan: make(chan struct{}),
 	}
+	if p.lg == nil {
+		p.lg = zap.NewNop()
+	}
 	puddleConfig := &puddle.Config[*connResource]{
 		Constructor: func(ctx context.Context) (*connResource, error) {
-			c, err := ch.Dial(ctx, p.options.ClientOptions)
+			// Interceptors are called by pool, see Pool.Do and Client.Do.
+			clientOptions := p.options.ClientOptions
+			clientOptions.Interceptors = nil
+			c, err := ch.Dial(ctx, clientOptions)
 			if err != nil {
 				return nil, err
 			}
+			if f := p.options.AfterConnect; f != nil {
+				if err := f(ctx, c); err != nil {
+					_ = c.Close()
+					return nil, errors.Wrap(err, "after connect")
+				}
+			}
 
 			return &connResource{
-				client:  c,
-				clients: make([]Client, 64),
+				client:    c,
+				clients:   make([]Client, 64),
+				expiresAt: time.Now().Add(p.options.connLifetime()),
 			}, nil
 		},
 		Destructor: func(c *connResource) {
+			if f := p.options.BeforeClose; f != nil {
+				f(c.client)
+			}
 			_ = c.client.Close()
 		},
 		MaxSize: opt.MaxConns,
@@ -93,6 +166,11 @@ func newPool(ctx context.Context, opt Options, dial bool) (*Pool, error) {
 	}
 	p.pool = pool
 
+	if p.metrics, err = p.registerMetrics(); err != nil {
+		p.Close()
+		return nil, errors.Wrap(err, "metrics")
+	}
+
 	if err := p.createIdleResources(ctx, int(p.options.MinConns)); err != nil {
 		p.Close()
 		return nil, err
@@ -113,23 +191,68 @@ func newPool(ctx context.Context, opt Options, dial bool) (*Pool, error) {
 }
 
 // Acquire connection from pool.
+//
+// Connections that failed validation or were rejected by BeforeAcquire
+// are destroyed and replaced, see Options.ValidateIdleTime.
 func (p *Pool) Acquire(ctx context.Context) (*Client, error) {
-	res, err := p.pool.Acquire(ctx)
-	if err != nil {
-		return nil, err
+	for {
+		res, err := p.pool.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cr := res.Value()
+		if d := p.options.ValidateIdleTime; d > 0 && res.IdleDuration() > d {
+			if err := p.ping(ctx, cr.client); err != nil {
+				res.Destroy()
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				p.lg.Warn("Connection validation failed", zap.Error(err))
+				continue
+			}
+		}
+		if f := p.options.BeforeAcquire; f != nil {
+			if err := f(ctx, cr.client); err != nil {
+				p.rejected("before acquire", err)
+				res.Destroy()
+				continue
+			}
+		}
+
+		return cr.getConn(p, res), nil
 	}
+}
 
-	return res.Value().getConn(p, res), nil
+// rejected logs connection rejected by hook.
+func (p *Pool) rejected(hook string, err error) {
+	p.lg.Warn("Connection rejected", zap.String("hook", hook), zap.Error(err))
 }
 
+// ping connection with PingTimeout.
+func (p *Pool) ping(ctx context.Context, c *ch.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, p.options.PingTimeout)
+	defer cancel()
+
+	return c.Ping(ctx)
+}
+
+// Do acquires connection and performs Query, calling
+// ClientOptions.Interceptors before acquiring.
 func (p *Pool) Do(ctx context.Context, q ch.Query) (err error) {
+	if q.QueryID == "" {
+		q.QueryID = uuid.New().String()
+	}
+	return ch.ChainInterceptors(p.do, p.options.ClientOptions.Interceptors...)(ctx, q)
+}
+
+func (p *Pool) do(ctx context.Context, q ch.Query) error {
 	c, err := p.Acquire(ctx)
 	if err != nil {
 		return err
 	}
 	defer c.Release()
 
-	return c.Do(ctx, q)
+	return c.client().Do(ctx, q)
 }
 
 func (p *Pool) Ping(ctx context.Context) error {
@@ -160,16 +283,29 @@ func (p *Pool) backgroundHealthCheck() {
 func (p *Pool) checkIdleConnsHealth() {
 	resources := p.pool.AcquireAllIdle()
 
+	var wg sync.WaitGroup
 	now := time.Now()
 	for _, res := range resources {
-		if now.Sub(res.CreationTime()) > p.options.MaxConnLifetime {
+		switch {
+		case res.Value().expired(now):
 			res.Destroy()
-		} else if res.IdleDuration() > p.options.MaxConnIdleTime {
+		case res.IdleDuration() > p.options.MaxConnIdleTime:
 			res.Destroy()
-		} else {
+		case p.options.HealthCheckPing:
+			wg.Add(1)
+			go func(res *puddle.Resource[*connResource]) {
+				defer wg.Done()
+				if err := p.ping(context.Background(), res.Value().client); err != nil {
+					res.Destroy()
+					return
+				}
+				res.ReleaseUnused()
+			}(res)
+		default:
 			res.ReleaseUnused()
 		}
 	}
+	wg.Wait()
 }
 
 func (p *Pool) checkMinConns() {
@@ -202,6 +338,9 @@ func (p *Pool) Stat() *puddle.Stat {
 func (p *Pool) Close() {
 	p.closeOnce.Do(func() {
 		close(p.closeChan)
+		if p.metrics != nil {
+			_ = p.metrics.Unregister()
+		}
 		p.pool.Close()
 	})
 }
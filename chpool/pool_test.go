@@ -2,11 +2,26 @@ package chpool
 
 import (
 	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-faster/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/atomic"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go"
+	"github.com/ClickHouse/ch-go/internal/chtest"
+	"github.com/ClickHouse/ch-go/otelch"
 )
 
 func TestDial(t *testing.T) {
@@ -78,3 +93,374 @@ func TestPool_Acquire(t *testing.T) {
 	waitForReleaseToComplete()
 	require.EqualValues(t, 2, p.Stat().AcquireCount())
 }
+
+func TestPool_Credentials(t *testing.T) {
+	t.Parallel()
+	var password atomic.String
+	password.Store("first")
+	addr := chtest.Server(t, ch.ServerOptions{
+		Authenticate: func(c ch.Credentials) error {
+			if c.Password != password.Load() {
+				return errors.New("bad password")
+			}
+			return nil
+		},
+	})
+
+	var calls atomic.Int64
+	ctx := context.Background()
+	p, err := Dial(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:  zaptest.NewLogger(t).Named("usr"),
+			Address: addr.String(),
+			Credentials: ch.CredentialsFunc(func(ctx context.Context) (ch.Credentials, error) {
+				calls.Inc()
+				return ch.Credentials{Password: password.Load()}, nil
+			}),
+		},
+		// Reconnecting on every release.
+		MaxConnLifetime: time.Nanosecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+
+	require.NoError(t, p.Ping(ctx))
+	password.Store("second")
+	require.NoError(t, p.Ping(ctx))
+	require.Equal(t, int64(2), calls.Load())
+}
+
+func TestPool_Interceptors(t *testing.T) {
+	t.Parallel()
+	addr := testServer(t)
+
+	var (
+		calls     atomic.Int64
+		errDenied = errors.New("denied")
+	)
+	ctx := context.Background()
+	p, err := Dial(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:  zaptest.NewLogger(t).Named("usr"),
+			Address: addr,
+			Interceptors: []ch.Interceptor{
+				func(ctx context.Context, q ch.Query, next ch.Invoker) error {
+					calls.Inc()
+					if q.Body == "DROP TABLE t" {
+						return errDenied
+					}
+					return next(ctx, q)
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+	acquired := p.Stat().AcquireCount()
+
+	require.NoError(t, p.Do(ctx, ch.Query{Body: "SELECT 1"}))
+	require.Equal(t, int64(1), calls.Load(), "should be called once per query")
+
+	require.ErrorIs(t, p.Do(ctx, ch.Query{Body: "DROP TABLE t"}), errDenied)
+	require.Equal(t, int64(2), calls.Load())
+	require.Equal(t, acquired+1, p.Stat().AcquireCount(), "short-circuit should not acquire")
+
+	c, err := p.Acquire(ctx)
+	require.NoError(t, err)
+	defer c.Release()
+	require.ErrorIs(t, c.Do(ctx, ch.Query{Body: "DROP TABLE t"}), errDenied)
+	require.NoError(t, c.Do(ctx, ch.Query{Body: "SELECT 1"}))
+	require.Equal(t, int64(4), calls.Load())
+}
+
+func TestPool_Metrics(t *testing.T) {
+	t.Parallel()
+	server := testServer(t)
+
+	reader := sdkmetric.NewManualReader()
+	ctx := context.Background()
+	p, err := Dial(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:        zaptest.NewLogger(t).Named("usr"),
+			Address:       server,
+			MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		},
+		MaxConns: 3,
+	})
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+
+	c, err := p.Acquire(ctx)
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			var attrs attribute.Set
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				got[m.Name] = data.DataPoints[0].Value
+				attrs = data.DataPoints[0].Attributes
+			case metricdata.Sum[int64]:
+				got[m.Name] = data.DataPoints[0].Value
+				attrs = data.DataPoints[0].Attributes
+			default:
+				continue
+			}
+			if strings.HasPrefix(m.Name, "ch.pool.") {
+				addr, _ := attrs.Value(otelch.ServerAddressKey)
+				assert.Equal(t, server, addr.AsString(), m.Name)
+			}
+		}
+	}
+	assert.Equal(t, int64(1), got[otelch.MetricPoolAcquired])
+	assert.Equal(t, int64(0), got[otelch.MetricPoolIdle])
+	assert.Equal(t, int64(1), got[otelch.MetricPoolTotal])
+	assert.Equal(t, int64(3), got[otelch.MetricPoolMax])
+	assert.Equal(t, p.Stat().AcquireCount(), got[otelch.MetricPoolAcquires])
+	assert.Equal(t, p.Stat().EmptyAcquireCount(), got[otelch.MetricPoolEmptyAcquires])
+	assert.Contains(t, got, otelch.MetricPoolCanceledAcquire)
+
+	// Unregistered on close.
+	c.Release()
+	p.Close()
+	rm = metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			require.False(t, strings.HasPrefix(m.Name, "ch.pool."), "%s should be unregistered", m.Name)
+		}
+	}
+}
+
+// breakingDialer tracks dialed connections, allowing to break them
+// without client noticing, like half-open connections.
+type breakingDialer struct {
+	mux   sync.Mutex
+	conns []net.Conn
+}
+
+func (d *breakingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	d.mux.Lock()
+	d.conns = append(d.conns, conn)
+	d.mux.Unlock()
+	return conn, nil
+}
+
+func (d *breakingDialer) Dials() int {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return len(d.conns)
+}
+
+func (d *breakingDialer) Break() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	for _, conn := range d.conns {
+		_ = conn.Close()
+	}
+}
+
+func testServer(t *testing.T) string {
+	t.Helper()
+	return chtest.Server(t, ch.ServerOptions{}).String()
+}
+
+func TestPool_ValidateIdleTime(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dialer := &breakingDialer{}
+	p, err := Dial(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:  zaptest.NewLogger(t).Named("usr"),
+			Address: testServer(t),
+			Dialer:  dialer,
+		},
+		ValidateIdleTime: time.Nanosecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+	require.Equal(t, 1, dialer.Dials())
+
+	dialer.Break()
+	require.NoError(t, p.Ping(ctx), "broken connection should be replaced")
+	require.Equal(t, 2, dialer.Dials())
+	require.NoError(t, p.Do(ctx, ch.Query{Body: "SELECT 1"}))
+}
+
+func TestPool_HealthCheckPing(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dialer := &breakingDialer{}
+	p, err := Dial(ctx, Options{
+		ClientOptions: ch.Options{
+			Logger:  zaptest.NewLogger(t).Named("usr"),
+			Address: testServer(t),
+			Dialer:  dialer,
+		},
+		MinConns:          2,
+		MaxConns:          2,
+		HealthCheckPeriod: time.Millisecond * 10,
+		HealthCheckPing:   true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+	require.Equal(t, 2, dialer.Dials())
+
+	dialer.Break()
+	require.Eventually(t, func() bool {
+		return dialer.Dials() >= 4 && p.Stat().TotalResources() == 2
+	}, time.Second*5, time.Millisecond*10, "broken connections should be replaced")
+	require.NoError(t, p.Ping(ctx))
+}
+
+func TestOptions_connLifetime(t *testing.T) {
+	t.Parallel()
+	opt := Options{MaxConnLifetime: time.Minute}
+	require.Equal(t, time.Minute, opt.connLifetime())
+
+	opt.MaxConnLifetimeJitter = time.Second
+	seen := map[time.Duration]struct{}{}
+	for i := 0; i < 100; i++ {
+		d := opt.connLifetime()
+		require.GreaterOrEqual(t, d, time.Minute)
+		require.Less(t, d, time.Minute+time.Second)
+		seen[d] = struct{}{}
+	}
+	require.Greater(t, len(seen), 1, "should be randomized")
+}
+
+func TestPool_Hooks(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	addr := testServer(t)
+
+	t.Run("AfterConnect", func(t *testing.T) {
+		errVersion := errors.New("server is too old")
+		_, err := Dial(ctx, Options{
+			ClientOptions: ch.Options{
+				Logger:  zaptest.NewLogger(t).Named("usr"),
+				Address: addr,
+			},
+			AfterConnect: func(ctx context.Context, c *ch.Client) error {
+				if c.ServerInfo().Major < 100 {
+					return errVersion
+				}
+				return nil
+			},
+		})
+		require.ErrorIs(t, err, errVersion)
+	})
+	t.Run("Lifecycle", func(t *testing.T) {
+		var (
+			connected atomic.Int64
+			acquired  atomic.Int64
+			released  atomic.Int64
+			closed    atomic.Int64
+			reject    atomic.Bool
+		)
+		p, err := Dial(ctx, Options{
+			ClientOptions: ch.Options{
+				Logger:  zaptest.NewLogger(t).Named("usr"),
+				Address: addr,
+			},
+			MaxConns: 1,
+			AfterConnect: func(ctx context.Context, c *ch.Client) error {
+				connected.Inc()
+				return c.Ping(ctx)
+			},
+			BeforeAcquire: func(ctx context.Context, c *ch.Client) error {
+				acquired.Inc()
+				if reject.CompareAndSwap(true, false) {
+					return errors.New("rejected")
+				}
+				return nil
+			},
+			AfterRelease: func(c *ch.Client) error {
+				if released.Inc() == 2 {
+					return errors.New("rejected")
+				}
+				return nil
+			},
+			BeforeClose: func(c *ch.Client) {
+				closed.Inc()
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), connected.Load())
+
+		// Rejected by BeforeAcquire and replaced.
+		reject.Store(true)
+		require.NoError(t, p.Ping(ctx))
+		require.Equal(t, int64(2), connected.Load())
+		require.Equal(t, int64(2), acquired.Load())
+		require.Equal(t, int64(1), released.Load())
+		require.Eventually(t, func() bool { return closed.Load() == 1 }, time.Second, time.Millisecond)
+
+		// Rejected by AfterRelease.
+		require.NoError(t, p.Ping(ctx))
+		require.Eventually(t, func() bool { return closed.Load() == 2 }, time.Second, time.Millisecond)
+		require.Eventually(t, func() bool { return p.Stat().TotalResources() == 0 }, time.Second, time.Millisecond)
+
+		require.NoError(t, p.Ping(ctx))
+		require.Equal(t, int64(3), connected.Load())
+		p.Close()
+		require.Equal(t, int64(3), closed.Load())
+	})
+}
+
+// recordHandler records messages of slog records.
+type recordHandler struct {
+	slog.Handler
+	mux      sync.Mutex
+	messages []string
+}
+
+func (h *recordHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.messages = append(h.messages, r.Message)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) Messages() []string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+func TestPool_LogHandler(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h := &recordHandler{
+		Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	}
+	var reject atomic.Bool
+	reject.Store(true)
+	p, err := Dial(ctx, Options{
+		ClientOptions: ch.Options{
+			Address:    testServer(t),
+			LogHandler: h,
+		},
+		BeforeAcquire: func(ctx context.Context, c *ch.Client) error {
+			if reject.CompareAndSwap(true, false) {
+				return errors.New("rejected")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Ping(ctx))
+	require.Equal(t, []string{"Connection rejected"}, h.Messages())
+}
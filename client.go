@@ -5,10 +5,12 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-faster/errors"
@@ -17,9 +19,11 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/ClickHouse/ch-go/compress"
 	pkgVersion "github.com/ClickHouse/ch-go/internal/version"
+	"github.com/ClickHouse/ch-go/internal/zslog"
 	"github.com/ClickHouse/ch-go/otelch"
 	"github.com/ClickHouse/ch-go/proto"
 )
@@ -36,15 +40,46 @@ type Client struct {
 	version  clientVersion
 	quotaKey string
 
+	// SSH key authentication, see Options.SSHSigner.
+	user      string
+	sshSigner ssh.Signer
+
+	// forwardServerLogs enables writing server logs to query logger,
+	// see Options.ForwardServerLogs.
+	forwardServerLogs bool
+
+	// invoke is Client.do wrapped with Options.Interceptors.
+	invoke Invoker
+
+	// Chunked framing settings, see Options.ChunkedSend.
+	chunkedSend string
+	chunkedRecv string
+	// chunked reports whether chunked framing is used for sending.
+	chunked bool
+
 	mux    sync.Mutex
 	closed bool
 
+	// writeMux serializes writes to conn, because packets can be sent
+	// from receiving goroutine, like proto.ReadTaskResponse.
+	writeMux sync.Mutex
+
 	// Single packet read timeout.
 	readTimeout time.Duration
 
-	otel   bool
-	tracer trace.Tracer
-	meter  metric.Meter
+	otel    bool
+	tracer  trace.Tracer
+	meter   metric.Meter
+	metrics *clientMetrics
+
+	// Span events of query, see SpanEvents.
+	spanEvents      SpanEvents
+	querySpan       trace.Span
+	progressEventAt time.Time
+	// progressPending is sum of progress packets since last progress
+	// event, see SpanEvents.ProgressInterval.
+	progressPending proto.Progress
+	serverSpans     ServerSpans
 
 	// TCP Binary protocol version.
 	protocolVersion int
@@ -56,6 +91,14 @@ type Client struct {
 	compressionMethod compress.Method
 
 	settings []Setting
+
+	// Session time zone of current query, set by server, see
+	// proto.ServerCodeTimezoneUpdate.
+	sessionTimezone *time.Location
+
+	// noMetrics is set during internal queries, like ones of ServerSpans,
+	// so sent and received bytes are not reported.
+	noMetrics atomic.Bool
 }
 
 // Setting to send to server.
@@ -155,7 +198,7 @@ func IsException(err error) bool {
 }
 
 // Exception reads exception from server.
-func (c *Client) exception() (*Exception, error) {
+func (c *Client) exception(ctx context.Context) (*Exception, error) {
 	var list []proto.Exception
 	for {
 		var ex proto.Exception
@@ -169,6 +212,12 @@ func (c *Client) exception() (*Exception, error) {
 		}
 	}
 	top := list[0]
+	if !c.noMetrics.Load() {
+		c.metrics.exceptions.Add(ctx, 1, c.metrics.with(
+			otelch.ErrorCode(int(top.Code)),
+			otelch.ErrorName(top.Name),
+		))
+	}
 	e := &Exception{
 		Code:    top.Code,
 		Name:    top.Name,
@@ -262,6 +311,8 @@ func (c *Client) flushBuf(ctx context.Context, b *proto.Buffer) error {
 		// Nothing to flush.
 		return nil
 	}
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
 	if deadline, ok := ctx.Deadline(); ok {
 		if err := c.conn.SetWriteDeadline(deadline); err != nil {
 			return errors.Wrap(err, "set write deadline")
@@ -269,6 +320,20 @@ func (c *Client) flushBuf(ctx context.Context, b *proto.Buffer) error {
 		// Reset deadline.
 		defer func() { _ = c.conn.SetWriteDeadline(time.Time{}) }()
 	}
+	if c.chunked {
+		n, err := b.WriteChunked(c.conn)
+		if err != nil {
+			return errors.Wrap(err, "write chunked")
+		}
+		if ce := c.lg.Check(zap.DebugLevel, "Flush"); ce != nil {
+			ce.Write(zap.Int64("bytes", n), zap.Bool("chunked", true))
+		}
+		if !c.noMetrics.Load() {
+			c.metrics.bytesSent.Add(ctx, n, c.metrics.with())
+		}
+		b.Reset()
+		return nil
+	}
 	n, err := c.conn.Write(b.Buf)
 	if err != nil {
 		return errors.Wrap(err, "write")
@@ -279,6 +344,9 @@ func (c *Client) flushBuf(ctx context.Context, b *proto.Buffer) error {
 	if ce := c.lg.Check(zap.DebugLevel, "Flush"); ce != nil {
 		ce.Write(zap.Int("bytes", n))
 	}
+	if !c.noMetrics.Load() {
+		c.metrics.bytesSent.Add(ctx, int64(n), c.metrics.with())
+	}
 	b.Reset()
 	return nil
 }
@@ -321,6 +389,32 @@ type Options struct {
 	ClientName  string      // blank string by default
 	Settings    []Setting   // none by default
 
+	// LogHandler is used for logging if Logger is not set, e.g.
+	// slog.Default().Handler().
+	LogHandler slog.Handler
+
+	// ServerLogLevel sets "send_logs_level" setting if not set explicitly
+	// in Settings, so server sends logs of queries with at least that level.
+	ServerLogLevel ServerLogLevel
+	// ForwardServerLogs enables writing server logs to query logger,
+	// mapping log priority to logger level. Logs are written with
+	// thread_id, source, host and query_id fields.
+	ForwardServerLogs bool
+
+	// SSHSigner enables authentication with SSH key instead of Password,
+	// e.g. signer from ssh.ParsePrivateKey.
+	SSHSigner ssh.Signer
+	// Credentials are requested on every connection if set, overriding
+	// User and Password.
+	Credentials CredentialsProvider
+
+	// Interceptors are called on every Client.Do in order, so first one
+	// is outermost, see Interceptor.
+	//
+	// In chpool, interceptors are called by Pool.Do before connection is
+	// acquired, so short-circuit does not require connection.
+	Interceptors []Interceptor
+
 	// ReadTimeout is a timeout for reading a single packet from the server.
 	//
 	// Defaults to 3s. No timeout if negative (you can use NoTimeout const).
@@ -330,6 +424,15 @@ type Options struct {
 	DialTimeout time.Duration // defaults to 1s
 	TLS         *tls.Config   // no TLS is used by default
 
+	// ChunkedSend and ChunkedRecv are chunked packets framing settings
+	// for sending and receiving, like proto.ChunkedOptional. Chunked framing
+	// is negotiated with server during handshake.
+	//
+	// Default is proto.NotChunkedOptional, so classic stream is used
+	// unless server requires chunked framing.
+	ChunkedSend string
+	ChunkedRecv string
+
 	ProtocolVersion  int           // force protocol version, optional
 	HandshakeTimeout time.Duration // longer lasting handshake is a case for ClickHouse cloud idle instances, defaults to 5m
 
@@ -339,7 +442,16 @@ type Options struct {
 	// Note: OpenTelemetry context propagation works without this option too.
 	OpenTelemetryInstrumentation bool
 	TracerProvider               trace.TracerProvider
-	MeterProvider                metric.MeterProvider
+	// SpanEvents configures events of query span.
+	SpanEvents SpanEvents
+	// ServerSpans configures import of server spans into query trace.
+	ServerSpans ServerSpans
+	// MeterProvider is used for client metrics, like query duration,
+	// rows and bytes sent or received, which are recorded regardless of
+	// OpenTelemetryInstrumentation. Also used by chpool for pool metrics.
+	//
+	// Default is otel.GetMeterProvider().
+	MeterProvider metric.MeterProvider
 
 	meter  metric.Meter
 	tracer trace.Tracer
@@ -366,12 +478,21 @@ func (o *Options) setDefaults() {
 	if o.HandshakeTimeout == 0 {
 		o.HandshakeTimeout = DefaultHandshakeTimeout
 	}
+	if o.ChunkedSend == "" {
+		o.ChunkedSend = proto.NotChunkedOptional
+	}
+	if o.ChunkedRecv == "" {
+		o.ChunkedRecv = proto.NotChunkedOptional
+	}
 	if o.Database == "" {
 		o.Database = DefaultDatabase
 	}
 	if o.User == "" {
 		o.User = DefaultUser
 	}
+	if o.Logger == nil && o.LogHandler != nil {
+		o.Logger = zslog.New(o.LogHandler)
+	}
 	if o.Logger == nil {
 		o.Logger = zap.NewNop()
 	}
@@ -406,6 +527,22 @@ func (o *Options) setDefaults() {
 	if o.ReadTimeout < 0 || o.ReadTimeout == NoTimeout {
 		o.ReadTimeout = 0
 	}
+	if o.ServerLogLevel != "" {
+		explicit := false
+		for _, s := range o.Settings {
+			if s.Key == settingSendLogsLevel {
+				explicit = true
+				break
+			}
+		}
+		if !explicit {
+			// Copying to prevent modification of caller's slice.
+			o.Settings = append(o.Settings[:len(o.Settings):len(o.Settings)], Setting{
+				Key:   settingSendLogsLevel,
+				Value: string(o.ServerLogLevel),
+			})
+		}
+	}
 }
 
 type clientVersion struct {
@@ -436,6 +573,24 @@ func Connect(ctx context.Context, conn net.Conn, opt Options) (*Client, error) {
 		Patch: pkg.Patch,
 	}
 
+	creds := Credentials{
+		User:     opt.User,
+		Password: opt.Password,
+	}
+	if opt.Credentials != nil {
+		v, err := opt.Credentials.Credentials(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "credentials")
+		}
+		if v.User == "" {
+			v.User = DefaultUser
+		}
+		creds = v
+	}
+	if creds.JWT != "" && opt.SSHSigner != nil {
+		return nil, errors.New("JWT and SSH key authentication are mutually exclusive")
+	}
+
 	if opt.OpenTelemetryInstrumentation {
 		newCtx, span := opt.tracer.Start(ctx, "Connect",
 			trace.WithSpanKind(trace.SpanKindClient),
@@ -446,17 +601,32 @@ func Connect(ctx context.Context, conn net.Conn, opt Options) (*Client, error) {
 		ctx = newCtx
 		defer span.End()
 	}
+	metrics, err := newClientMetrics(opt.meter, otelch.ServerAddress(opt.Address))
+	if err != nil {
+		return nil, errors.Wrap(err, "metrics")
+	}
 	c := &Client{
 		conn:     conn,
 		buf:      new(proto.Buffer),
-		reader:   proto.NewReader(conn),
 		settings: opt.Settings,
 		lg:       opt.Logger,
 		otel:     opt.OpenTelemetryInstrumentation,
 		tracer:   opt.tracer,
 		meter:    opt.meter,
+		metrics:  metrics,
 		quotaKey: opt.QuotaKey,
 
+		spanEvents:  opt.SpanEvents,
+		serverSpans: opt.ServerSpans,
+
+		forwardServerLogs: opt.ForwardServerLogs,
+
+		user:      creds.User,
+		sshSigner: opt.SSHSigner,
+
+		chunkedSend: opt.ChunkedSend,
+		chunkedRecv: opt.ChunkedRecv,
+
 		readTimeout: opt.ReadTimeout,
 
 		compressor: compress.NewWriter(),
@@ -471,10 +641,27 @@ func Connect(ctx context.Context, conn net.Conn, opt Options) (*Client, error) {
 			ProtocolVersion: opt.ProtocolVersion,
 
 			Database: opt.Database,
-			User:     opt.User,
-			Password: opt.Password,
+			User:     creds.User,
+			Password: creds.Password,
 		},
 	}
+	c.reader = proto.NewReader(countingReader{
+		r:       conn,
+		counter: metrics.bytesReceived,
+		attrs:   metrics.with(),
+		skip:    &c.noMetrics,
+	})
+	switch {
+	case opt.SSHSigner != nil:
+		// Password is not used with SSH key.
+		c.info.User = proto.SSHKeyAuthMarker + creds.User
+		c.info.Password = ""
+	case creds.JWT != "":
+		c.user = ""
+		c.info.User = proto.JWTAuthMarker
+		c.info.Password = creds.JWT
+	}
+	c.invoke = ChainInterceptors(c.do, opt.Interceptors...)
 	switch opt.Compression {
 	case CompressionLZ4:
 		c.compression = proto.CompressionEnabled
@@ -491,7 +678,10 @@ func Connect(ctx context.Context, conn net.Conn, opt Options) (*Client, error) {
 
 	handshakeCtx, cancel := context.WithTimeout(ctx, opt.HandshakeTimeout)
 	defer cancel()
-	if err := c.handshake(handshakeCtx); err != nil {
+	start := time.Now()
+	err = c.handshake(handshakeCtx)
+	c.metrics.recordDuration(ctx, c.metrics.handshakeDuration, start, err)
+	if err != nil {
 		return nil, errors.Wrap(err, "handshake")
 	}
 
@@ -545,6 +735,7 @@ func Dial(ctx context.Context, opt Options) (c *Client, err error) {
 
 	client, err := Connect(ctx, conn, opt)
 	if err != nil {
+		_ = conn.Close()
 		return nil, errors.Wrap(err, "connect")
 	}
 
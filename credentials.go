@@ -0,0 +1,33 @@
+package ch
+
+import "context"
+
+// Credentials to authenticate on server.
+type Credentials struct {
+	User     string // defaults to "default"
+	Password string
+	// JWT is token for authentication instead of User and Password,
+	// supported by ClickHouse Cloud.
+	JWT string
+}
+
+// CredentialsProvider provides Credentials on every connection, e.g. to
+// rotate passwords from secret store or refresh tokens.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsFunc is functional CredentialsProvider.
+type CredentialsFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials calls f(ctx).
+func (f CredentialsFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials returns CredentialsProvider of c.
+func StaticCredentials(c Credentials) CredentialsProvider {
+	return CredentialsFunc(func(ctx context.Context) (Credentials, error) {
+		return c, nil
+	})
+}
@@ -2,21 +2,89 @@ package ch
 
 import (
 	"context"
+	"crypto/rand"
 
 	"github.com/go-faster/errors"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/ClickHouse/ch-go/otelch"
 	"github.com/ClickHouse/ch-go/proto"
 )
 
-func (c *Client) encodeAddendum() {
-	if proto.FeatureQuotaKey.In(c.protocolVersion) {
-		c.buf.PutString(c.quotaKey)
+func (c *Client) encodeAddendum(send, recv bool) {
+	a := proto.Addendum{
+		QuotaKey:                 c.quotaKey,
+		ChunkedSend:              proto.NotChunked,
+		ChunkedRecv:              proto.NotChunked,
+		ParallelReplicasProtocol: proto.ParallelReplicasProtocolVersion,
 	}
+	if send {
+		a.ChunkedSend = proto.Chunked
+	}
+	if recv {
+		a.ChunkedRecv = proto.Chunked
+	}
+	a.EncodeAware(c.buf, c.protocolVersion)
+}
+
+// negotiateChunked resolves chunked framing for sending and receiving
+// from client options and ServerHello.
+func (c *Client) negotiateChunked() (send, recv bool, err error) {
+	if !proto.FeatureChunkedPackets.In(c.protocolVersion) {
+		return false, false, nil
+	}
+	if send, err = proto.NegotiateChunked(c.chunkedSend, c.server.ChunkedRecv); err != nil {
+		return false, false, errors.Wrap(err, "send")
+	}
+	if recv, err = proto.NegotiateChunked(c.chunkedRecv, c.server.ChunkedSend); err != nil {
+		return false, false, errors.Wrap(err, "recv")
+	}
+	return send, recv, nil
+}
+
+// sshAuth signs SSH challenge requested after ClientHello.
+func (c *Client) sshAuth(ctx context.Context) error {
+	code, err := c.packet(ctx)
+	if err != nil {
+		return errors.Wrap(err, "packet")
+	}
+	switch code {
+	case proto.ServerCodeSSHChallenge:
+	case proto.ServerCodeException:
+		e, err := c.exception(ctx)
+		if err != nil {
+			return errors.Wrap(err, "decode exception")
+		}
+		return errors.Wrap(e, "exception")
+	case proto.ServerCodeHello:
+		// Old servers ignore challenge request.
+		return errors.New("server does not support SSH authentication")
+	default:
+		return errors.Errorf("got %s instead of %s", code, proto.ServerCodeSSHChallenge)
+	}
+	var challenge proto.SSHChallenge
+	if err := challenge.Decode(c.reader); err != nil {
+		return errors.Wrap(err, "decode challenge")
+	}
+	msg := challenge.Message(c.info.ProtocolVersion, c.info.Database, c.user)
+	sig, err := c.sshSigner.Sign(rand.Reader, msg)
+	if err != nil {
+		return errors.Wrap(err, "sign")
+	}
+	if ce := c.lg.Check(zap.DebugLevel, "Signed SSH challenge"); ce != nil {
+		ce.Write(zap.String("format", sig.Format))
+	}
+
+	resp := proto.SSHChallengeResponse{Signature: ssh.Marshal(sig)}
+	resp.Encode(c.buf)
+	if err := c.flush(ctx); err != nil {
+		return errors.Wrap(err, "flush")
+	}
+	return nil
 }
 
 func (c *Client) handshake(ctx context.Context) error {
@@ -45,11 +113,22 @@ func (c *Client) handshake(ctx context.Context) error {
 	wg.Go(func() error {
 		defer cancel()
 
+		if c.sshSigner != nil && !proto.FeatureSSHAuthentication.In(c.protocolVersion) {
+			return errors.Errorf("SSH authentication is not supported in protocol version %d", c.protocolVersion)
+		}
 		c.buf.Reset()
 		c.info.Encode(c.buf)
+		if c.sshSigner != nil {
+			proto.ClientCodeSSHChallengeRequest.Encode(c.buf)
+		}
 		if err := c.flush(wgCtx); err != nil {
 			return errors.Wrap(err, "flush")
 		}
+		if c.sshSigner != nil {
+			if err := c.sshAuth(wgCtx); err != nil {
+				return errors.Wrap(err, "ssh auth")
+			}
+		}
 
 		code, err := c.packet(ctx)
 		if err != nil {
@@ -57,7 +136,7 @@ func (c *Client) handshake(ctx context.Context) error {
 		}
 		if code == proto.ServerCodeException {
 			// Bad password, etc.
-			e, err := c.exception()
+			e, err := c.exception(ctx)
 			if err != nil {
 				return errors.Wrap(err, "decode exception")
 			}
@@ -97,13 +176,25 @@ func (c *Client) handshake(ctx context.Context) error {
 				otelch.ProtocolVersion(c.protocolVersion),
 			)
 		}
+		send, recv, err := c.negotiateChunked()
+		if err != nil {
+			return errors.Wrap(err, "chunked")
+		}
 		if proto.FeatureAddendum.In(c.protocolVersion) {
 			c.lg.Debug("Writing addendum")
-			c.encodeAddendum()
+			c.encodeAddendum(send, recv)
 			if err := c.flush(wgCtx); err != nil {
 				return errors.Wrap(err, "flush")
 			}
 		}
+		// Chunked framing is used after handshake.
+		if recv {
+			c.reader.EnableChunked()
+		}
+		c.chunked = send
+		if send || recv {
+			c.lg.Debug("Chunked framing", zap.Bool("send", send), zap.Bool("recv", recv))
+		}
 
 		return nil
 	})
@@ -0,0 +1,29 @@
+package ch
+
+import "context"
+
+// Invoker performs Query, like Client.Do.
+type Invoker func(ctx context.Context, q Query) error
+
+// Interceptor intercepts query execution, similar to gRPC unary interceptor.
+//
+// Interceptor can modify Query before passing it to next, wrap handlers
+// like OnResult to observe results, inspect error returned by next or
+// short-circuit by not calling next at all.
+//
+// Interceptors are called before OpenTelemetry instrumentation of Client,
+// so span of query is child of spans started by interceptors and
+// reflects modified Query.
+type Interceptor func(ctx context.Context, q Query, next Invoker) error
+
+// ChainInterceptors returns Invoker that calls interceptors in order and
+// then invoker, so first interceptor is outermost one.
+func ChainInterceptors(invoker Invoker, interceptors ...Interceptor) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, q Query) error {
+			return interceptor(ctx, q, next)
+		}
+	}
+	return invoker
+}
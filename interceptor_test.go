@@ -0,0 +1,125 @@
+package ch
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+
+	"github.com/ClickHouse/ch-go/internal/ztest"
+)
+
+func TestChainInterceptors(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, q Query, next Invoker) error {
+			calls = append(calls, name+":"+q.Body)
+			q.Body += " " + name
+			return next(ctx, q)
+		}
+	}
+	invoker := func(ctx context.Context, q Query) error {
+		calls = append(calls, "invoker:"+q.Body)
+		return nil
+	}
+	t.Run("Order", func(t *testing.T) {
+		calls = nil
+		do := ChainInterceptors(invoker, record("a"), record("b"))
+		require.NoError(t, do(ctx, Query{Body: "SELECT 1"}))
+		require.Equal(t, []string{
+			"a:SELECT 1",
+			"b:SELECT 1 a",
+			"invoker:SELECT 1 a b",
+		}, calls)
+	})
+	t.Run("ShortCircuit", func(t *testing.T) {
+		calls = nil
+		errDenied := errors.New("denied")
+		do := ChainInterceptors(invoker, record("a"), func(ctx context.Context, q Query, next Invoker) error {
+			return errDenied
+		}, record("b"))
+		require.ErrorIs(t, do(ctx, Query{Body: "SELECT 1"}), errDenied)
+		require.Equal(t, []string{"a:SELECT 1"}, calls)
+	})
+	t.Run("Empty", func(t *testing.T) {
+		calls = nil
+		require.NoError(t, ChainInterceptors(invoker)(ctx, Query{Body: "SELECT 1"}))
+		require.Equal(t, []string{"invoker:SELECT 1"}, calls)
+	})
+}
+
+func TestClient_Interceptors(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	lg := ztest.NewLogger(t)
+	s := NewServer(ServerOptions{Logger: lg.Named("srv")})
+	go func() { _ = s.Serve(ln) }()
+
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	tracer := tp.Tracer("test")
+
+	var (
+		queryID string
+		errs    []error
+	)
+	c, err := Dial(ctx, Options{
+		Logger:                       lg.Named("usr"),
+		Address:                      ln.Addr().String(),
+		OpenTelemetryInstrumentation: true,
+		TracerProvider:               tp,
+		Interceptors: []Interceptor{
+			func(ctx context.Context, q Query, next Invoker) error {
+				ctx, span := tracer.Start(ctx, "Audit")
+				defer span.End()
+				queryID = q.QueryID
+				err := next(ctx, q)
+				errs = append(errs, err)
+				return err
+			},
+			func(ctx context.Context, q Query, next Invoker) error {
+				if q.Body == "DROP TABLE t" {
+					return errors.New("denied")
+				}
+				q.Body = "/* audited */ " + q.Body
+				return next(ctx, q)
+			},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	connectSpans := len(recorder.Ended())
+
+	require.NoError(t, c.Do(ctx, Query{Body: "SELECT 1"}))
+	require.NotEmpty(t, queryID, "query id should be set before interceptors")
+	require.ErrorContains(t, c.Do(ctx, Query{Body: "DROP TABLE t"}), "denied")
+	require.Len(t, errs, 2)
+	require.NoError(t, errs[0])
+	require.Error(t, errs[1])
+
+	// Query span is child of interceptor span and reflects modified query.
+	var spans []tracesdk.ReadOnlySpan
+	for _, span := range recorder.Ended()[connectSpans:] {
+		if span.Name() == "Send" || span.Name() == "Receive" {
+			// Child spans of query.
+			continue
+		}
+		spans = append(spans, span)
+	}
+	require.Len(t, spans, 3)
+	do, audit := spans[0], spans[1]
+	require.Equal(t, "Do", do.Name())
+	require.Equal(t, "Audit", audit.Name())
+	require.Equal(t, audit.SpanContext().SpanID(), do.Parent().SpanID())
+	require.Contains(t, do.Attributes(), semconv.DBStatementKey.String("/* audited */ SELECT 1"))
+	require.Equal(t, "Audit", spans[2].Name(), "short-circuit should not start query span")
+}
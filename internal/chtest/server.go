@@ -0,0 +1,73 @@
+// Package chtest implements helpers for tests with in-process ch.Server.
+package chtest
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go"
+)
+
+// Server starts ch.Server on random local port, returning its address.
+//
+// Server is stopped on test cleanup, closing connections and waiting for
+// their handlers, so server does not log after test is completed.
+// Logger defaults to zaptest one.
+func Server(t testing.TB, opt ch.ServerOptions) *net.TCPAddr {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if opt.Logger == nil {
+		opt.Logger = zaptest.NewLogger(t).Named("srv")
+	}
+	tl := &trackingListener{Listener: ln}
+	srv := ch.NewServer(opt)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = srv.Serve(tl)
+	}()
+	t.Cleanup(func() {
+		_ = ln.Close()
+		tl.closeConns()
+		<-done
+	})
+	return ln.Addr().(*net.TCPAddr)
+}
+
+// trackingListener tracks accepted connections to close them.
+type trackingListener struct {
+	net.Listener
+
+	mux    sync.Mutex
+	conns  []net.Conn
+	closed bool
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.closed {
+		_ = conn.Close()
+	} else {
+		l.conns = append(l.conns, conn)
+	}
+	return conn, nil
+}
+
+func (l *trackingListener) closeConns() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.closed = true
+	for _, conn := range l.conns {
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,117 @@
+// Package zslog implements zapcore.Core on top of slog.Handler.
+package zslog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggerKey is attribute key for name of zap logger.
+const LoggerKey = "logger"
+
+// New returns new *zap.Logger that writes to h.
+func New(h slog.Handler) *zap.Logger {
+	return zap.New(NewCore(h))
+}
+
+// NewCore returns new zapcore.Core that writes to h.
+//
+// Level checks are delegated to slog.Handler.Enabled, so zap.Logger.Check
+// is still cheap for disabled levels.
+func NewCore(h slog.Handler) zapcore.Core {
+	return &core{h: h}
+}
+
+type core struct {
+	h slog.Handler
+}
+
+// Level maps zap level to slog level.
+func Level(l zapcore.Level) slog.Level {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case l == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case l == zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		// DPanic, Panic and Fatal are more severe than error.
+		return slog.LevelError + slog.Level(l-zapcore.ErrorLevel)
+	}
+}
+
+func (c *core) Enabled(l zapcore.Level) bool {
+	return c.h.Enabled(context.Background(), Level(l))
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	return &core{h: c.h.WithAttrs(attrs(fields))}
+}
+
+func (c *core) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c *core) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(e.Time, Level(e.Level), e.Message, 0)
+	if e.LoggerName != "" {
+		r.AddAttrs(slog.String(LoggerKey, e.LoggerName))
+	}
+	r.AddAttrs(attrs(fields)...)
+	return c.h.Handle(context.Background(), r)
+}
+
+func (c *core) Sync() error { return nil }
+
+func attrs(fields []zapcore.Field) []slog.Attr {
+	out := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		if f.Type == zapcore.SkipType {
+			continue
+		}
+		out = append(out, attr(f))
+	}
+	return out
+}
+
+func attr(f zapcore.Field) slog.Attr {
+	switch f.Type {
+	case zapcore.StringType:
+		return slog.String(f.Key, f.String)
+	case zapcore.BoolType:
+		return slog.Bool(f.Key, f.Integer == 1)
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return slog.Int64(f.Key, f.Integer)
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+		return slog.Uint64(f.Key, uint64(f.Integer))
+	case zapcore.Float64Type:
+		return slog.Float64(f.Key, math.Float64frombits(uint64(f.Integer)))
+	case zapcore.DurationType:
+		return slog.Duration(f.Key, time.Duration(f.Integer))
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return slog.String(f.Key, err.Error())
+		}
+	}
+	// Using zap encoding for other types, e.g. objects, arrays and stringers.
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	if len(enc.Fields) == 1 {
+		for k, v := range enc.Fields {
+			return slog.Any(k, v)
+		}
+	}
+	return slog.Any(f.Key, enc.Fields)
+}
@@ -0,0 +1,69 @@
+package zslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	lg := New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	require.Nil(t, lg.Check(zap.DebugLevel, "Debug"), "debug should be gated")
+	require.NotNil(t, lg.Check(zap.InfoLevel, "Info"))
+
+	lg.Named("usr").With(zap.String("query_id", "1")).Info("Hello",
+		zap.Int("rows", 10),
+		zap.Uint64("bytes", 20),
+		zap.Bool("chunked", true),
+		zap.Float64("ratio", 0.5),
+		zap.Duration("elapsed", time.Second),
+		zap.Error(errors.New("failed")),
+		zap.Stringer("packet", zapcore.InfoLevel),
+		zap.Strings("columns", []string{"a", "b"}),
+		zap.Skip(),
+	)
+
+	var v map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &v))
+	delete(v, "time")
+	require.Equal(t, map[string]any{
+		"level":    "INFO",
+		"msg":      "Hello",
+		"logger":   "usr",
+		"query_id": "1",
+		"rows":     float64(10),
+		"bytes":    float64(20),
+		"chunked":  true,
+		"ratio":    0.5,
+		"elapsed":  float64(time.Second),
+		"error":    "failed",
+		"packet":   "info",
+		"columns":  []any{"a", "b"},
+	}, v)
+}
+
+func TestLevel(t *testing.T) {
+	for _, tt := range []struct {
+		Zap  zapcore.Level
+		Slog slog.Level
+	}{
+		{zapcore.DebugLevel, slog.LevelDebug},
+		{zapcore.InfoLevel, slog.LevelInfo},
+		{zapcore.WarnLevel, slog.LevelWarn},
+		{zapcore.ErrorLevel, slog.LevelError},
+		{zapcore.FatalLevel, slog.LevelError + 3},
+	} {
+		require.Equal(t, tt.Slog, Level(tt.Zap), tt.Zap.String())
+	}
+}
@@ -0,0 +1,138 @@
+package ch
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/ClickHouse/ch-go/otelch"
+)
+
+// Bucket boundaries of histograms, default ones are suited for milliseconds.
+var (
+	durationBuckets = []float64{
+		0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1,
+		0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300,
+	}
+	ratioBuckets = []float64{1, 1.5, 2, 3, 4, 5, 7.5, 10, 15, 20, 50, 100}
+)
+
+// clientMetrics is set of OpenTelemetry instruments of Client.
+type clientMetrics struct {
+	queryDuration     metric.Float64Histogram
+	handshakeDuration metric.Float64Histogram
+	compressionRatio  metric.Float64Histogram
+	rowsSent          metric.Int64Counter
+	rowsReceived      metric.Int64Counter
+	bytesSent         metric.Int64Counter
+	bytesReceived     metric.Int64Counter
+	exceptions        metric.Int64Counter
+
+	// attrs are added to all measurements, like server address.
+	attrs []attribute.KeyValue
+}
+
+func newClientMetrics(m metric.Meter, attrs ...attribute.KeyValue) (*clientMetrics, error) {
+	var (
+		cm  = clientMetrics{attrs: attrs}
+		err error
+	)
+	if cm.queryDuration, err = m.Float64Histogram(otelch.MetricQueryDuration,
+		metric.WithDescription("Duration of query"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	); err != nil {
+		return nil, errors.Wrap(err, "query duration")
+	}
+	if cm.handshakeDuration, err = m.Float64Histogram(otelch.MetricHandshakeDuration,
+		metric.WithDescription("Duration of handshake"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	); err != nil {
+		return nil, errors.Wrap(err, "handshake duration")
+	}
+	if cm.compressionRatio, err = m.Float64Histogram(otelch.MetricCompressionRatio,
+		metric.WithDescription("Ratio of uncompressed to compressed size of sent blocks"),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(ratioBuckets...),
+	); err != nil {
+		return nil, errors.Wrap(err, "compression ratio")
+	}
+	if cm.rowsSent, err = m.Int64Counter(otelch.MetricRowsSent,
+		metric.WithDescription("Rows sent to server"),
+		metric.WithUnit("{row}"),
+	); err != nil {
+		return nil, errors.Wrap(err, "rows sent")
+	}
+	if cm.rowsReceived, err = m.Int64Counter(otelch.MetricRowsReceived,
+		metric.WithDescription("Rows received from server"),
+		metric.WithUnit("{row}"),
+	); err != nil {
+		return nil, errors.Wrap(err, "rows received")
+	}
+	if cm.bytesSent, err = m.Int64Counter(otelch.MetricBytesSent,
+		metric.WithDescription("Bytes sent to server"),
+		metric.WithUnit("By"),
+	); err != nil {
+		return nil, errors.Wrap(err, "bytes sent")
+	}
+	if cm.bytesReceived, err = m.Int64Counter(otelch.MetricBytesReceived,
+		metric.WithDescription("Bytes received from server"),
+		metric.WithUnit("By"),
+	); err != nil {
+		return nil, errors.Wrap(err, "bytes received")
+	}
+	if cm.exceptions, err = m.Int64Counter(otelch.MetricExceptions,
+		metric.WithDescription("Exceptions received from server"),
+		metric.WithUnit("{exception}"),
+	); err != nil {
+		return nil, errors.Wrap(err, "exceptions")
+	}
+	return &cm, nil
+}
+
+// with returns measurement option with attrs of client and provided ones.
+func (cm *clientMetrics) with(attrs ...attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(append(append([]attribute.KeyValue(nil), cm.attrs...), attrs...)...)
+}
+
+// outcome of operation that ended with err.
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return otelch.OutcomeOK
+	case IsException(err):
+		return otelch.OutcomeException
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return otelch.OutcomeCanceled
+	default:
+		return otelch.OutcomeError
+	}
+}
+
+// recordDuration records seconds elapsed since start to h with outcome of err.
+func (cm *clientMetrics) recordDuration(ctx context.Context, h metric.Float64Histogram, start time.Time, err error) {
+	h.Record(ctx, time.Since(start).Seconds(), cm.with(otelch.Outcome(outcome(err))))
+}
+
+// countingReader counts bytes read from underlying reader, unless skip
+// is set.
+type countingReader struct {
+	r       io.Reader
+	counter metric.Int64Counter
+	attrs   metric.MeasurementOption
+	skip    *atomic.Bool
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 && !r.skip.Load() {
+		r.counter.Add(context.Background(), int64(n), r.attrs)
+	}
+	return n, err
+}
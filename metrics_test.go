@@ -0,0 +1,222 @@
+package ch
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/ClickHouse/ch-go/otelch"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func findMetric(t *testing.T, rm metricdata.ResourceMetrics, name string) metricdata.Metrics {
+	t.Helper()
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("metric %q not found", name)
+	return metricdata.Metrics{}
+}
+
+func sumMetric(t *testing.T, m metricdata.Metrics) int64 {
+	t.Helper()
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	require.True(t, ok, "unexpected type %T", m.Data)
+	var total int64
+	for _, p := range sum.DataPoints {
+		total += p.Value
+	}
+	return total
+}
+
+func histogramCount(t *testing.T, m metricdata.Metrics, outcome string) uint64 {
+	t.Helper()
+	h, ok := m.Data.(metricdata.Histogram[float64])
+	require.True(t, ok, "unexpected type %T", m.Data)
+	var total uint64
+	for _, p := range h.DataPoints {
+		if v, _ := p.Attributes.Value(otelch.OutcomeKey); v.AsString() == outcome {
+			total += p.Count
+		}
+	}
+	return total
+}
+
+func TestClient_Metrics(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	srv := NewServer(ServerOptions{
+		Logger: zaptest.NewLogger(t).Named("srv"),
+		Authenticate: func(c Credentials) error {
+			if c.Password != "secret" {
+				return errors.New("bad password")
+			}
+			return nil
+		},
+	})
+	go func() { _ = srv.Serve(ln) }()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	ctx := context.Background()
+	client, err := Dial(ctx, Options{
+		Logger:        zaptest.NewLogger(t).Named("usr"),
+		Address:       ln.Addr().String(),
+		Password:      "secret",
+		MeterProvider: mp,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	require.NoError(t, client.Do(ctx, Query{Body: "SELECT 1"}))
+	require.NoError(t, client.Do(ctx, Query{Body: "SELECT 2"}))
+
+	_, err = Dial(ctx, Options{
+		Logger:        zaptest.NewLogger(t).Named("bad"),
+		Address:       ln.Addr().String(),
+		Password:      "wrong",
+		MeterProvider: mp,
+	})
+	require.Error(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	queries := findMetric(t, rm, otelch.MetricQueryDuration)
+	require.Equal(t, uint64(2), histogramCount(t, queries, otelch.OutcomeOK))
+
+	handshakes := findMetric(t, rm, otelch.MetricHandshakeDuration)
+	require.Equal(t, uint64(1), histogramCount(t, handshakes, otelch.OutcomeOK))
+	require.Equal(t, uint64(1), histogramCount(t, handshakes, otelch.OutcomeException))
+
+	require.Positive(t, sumMetric(t, findMetric(t, rm, otelch.MetricBytesSent)))
+	require.Positive(t, sumMetric(t, findMetric(t, rm, otelch.MetricBytesReceived)))
+
+	exceptions := findMetric(t, rm, otelch.MetricExceptions)
+	require.Equal(t, int64(1), sumMetric(t, exceptions))
+	p := exceptions.Data.(metricdata.Sum[int64]).DataPoints[0]
+	require.True(t, p.Attributes.HasValue(otelch.ErrorCodeKey))
+	require.Contains(t, p.Attributes.ToSlice(),
+		attribute.Int(string(otelch.ErrorCodeKey), int(proto.ErrAuthenticationFailed)),
+	)
+
+	// All client measurements have server address.
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			var sets []attribute.Set
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, p := range data.DataPoints {
+					sets = append(sets, p.Attributes)
+				}
+			case metricdata.Histogram[float64]:
+				for _, p := range data.DataPoints {
+					sets = append(sets, p.Attributes)
+				}
+			}
+			for _, set := range sets {
+				addr, _ := set.Value(otelch.ServerAddressKey)
+				require.Equal(t, ln.Addr().String(), addr.AsString(), m.Name)
+			}
+		}
+	}
+}
+
+func TestClient_MetricsException(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	cm, err := newClientMetrics(
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter(otelch.Name),
+		otelch.ServerAddress("localhost:9000"),
+	)
+	require.NoError(t, err)
+
+	buf := new(proto.Buffer)
+	for i := 0; i < 2; i++ {
+		e := proto.Exception{Code: proto.ErrUnknownTable, Name: "DB::Exception"}
+		e.EncodeAware(buf, proto.Version)
+	}
+	c := &Client{
+		metrics:         cm,
+		reader:          proto.NewReader(bytes.NewReader(buf.Buf)),
+		protocolVersion: proto.Version,
+	}
+	ctx := context.Background()
+
+	// Exceptions of internal queries are not reported.
+	c.noMetrics.Store(true)
+	_, err = c.exception(ctx)
+	require.NoError(t, err)
+	c.noMetrics.Store(false)
+	_, err = c.exception(ctx)
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	exceptions := findMetric(t, rm, otelch.MetricExceptions)
+	require.Equal(t, int64(1), sumMetric(t, exceptions))
+	p := exceptions.Data.(metricdata.Sum[int64]).DataPoints[0]
+	addr, _ := p.Attributes.Value(otelch.ServerAddressKey)
+	require.Equal(t, "localhost:9000", addr.AsString())
+}
+
+func TestOutcome(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{nil, otelch.OutcomeOK},
+		{errors.Wrap(&Exception{Code: proto.ErrTimeoutExceeded}, "query"), otelch.OutcomeException},
+		{errors.Wrap(context.Canceled, "canceled"), otelch.OutcomeCanceled},
+		{errors.Wrap(context.DeadlineExceeded, "read"), otelch.OutcomeCanceled},
+		{errors.New("unexpected"), otelch.OutcomeError},
+	} {
+		require.Equal(t, tt.want, outcome(tt.err))
+	}
+}
+
+func TestClient_Metrics_Rows(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	conn := ConnOpt(t, Options{
+		Compression:   CompressionLZ4,
+		MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	require.NoError(t, conn.Do(ctx, Query{
+		Body: "CREATE TABLE test_table (id UInt64) ENGINE = MergeTree ORDER BY id",
+	}))
+	data := make(proto.ColUInt64, 1000)
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:  "INSERT INTO test_table VALUES",
+		Input: proto.Input{{Name: "id", Data: &data}},
+	}))
+	var result proto.ColUInt64
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:   "SELECT id FROM test_table",
+		Result: proto.Results{{Name: "id", Data: &result}},
+	}))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Equal(t, int64(1000), sumMetric(t, findMetric(t, rm, otelch.MetricRowsSent)))
+	require.Equal(t, int64(1000), sumMetric(t, findMetric(t, rm, otelch.MetricRowsReceived)))
+
+	ratio := findMetric(t, rm, otelch.MetricCompressionRatio).Data.(metricdata.Histogram[float64])
+	require.Len(t, ratio.DataPoints, 1)
+	require.Greater(t, ratio.DataPoints[0].Sum, float64(1), "zeroes should be compressed")
+}
@@ -0,0 +1,32 @@
+package otelch
+
+import "go.opentelemetry.io/otel/attribute"
+
+// Names of span events.
+const (
+	EventProgress      = "ch.progress"
+	EventLog           = "ch.log"
+	EventProfileEvents = "ch.profile_events"
+)
+
+// Attribute keys of span events.
+const (
+	ProgressRowsKey       = attribute.Key("ch.progress.rows")
+	ProgressBytesKey      = attribute.Key("ch.progress.bytes")
+	ProgressTotalRowsKey  = attribute.Key("ch.progress.total_rows")
+	ProgressTotalBytesKey = attribute.Key("ch.progress.total_bytes")
+	ProgressWroteRowsKey  = attribute.Key("ch.progress.wrote_rows")
+	ProgressWroteBytesKey = attribute.Key("ch.progress.wrote_bytes")
+	ProgressElapsedKey    = attribute.Key("ch.progress.elapsed_ns")
+
+	LogTextKey     = attribute.Key("ch.log.text")
+	LogSourceKey   = attribute.Key("ch.log.source")
+	LogPriorityKey = attribute.Key("ch.log.priority")
+	LogHostKey     = attribute.Key("ch.log.host")
+	LogThreadIDKey = attribute.Key("ch.log.thread_id")
+)
+
+// ProfileEventKey returns attribute key of profile event with provided name.
+func ProfileEventKey(name string) attribute.Key {
+	return attribute.Key("ch.profile_event." + name)
+}
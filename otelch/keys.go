@@ -114,3 +114,6 @@ func ServerName(v string) attribute.KeyValue {
 		Value: attribute.StringValue(v),
 	}
 }
+
+// ServerSpanIDKey is attribute key of original span id of imported server span.
+const ServerSpanIDKey = attribute.Key("ch.server.span_id")
@@ -0,0 +1,82 @@
+package otelch
+
+import "go.opentelemetry.io/otel/attribute"
+
+// Names of metric instruments.
+const (
+	MetricQueryDuration     = "ch.query.duration"
+	MetricRowsSent          = "ch.rows.sent"
+	MetricRowsReceived      = "ch.rows.received"
+	MetricBytesSent         = "ch.bytes.sent"
+	MetricBytesReceived     = "ch.bytes.received"
+	MetricCompressionRatio  = "ch.compression.ratio"
+	MetricExceptions        = "ch.exceptions"
+	MetricHandshakeDuration = "ch.handshake.duration"
+
+	MetricPoolAcquired        = "ch.pool.acquired"
+	MetricPoolIdle            = "ch.pool.idle"
+	MetricPoolTotal           = "ch.pool.total"
+	MetricPoolMax             = "ch.pool.max"
+	MetricPoolAcquires        = "ch.pool.acquires"
+	MetricPoolAcquireDuration = "ch.pool.acquire.duration"
+	MetricPoolEmptyAcquires   = "ch.pool.acquires.empty"
+	MetricPoolCanceledAcquire = "ch.pool.acquires.canceled"
+
+	MetricBatchQueueRows      = "ch.batch.queue.rows"
+	MetricBatchQueueBatches   = "ch.batch.queue.batches"
+	MetricBatchFlushDuration  = "ch.batch.flush.duration"
+	MetricBatchRows           = "ch.batch.rows"
+	MetricBatchRowsDropped    = "ch.batch.rows.dropped"
+	MetricBatchRetries        = "ch.batch.retries"
+	MetricBatchSpoolBatches   = "ch.batch.spool.batches"
+	MetricBatchSpoolSize      = "ch.batch.spool.size"
+	MetricBatchSpoolCorrupted = "ch.batch.spool.corrupted"
+)
+
+// Attribute keys of metrics.
+const (
+	OutcomeKey           = attribute.Key("ch.outcome")
+	CompressionMethodKey = attribute.Key("ch.compression.method")
+	ServerAddressKey     = attribute.Key("ch.server.address")
+	TableKey             = attribute.Key("ch.table")
+)
+
+// Possible values of OutcomeKey.
+const (
+	OutcomeOK        = "ok"
+	OutcomeException = "exception"
+	OutcomeCanceled  = "canceled"
+	OutcomeError     = "error"
+)
+
+// Outcome attribute.
+func Outcome(v string) attribute.KeyValue {
+	return attribute.KeyValue{
+		Key:   OutcomeKey,
+		Value: attribute.StringValue(v),
+	}
+}
+
+// CompressionMethod attribute.
+func CompressionMethod(v string) attribute.KeyValue {
+	return attribute.KeyValue{
+		Key:   CompressionMethodKey,
+		Value: attribute.StringValue(v),
+	}
+}
+
+// ServerAddress attribute.
+func ServerAddress(v string) attribute.KeyValue {
+	return attribute.KeyValue{
+		Key:   ServerAddressKey,
+		Value: attribute.StringValue(v),
+	}
+}
+
+// Table attribute.
+func Table(v string) attribute.KeyValue {
+	return attribute.KeyValue{
+		Key:   TableKey,
+		Value: attribute.StringValue(v),
+	}
+}
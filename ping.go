@@ -48,7 +48,7 @@ func (c *Client) Ping(ctx context.Context) (err error) {
 	case proto.ServerCodePong:
 		return nil
 	case proto.ServerCodeException:
-		e, err := c.exception()
+		e, err := c.exception(ctx)
 		if err != nil {
 			return errors.Wrap(err, "decode exception")
 		}
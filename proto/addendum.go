@@ -0,0 +1,61 @@
+package proto
+
+import "github.com/go-faster/errors"
+
+// ParallelReplicasProtocolVersion is version of parallel replicas protocol
+// that is reported in handshake.
+const ParallelReplicasProtocolVersion = 4
+
+// Addendum is sent by client after ServerHello if FeatureAddendum is
+// supported by negotiated protocol version.
+type Addendum struct {
+	QuotaKey string
+	// ChunkedSend and ChunkedRecv are chunked packets framing settings
+	// selected by client, Chunked or NotChunked.
+	ChunkedSend string
+	ChunkedRecv string
+	// ParallelReplicasProtocol is version of parallel replicas protocol.
+	ParallelReplicasProtocol uint64
+}
+
+func (a Addendum) EncodeAware(b *Buffer, version int) {
+	if FeatureQuotaKey.In(version) {
+		b.PutString(a.QuotaKey)
+	}
+	if FeatureChunkedPackets.In(version) {
+		b.PutString(a.ChunkedSend)
+		b.PutString(a.ChunkedRecv)
+	}
+	if FeatureVersionedParallelReplicas.In(version) {
+		b.PutUVarInt(a.ParallelReplicasProtocol)
+	}
+}
+
+func (a *Addendum) DecodeAware(r *Reader, version int) error {
+	if FeatureQuotaKey.In(version) {
+		v, err := r.Str()
+		if err != nil {
+			return errors.Wrap(err, "quota key")
+		}
+		a.QuotaKey = v
+	}
+	if FeatureChunkedPackets.In(version) {
+		send, err := r.Str()
+		if err != nil {
+			return errors.Wrap(err, "chunked send")
+		}
+		recv, err := r.Str()
+		if err != nil {
+			return errors.Wrap(err, "chunked recv")
+		}
+		a.ChunkedSend, a.ChunkedRecv = send, recv
+	}
+	if FeatureVersionedParallelReplicas.In(version) {
+		v, err := r.UVarInt()
+		if err != nil {
+			return errors.Wrap(err, "parallel replicas protocol")
+		}
+		a.ParallelReplicasProtocol = v
+	}
+	return nil
+}
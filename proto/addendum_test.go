@@ -0,0 +1,35 @@
+package proto
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddendum_Revisions(t *testing.T) {
+	for _, revision := range []int{
+		int(FeatureAddendum),
+		int(FeatureChunkedPackets),
+		int(FeatureVersionedParallelReplicas),
+	} {
+		t.Run(strconv.Itoa(revision), func(t *testing.T) {
+			v := Addendum{
+				QuotaKey: "key",
+			}
+			if FeatureChunkedPackets.In(revision) {
+				v.ChunkedSend = NotChunked
+				v.ChunkedRecv = Chunked
+			}
+			if FeatureVersionedParallelReplicas.In(revision) {
+				v.ParallelReplicasProtocol = ParallelReplicasProtocolVersion
+			}
+			buf := GoldRevision(t, v, revision)
+
+			var dec Addendum
+			requireDecode(t, buf, awareOf(&dec, revision))
+			require.Equal(t, v, dec)
+			requireNoShortRead(t, buf, awareOf(&dec, revision))
+		})
+	}
+}
@@ -249,18 +249,12 @@ func (b *Block) DecodeRawBlock(r *Reader, version int, target Result) error {
 				return errors.Wrapf(err, "column [%d] name", i)
 			}
 			// Type.
-			if _, err := r.Str(); err != nil {
+			t, err := r.Str()
+			if err != nil {
 				return errors.Wrapf(err, "column [%d] type", i)
 			}
-			if FeatureCustomSerialization.In(version) {
-				// Custom serialization flag.
-				v, err := r.Bool()
-				if err != nil {
-					return errors.Wrapf(err, "column [%d] custom serialization flag", i)
-				}
-				if v {
-					return errors.Errorf("column [%d] has custom serialization (not supported)", i)
-				}
+			if _, err := decodeSerialization(r, version, ColumnType(t)); err != nil {
+				return errors.Wrapf(err, "column [%d]", i)
 			}
 		}
 		return nil
@@ -10,6 +10,8 @@ import (
 // Buffer implements ClickHouse binary protocol encoding.
 type Buffer struct {
 	Buf []byte
+
+	packets []int // offsets of packets, see StartPacket
 }
 
 // Reader returns new *Reader from *Buffer.
@@ -20,6 +22,7 @@ func (b *Buffer) Reader() *Reader {
 // Ensure Buf length.
 func (b *Buffer) Ensure(n int) {
 	b.Buf = append(b.Buf[:0], make([]byte, n)...)
+	b.packets = b.packets[:0]
 }
 
 // Encoder implements encoding to Buffer.
@@ -45,6 +48,7 @@ func (b *Buffer) Encode(e Encoder) {
 // Reset buffer to zero length.
 func (b *Buffer) Reset() {
 	b.Buf = b.Buf[:0]
+	b.packets = b.packets[:0]
 }
 
 // Read implements io.Reader.
@@ -0,0 +1,142 @@
+package proto
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// Possible values of chunked packets framing setting, sent in ServerHello
+// and configured on client ("proto_caps" in ClickHouse).
+//
+// Optional values mean that peer prefers framing but supports other one.
+const (
+	Chunked            = "chunked"
+	NotChunked         = "notchunked"
+	ChunkedOptional    = "chunked_optional"
+	NotChunkedOptional = "notchunked_optional"
+)
+
+// NegotiateChunked resolves chunked framing of one direction of connection
+// from local and remote settings, where local wins if both are optional.
+//
+// Empty remote is treated as NotChunked, e.g. for older revisions.
+func NegotiateChunked(local, remote string) (bool, error) {
+	if remote == "" {
+		remote = NotChunked
+	}
+	for _, v := range []string{local, remote} {
+		switch v {
+		case Chunked, NotChunked, ChunkedOptional, NotChunkedOptional:
+		default:
+			return false, errors.Errorf("invalid chunked setting %q", v)
+		}
+	}
+	var (
+		localChunked  = strings.HasPrefix(local, Chunked)
+		remoteChunked = strings.HasPrefix(remote, Chunked)
+		localStrict   = !strings.HasSuffix(local, "_optional")
+		remoteStrict  = !strings.HasSuffix(remote, "_optional")
+	)
+	switch {
+	case localChunked == remoteChunked:
+		return localChunked, nil
+	case localStrict && remoteStrict:
+		return false, errors.Errorf("incompatible chunked settings: %q (local) and %q (remote)", local, remote)
+	case remoteStrict:
+		return remoteChunked, nil
+	default:
+		return localChunked, nil
+	}
+}
+
+// StartPacket marks start of new packet at current position of buffer,
+// so WriteChunked can frame each packet as separate message.
+//
+// Called by ClientCode.Encode and ServerCode.Encode.
+func (b *Buffer) StartPacket() {
+	b.packets = append(b.packets, len(b.Buf))
+}
+
+// WriteChunked writes buffer to w with chunked framing, where each packet
+// is written as message of chunks, prefixed with UInt32 chunk length, and
+// terminated by chunk of zero length.
+//
+// Buffer is written as single message if no packets are marked.
+func (b *Buffer) WriteChunked(w io.Writer) (int64, error) {
+	if len(b.Buf) == 0 {
+		return 0, nil
+	}
+	var messages [][]byte
+	start := 0
+	for _, end := range b.packets {
+		if end > start {
+			messages = append(messages, b.Buf[start:end])
+		}
+		start = end
+	}
+	if start < len(b.Buf) {
+		messages = append(messages, b.Buf[start:])
+	}
+	chunks := 0
+	for _, m := range messages {
+		chunks += int((uint64(len(m)) + maxChunkSize - 1) / maxChunkSize)
+	}
+	var (
+		headers = make([]byte, 4*(chunks+len(messages)))
+		bufs    = make(net.Buffers, 0, 2*chunks+len(messages))
+	)
+	header := func(n int) []byte {
+		h := headers[:4]
+		headers = headers[4:]
+		binary.LittleEndian.PutUint32(h, uint32(n))
+		return h
+	}
+	for _, m := range messages {
+		for len(m) > 0 {
+			n := len(m)
+			if uint64(n) > maxChunkSize {
+				n = int(maxChunkSize)
+			}
+			bufs = append(bufs, header(n), m[:n])
+			m = m[n:]
+		}
+		bufs = append(bufs, header(0))
+	}
+	return bufs.WriteTo(w)
+}
+
+// maxChunkSize is variable to prevent overflow of int on 32-bit platforms.
+var maxChunkSize uint64 = math.MaxUint32
+
+// chunkedReader reads chunked framing from r if enabled, passing data
+// through otherwise.
+type chunkedReader struct {
+	r       io.Reader
+	enabled bool
+	left    uint32 // bytes left in current chunk
+	header  [4]byte
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if !c.enabled || len(p) == 0 {
+		return c.r.Read(p)
+	}
+	for c.left == 0 {
+		// Zero length chunk is end of message, skipping it.
+		if _, err := io.ReadFull(c.r, c.header[:]); err != nil {
+			return 0, errors.Wrap(err, "chunk length")
+		}
+		c.left = binary.LittleEndian.Uint32(c.header[:])
+	}
+	if uint64(len(p)) > uint64(c.left) {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= uint32(n)
+	return n, err
+}
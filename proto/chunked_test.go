@@ -0,0 +1,95 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/ch-go/internal/gold"
+)
+
+func TestNegotiateChunked(t *testing.T) {
+	for _, tt := range []struct {
+		Local, Remote string
+		Chunked       bool
+		Error         bool
+	}{
+		{Local: Chunked, Remote: Chunked, Chunked: true},
+		{Local: NotChunked, Remote: NotChunked},
+		{Local: NotChunked, Remote: ""},
+		{Local: Chunked, Remote: "", Error: true},
+		{Local: Chunked, Remote: NotChunked, Error: true},
+		{Local: NotChunked, Remote: Chunked, Error: true},
+		{Local: Chunked, Remote: NotChunkedOptional, Chunked: true},
+		{Local: NotChunked, Remote: ChunkedOptional},
+		{Local: ChunkedOptional, Remote: NotChunked},
+		{Local: NotChunkedOptional, Remote: Chunked, Chunked: true},
+		{Local: ChunkedOptional, Remote: NotChunkedOptional, Chunked: true},
+		{Local: NotChunkedOptional, Remote: ChunkedOptional},
+		{Local: "bad", Remote: Chunked, Error: true},
+		{Local: Chunked, Remote: "bad", Error: true},
+	} {
+		t.Run(tt.Local+"_"+tt.Remote, func(t *testing.T) {
+			v, err := NegotiateChunked(tt.Local, tt.Remote)
+			if tt.Error {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.Chunked, v)
+		})
+	}
+}
+
+func TestBuffer_WriteChunked(t *testing.T) {
+	var b Buffer
+	ServerCodePong.Encode(&b)
+	ServerCodeProgress.Encode(&b)
+	Progress{Rows: 100, Bytes: 608, TotalRows: 1000}.EncodeAware(&b, Version)
+	ServerCodeEndOfStream.Encode(&b)
+
+	var out bytes.Buffer
+	n, err := b.WriteChunked(&out)
+	require.NoError(t, err)
+	require.Equal(t, int64(out.Len()), n)
+	require.Equal(t, len(b.Buf)+3*8, out.Len(), "header and terminator per packet")
+	gold.Bytes(t, out.Bytes(), "chunked")
+
+	r := NewReader(&out)
+	r.EnableChunked()
+	code, err := r.UVarInt()
+	require.NoError(t, err)
+	require.Equal(t, ServerCodePong, ServerCode(code))
+	code, err = r.UVarInt()
+	require.NoError(t, err)
+	require.Equal(t, ServerCodeProgress, ServerCode(code))
+	var p Progress
+	require.NoError(t, p.DecodeAware(r, Version))
+	require.Equal(t, uint64(100), p.Rows)
+	code, err = r.UVarInt()
+	require.NoError(t, err)
+	require.Equal(t, ServerCodeEndOfStream, ServerCode(code))
+
+	t.Run("Unmarked", func(t *testing.T) {
+		b := Buffer{Buf: []byte{1, 2, 3}}
+		var out bytes.Buffer
+		_, err := b.WriteChunked(&out)
+		require.NoError(t, err)
+		require.Equal(t, []byte{3, 0, 0, 0, 1, 2, 3, 0, 0, 0, 0}, out.Bytes())
+	})
+	t.Run("Reset", func(t *testing.T) {
+		b.Reset()
+		ServerCodePong.Encode(&b)
+		var out bytes.Buffer
+		_, err := b.WriteChunked(&out)
+		require.NoError(t, err)
+		require.Equal(t, []byte{1, 0, 0, 0, byte(ServerCodePong), 0, 0, 0, 0}, out.Bytes())
+	})
+	t.Run("ShortRead", func(t *testing.T) {
+		r := NewReader(bytes.NewReader([]byte{4, 0, 0, 0, 1}))
+		r.EnableChunked()
+		_, err := r.ReadRaw(4)
+		require.Error(t, err)
+	})
+}
@@ -13,7 +13,18 @@ const (
 	ClientCodeCancel          ClientCode = 3 // query cancel
 	ClientCodePing            ClientCode = 4 // ping request to server
 	ClientTablesStatusRequest ClientCode = 5 // tables status request
+
+	ClientCodeKeepAlive                 ClientCode = 6  // keep connection alive
+	ClientCodeScalar                    ClientCode = 7  // scalar subquery result block
+	ClientCodeIgnoredPartUUIDs          ClientCode = 8  // list of part uuids to ignore during query
+	ClientCodeReadTaskResponse          ClientCode = 9  // response to ServerCodeReadTaskRequest
+	ClientCodeMergeTreeReadTaskResponse ClientCode = 10 // response to ServerCodeMergeTreeReadTaskRequest
+	ClientCodeSSHChallengeRequest       ClientCode = 11 // request for SSH signature challenge
+	ClientCodeSSHChallengeResponse      ClientCode = 12 // response with SSH signature of challenge
 )
 
 // Encode to buffer.
-func (c ClientCode) Encode(b *Buffer) { b.PutByte(byte(c)) }
+func (c ClientCode) Encode(b *Buffer) {
+	b.StartPacket()
+	b.PutByte(byte(c))
+}
@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _ClientCodeName = "HelloQueryDataCancelPingClientTablesStatusRequest"
+const _ClientCodeName = "HelloQueryDataCancelPingClientTablesStatusRequestKeepAliveScalarIgnoredPartUUIDsReadTaskResponseMergeTreeReadTaskResponseSSHChallengeRequestSSHChallengeResponse"
 
-var _ClientCodeIndex = [...]uint8{0, 5, 10, 14, 20, 24, 49}
+var _ClientCodeIndex = [...]uint8{0, 5, 10, 14, 20, 24, 49, 58, 64, 80, 96, 121, 140, 160}
 
-const _ClientCodeLowerName = "helloquerydatacancelpingclienttablesstatusrequest"
+const _ClientCodeLowerName = "helloquerydatacancelpingclienttablesstatusrequestkeepalivescalarignoredpartuuidsreadtaskresponsemergetreereadtaskresponsesshchallengerequestsshchallengeresponse"
 
 func (i ClientCode) String() string {
 	if i >= ClientCode(len(_ClientCodeIndex)-1) {
@@ -30,23 +30,44 @@ func _ClientCodeNoOp() {
 	_ = x[ClientCodeCancel-(3)]
 	_ = x[ClientCodePing-(4)]
 	_ = x[ClientTablesStatusRequest-(5)]
+	_ = x[ClientCodeKeepAlive-(6)]
+	_ = x[ClientCodeScalar-(7)]
+	_ = x[ClientCodeIgnoredPartUUIDs-(8)]
+	_ = x[ClientCodeReadTaskResponse-(9)]
+	_ = x[ClientCodeMergeTreeReadTaskResponse-(10)]
+	_ = x[ClientCodeSSHChallengeRequest-(11)]
+	_ = x[ClientCodeSSHChallengeResponse-(12)]
 }
 
-var _ClientCodeValues = []ClientCode{ClientCodeHello, ClientCodeQuery, ClientCodeData, ClientCodeCancel, ClientCodePing, ClientTablesStatusRequest}
+var _ClientCodeValues = []ClientCode{ClientCodeHello, ClientCodeQuery, ClientCodeData, ClientCodeCancel, ClientCodePing, ClientTablesStatusRequest, ClientCodeKeepAlive, ClientCodeScalar, ClientCodeIgnoredPartUUIDs, ClientCodeReadTaskResponse, ClientCodeMergeTreeReadTaskResponse, ClientCodeSSHChallengeRequest, ClientCodeSSHChallengeResponse}
 
 var _ClientCodeNameToValueMap = map[string]ClientCode{
-	_ClientCodeName[0:5]:        ClientCodeHello,
-	_ClientCodeLowerName[0:5]:   ClientCodeHello,
-	_ClientCodeName[5:10]:       ClientCodeQuery,
-	_ClientCodeLowerName[5:10]:  ClientCodeQuery,
-	_ClientCodeName[10:14]:      ClientCodeData,
-	_ClientCodeLowerName[10:14]: ClientCodeData,
-	_ClientCodeName[14:20]:      ClientCodeCancel,
-	_ClientCodeLowerName[14:20]: ClientCodeCancel,
-	_ClientCodeName[20:24]:      ClientCodePing,
-	_ClientCodeLowerName[20:24]: ClientCodePing,
-	_ClientCodeName[24:49]:      ClientTablesStatusRequest,
-	_ClientCodeLowerName[24:49]: ClientTablesStatusRequest,
+	_ClientCodeName[0:5]:          ClientCodeHello,
+	_ClientCodeLowerName[0:5]:     ClientCodeHello,
+	_ClientCodeName[5:10]:         ClientCodeQuery,
+	_ClientCodeLowerName[5:10]:    ClientCodeQuery,
+	_ClientCodeName[10:14]:        ClientCodeData,
+	_ClientCodeLowerName[10:14]:   ClientCodeData,
+	_ClientCodeName[14:20]:        ClientCodeCancel,
+	_ClientCodeLowerName[14:20]:   ClientCodeCancel,
+	_ClientCodeName[20:24]:        ClientCodePing,
+	_ClientCodeLowerName[20:24]:   ClientCodePing,
+	_ClientCodeName[24:49]:        ClientTablesStatusRequest,
+	_ClientCodeLowerName[24:49]:   ClientTablesStatusRequest,
+	_ClientCodeName[49:58]:        ClientCodeKeepAlive,
+	_ClientCodeLowerName[49:58]:   ClientCodeKeepAlive,
+	_ClientCodeName[58:64]:        ClientCodeScalar,
+	_ClientCodeLowerName[58:64]:   ClientCodeScalar,
+	_ClientCodeName[64:80]:        ClientCodeIgnoredPartUUIDs,
+	_ClientCodeLowerName[64:80]:   ClientCodeIgnoredPartUUIDs,
+	_ClientCodeName[80:96]:        ClientCodeReadTaskResponse,
+	_ClientCodeLowerName[80:96]:   ClientCodeReadTaskResponse,
+	_ClientCodeName[96:121]:       ClientCodeMergeTreeReadTaskResponse,
+	_ClientCodeLowerName[96:121]:  ClientCodeMergeTreeReadTaskResponse,
+	_ClientCodeName[121:140]:      ClientCodeSSHChallengeRequest,
+	_ClientCodeLowerName[121:140]: ClientCodeSSHChallengeRequest,
+	_ClientCodeName[140:160]:      ClientCodeSSHChallengeResponse,
+	_ClientCodeLowerName[140:160]: ClientCodeSSHChallengeResponse,
 }
 
 var _ClientCodeNames = []string{
@@ -56,6 +77,13 @@ var _ClientCodeNames = []string{
 	_ClientCodeName[14:20],
 	_ClientCodeName[20:24],
 	_ClientCodeName[24:49],
+	_ClientCodeName[49:58],
+	_ClientCodeName[58:64],
+	_ClientCodeName[64:80],
+	_ClientCodeName[80:96],
+	_ClientCodeName[96:121],
+	_ClientCodeName[121:140],
+	_ClientCodeName[140:160],
 }
 
 // ClientCodeString retrieves an enum value from the enum constants string name.
@@ -2,6 +2,8 @@ package proto
 
 import "github.com/go-faster/errors"
 
+// ClientData is header of data block sent by client as ClientCodeData or
+// ClientCodeScalar, where TableName is temporary table or scalar name.
 type ClientData struct {
 	TableName string
 }
@@ -2,6 +2,10 @@ package proto
 
 import "github.com/go-faster/errors"
 
+// JWTAuthMarker is ClientHello.User that requests authentication with JWT
+// that is passed as ClientHello.Password.
+const JWTAuthMarker = " JWT AUTHENTICATION "
+
 // ClientHello represents ClientCodeHello message.
 type ClientHello struct {
 	Name string
@@ -2,6 +2,7 @@ package proto
 
 import (
 	"bytes"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -60,3 +61,21 @@ func TestClientInfo_OpenTelemetry(t *testing.T) {
 		requireNoShortRead(t, b.Buf, aware(&i))
 	})
 }
+
+func TestClientInfo_Revisions(t *testing.T) {
+	for _, revision := range []int{
+		int(FeatureServerQueryTimeInProgress),
+		Version,
+	} {
+		t.Run(strconv.Itoa(revision), func(t *testing.T) {
+			v := queryCreateDatabase.Info
+			v.ProtocolVersion = revision
+			buf := GoldRevision(t, v, revision)
+
+			var dec ClientInfo
+			requireDecode(t, buf, awareOf(&dec, revision))
+			assert.Equal(t, v, dec)
+			requireNoShortRead(t, buf, awareOf(&dec, revision))
+		})
+	}
+}
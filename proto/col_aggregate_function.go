@@ -0,0 +1,404 @@
+package proto
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// Compile-time assertions for ColAggregateFunction.
+var (
+	_ ColInput         = (*ColAggregateFunction)(nil)
+	_ ColResult        = (*ColAggregateFunction)(nil)
+	_ Column           = (*ColAggregateFunction)(nil)
+	_ Inferable        = (*ColAggregateFunction)(nil)
+	_ ColumnOf[[]byte] = (*ColAggregateFunction)(nil)
+)
+
+// AggregateState is decoded intermediate state of aggregate function.
+type AggregateState struct {
+	// Has reports whether state of min, max, any or anyLast has value.
+	Has bool
+	// Value is little-endian binary value of state, like sum of sum or
+	// numerator of avg. See ColAggregateFunction.ValueType.
+	Value []byte
+	// Count is value of count or denominator of avg.
+	Count uint64
+}
+
+type aggregateKind byte
+
+const (
+	aggregateCount  aggregateKind = iota + 1 // VarUInt(count)
+	aggregateValue                           // value
+	aggregateAvg                             // value, VarUInt(denominator)
+	aggregateSingle                          // Bool(has), value if has
+)
+
+// ColAggregateFunction represents AggregateFunction(name, T) column of
+// serialized intermediate states of aggregate function, e.g. returned
+// by server on StageWithMergeableState.
+//
+// States of count, sum, sumWithOverflow, avg, min, max, any and anyLast
+// of fixed size types are supported.
+//
+// Raw states can be sent back to server to merge them, e.g. with sumMerge.
+type ColAggregateFunction struct {
+	Function string     // name of function, like "sum"
+	Arg      ColumnType // type of argument, blank for count()
+
+	Buf []byte
+	Pos []Position
+
+	t         ColumnType
+	kind      aggregateKind
+	valueType ColumnType
+	valueSize int
+}
+
+// fixedSize returns size of fixed size type, or zero.
+func fixedSize(t ColumnType) int {
+	switch t.Base() {
+	case ColumnTypeInt8, ColumnTypeUInt8, ColumnTypeBool, ColumnTypeEnum8:
+		return 1
+	case ColumnTypeInt16, ColumnTypeUInt16, ColumnTypeDate, ColumnTypeEnum16:
+		return 2
+	case ColumnTypeInt32, ColumnTypeUInt32, ColumnTypeFloat32, ColumnTypeDate32,
+		ColumnTypeDateTime, ColumnTypeIPv4, ColumnTypeDecimal32:
+		return 4
+	case ColumnTypeInt64, ColumnTypeUInt64, ColumnTypeFloat64, ColumnTypeDateTime64,
+		ColumnTypeDecimal64:
+		return 8
+	case ColumnTypeInt128, ColumnTypeUInt128, ColumnTypeDecimal128, ColumnTypeUUID, ColumnTypeIPv6:
+		return 16
+	case ColumnTypeInt256, ColumnTypeUInt256, ColumnTypeDecimal256:
+		return 32
+	case ColumnTypeDecimal:
+		elems := strings.Split(string(t.Elem()), ",")
+		p, err := strconv.Atoi(strings.TrimSpace(elems[0]))
+		if err != nil {
+			return 0
+		}
+		switch {
+		case p <= 9:
+			return 4
+		case p <= 18:
+			return 8
+		case p <= 38:
+			return 16
+		case p <= 76:
+			return 32
+		}
+	}
+	return 0
+}
+
+// isDecimal reports whether t is Decimal type.
+func isDecimal(t ColumnType) bool {
+	return strings.HasPrefix(string(t.Base()), string(ColumnTypeDecimal))
+}
+
+// sumType returns type of sum of t, used as sum or numerator of average.
+func sumType(t ColumnType) ColumnType {
+	switch t.Base() {
+	case ColumnTypeInt8, ColumnTypeInt16, ColumnTypeInt32, ColumnTypeInt64:
+		return ColumnTypeInt64
+	case ColumnTypeUInt8, ColumnTypeUInt16, ColumnTypeUInt32, ColumnTypeUInt64:
+		return ColumnTypeUInt64
+	case ColumnTypeFloat32, ColumnTypeFloat64:
+		return ColumnTypeFloat64
+	case ColumnTypeInt128, ColumnTypeUInt128, ColumnTypeInt256, ColumnTypeUInt256:
+		return t
+	}
+	if isDecimal(t) {
+		if fixedSize(t) == 32 {
+			return ColumnTypeDecimal256
+		}
+		return ColumnTypeDecimal128
+	}
+	return ""
+}
+
+// splitAggregateFunction splits AggregateFunction type arguments, like
+// AggregateFunction(sum, UInt64) -> [sum, UInt64].
+func splitAggregateFunction(t ColumnType) []string {
+	var (
+		elem  = string(t.Elem())
+		out   []string
+		depth int
+		start int
+	)
+	for i := 0; i < len(elem); i++ {
+		switch elem[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(elem[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(out, strings.TrimSpace(elem[start:]))
+}
+
+// Infer and initialize state serialization from AggregateFunction type.
+func (c *ColAggregateFunction) Infer(t ColumnType) error {
+	if t.Base() != ColumnTypeAggregateFunction {
+		return errors.Errorf("invalid base %q to infer aggregate function", t.Base())
+	}
+	args := splitAggregateFunction(t)
+	if _, err := strconv.Atoi(args[0]); err == nil && len(args) > 1 {
+		// Skipping version of aggregate function state.
+		args = args[1:]
+	}
+	c.Function = args[0]
+	c.Arg = ""
+	switch len(args) {
+	case 1:
+	case 2:
+		c.Arg = ColumnType(args[1])
+	default:
+		return errors.Errorf("aggregate function %q with %d arguments is not supported", c.Function, len(args)-1)
+	}
+
+	var valueType ColumnType
+	switch c.Function {
+	case "count":
+		c.kind = aggregateCount
+	case "sum":
+		c.kind = aggregateValue
+		valueType = sumType(c.Arg)
+	case "sumWithOverflow":
+		c.kind = aggregateValue
+		valueType = c.Arg
+	case "avg":
+		c.kind = aggregateAvg
+		valueType = sumType(c.Arg)
+		switch c.Arg.Base() {
+		case ColumnTypeInt128, ColumnTypeUInt128, ColumnTypeInt256, ColumnTypeUInt256:
+			// Big integers are averaged as floats.
+			valueType = ColumnTypeFloat64
+		}
+	case "min", "max", "any", "anyLast":
+		c.kind = aggregateSingle
+		valueType = c.Arg
+	default:
+		return errors.Errorf("aggregate function %q is not supported", c.Function)
+	}
+	if c.kind != aggregateCount {
+		c.valueSize = fixedSize(valueType)
+		if c.valueSize == 0 {
+			return errors.Errorf("%s(%s) is not supported", c.Function, c.Arg)
+		}
+	}
+	c.valueType = valueType
+	c.t = t
+	return nil
+}
+
+// ValueType returns type of AggregateState.Value.
+func (c ColAggregateFunction) ValueType() ColumnType {
+	return c.valueType
+}
+
+func (c ColAggregateFunction) Type() ColumnType {
+	return c.t
+}
+
+func (c ColAggregateFunction) Rows() int {
+	return len(c.Pos)
+}
+
+func (c *ColAggregateFunction) Reset() {
+	c.Buf = c.Buf[:0]
+	c.Pos = c.Pos[:0]
+}
+
+// Row returns serialized state of i-th row.
+func (c ColAggregateFunction) Row(i int) []byte {
+	p := c.Pos[i]
+	return c.Buf[p.Start:p.End]
+}
+
+// Append serialized state.
+func (c *ColAggregateFunction) Append(v []byte) {
+	start := len(c.Buf)
+	c.Buf = append(c.Buf, v...)
+	c.Pos = append(c.Pos, Position{Start: start, End: len(c.Buf)})
+}
+
+// AppendArr appends serialized states.
+func (c *ColAggregateFunction) AppendArr(v [][]byte) {
+	for _, s := range v {
+		c.Append(s)
+	}
+}
+
+// State decodes state of i-th row.
+func (c ColAggregateFunction) State(i int) (AggregateState, error) {
+	var (
+		s   AggregateState
+		buf = c.Row(i)
+	)
+	switch c.kind {
+	case aggregateCount:
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return s, errors.New("bad count")
+		}
+		s.Count = v
+	case aggregateValue:
+		if len(buf) < c.valueSize {
+			return s, errors.Errorf("value: %d bytes, expected %d", len(buf), c.valueSize)
+		}
+		s.Value = buf
+	case aggregateAvg:
+		if len(buf) < c.valueSize {
+			return s, errors.Errorf("numerator: %d bytes, expected %d", len(buf), c.valueSize)
+		}
+		s.Value = buf[:c.valueSize]
+		v, n := binary.Uvarint(buf[c.valueSize:])
+		if n <= 0 {
+			return s, errors.New("bad denominator")
+		}
+		s.Count = v
+	case aggregateSingle:
+		if len(buf) == 0 {
+			return s, errors.New("no has flag")
+		}
+		s.Has = buf[0] == boolTrue
+		if s.Has {
+			if len(buf)-1 < c.valueSize {
+				return s, errors.Errorf("value: %d bytes, expected %d", len(buf)-1, c.valueSize)
+			}
+			s.Value = buf[1:]
+		}
+	default:
+		return s, errors.New("not inferred")
+	}
+	return s, nil
+}
+
+// AppendState appends state, serializing it.
+func (c *ColAggregateFunction) AppendState(s AggregateState) {
+	start := len(c.Buf)
+	switch c.kind {
+	case aggregateCount:
+		c.Buf = binary.AppendUvarint(c.Buf, s.Count)
+	case aggregateValue:
+		c.Buf = appendValue(c.Buf, s.Value, c.valueSize)
+	case aggregateAvg:
+		c.Buf = appendValue(c.Buf, s.Value, c.valueSize)
+		c.Buf = binary.AppendUvarint(c.Buf, s.Count)
+	case aggregateSingle:
+		if s.Has {
+			c.Buf = append(c.Buf, boolTrue)
+			c.Buf = appendValue(c.Buf, s.Value, c.valueSize)
+		} else {
+			c.Buf = append(c.Buf, boolFalse)
+		}
+	}
+	c.Pos = append(c.Pos, Position{Start: start, End: len(c.Buf)})
+}
+
+// appendValue appends v, padded or truncated to size.
+func appendValue(b, v []byte, size int) []byte {
+	if len(v) > size {
+		v = v[:size]
+	}
+	b = append(b, v...)
+	for i := len(v); i < size; i++ {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// readUVarInt reads VarUInt and appends its raw bytes to buf.
+func readUVarInt(r *Reader, buf []byte) ([]byte, error) {
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		v, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, v)
+		if v < 0x80 {
+			return buf, nil
+		}
+	}
+	return nil, errors.New("varint overflows 64 bits")
+}
+
+func (c *ColAggregateFunction) readValue(r *Reader) error {
+	v, err := r.ReadRaw(c.valueSize)
+	if err != nil {
+		return errors.Wrap(err, "value")
+	}
+	c.Buf = append(c.Buf, v...)
+	return nil
+}
+
+func (c *ColAggregateFunction) DecodeColumn(r *Reader, rows int) error {
+	if c.kind == 0 {
+		return errors.New("aggregate function is not inferred")
+	}
+	for i := 0; i < rows; i++ {
+		start := len(c.Buf)
+		switch c.kind {
+		case aggregateCount:
+			buf, err := readUVarInt(r, c.Buf)
+			if err != nil {
+				return errors.Wrapf(err, "[%d]: count", i)
+			}
+			c.Buf = buf
+		case aggregateValue:
+			if err := c.readValue(r); err != nil {
+				return errors.Wrapf(err, "[%d]", i)
+			}
+		case aggregateAvg:
+			if err := c.readValue(r); err != nil {
+				return errors.Wrapf(err, "[%d]", i)
+			}
+			buf, err := readUVarInt(r, c.Buf)
+			if err != nil {
+				return errors.Wrapf(err, "[%d]: denominator", i)
+			}
+			c.Buf = buf
+		case aggregateSingle:
+			has, err := r.Bool()
+			if err != nil {
+				return errors.Wrapf(err, "[%d]: has", i)
+			}
+			if !has {
+				c.Buf = append(c.Buf, boolFalse)
+				break
+			}
+			c.Buf = append(c.Buf, boolTrue)
+			if err := c.readValue(r); err != nil {
+				return errors.Wrapf(err, "[%d]", i)
+			}
+		}
+		c.Pos = append(c.Pos, Position{Start: start, End: len(c.Buf)})
+	}
+	return nil
+}
+
+func (c ColAggregateFunction) EncodeColumn(b *Buffer) {
+	if b == nil {
+		return
+	}
+	for _, p := range c.Pos {
+		b.Buf = append(b.Buf, c.Buf[p.Start:p.End]...)
+	}
+}
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColAggregateFunction) Slice(start, end int) ColInput {
+	s := c
+	s.Pos = c.Pos[start:end]
+	return &s
+}
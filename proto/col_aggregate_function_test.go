@@ -0,0 +1,139 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/ch-go/internal/gold"
+)
+
+func TestColAggregateFunction(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		Name      string
+		Type      ColumnType
+		ValueType ColumnType
+		States    []AggregateState
+	}{
+		{
+			Name: "count",
+			Type: "AggregateFunction(count)",
+			States: []AggregateState{
+				{Count: 0}, {Count: 1}, {Count: 100500},
+			},
+		},
+		{
+			Name:      "sum",
+			Type:      "AggregateFunction(sum, UInt8)",
+			ValueType: ColumnTypeUInt64,
+			States: []AggregateState{
+				{Value: binary.LittleEndian.AppendUint64(nil, 0)},
+				{Value: binary.LittleEndian.AppendUint64(nil, 1000)},
+			},
+		},
+		{
+			Name:      "avg",
+			Type:      "AggregateFunction(avg, Float32)",
+			ValueType: ColumnTypeFloat64,
+			States: []AggregateState{
+				{Value: binary.LittleEndian.AppendUint64(nil, 10), Count: 2},
+				{Value: binary.LittleEndian.AppendUint64(nil, 0), Count: 0},
+			},
+		},
+		{
+			Name:      "max",
+			Type:      "AggregateFunction(1, max, Int32)",
+			ValueType: ColumnTypeInt32,
+			States: []AggregateState{
+				{Has: true, Value: binary.LittleEndian.AppendUint32(nil, 5)},
+				{Has: false},
+				{Has: true, Value: binary.LittleEndian.AppendUint32(nil, 100)},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			var data ColAggregateFunction
+			require.NoError(t, data.Infer(tt.Type))
+			require.Equal(t, tt.Type, data.Type())
+			require.Equal(t, tt.ValueType, data.ValueType())
+			for i, s := range tt.States {
+				data.AppendState(s)
+				got, err := data.State(i)
+				require.NoError(t, err)
+				require.Equal(t, s, got)
+			}
+			rows := len(tt.States)
+
+			var buf Buffer
+			data.EncodeColumn(&buf)
+			t.Run("Golden", func(t *testing.T) {
+				gold.Bytes(t, buf.Buf, "col_aggregate_function_"+tt.Name)
+			})
+			t.Run("Ok", func(t *testing.T) {
+				r := NewReader(bytes.NewReader(buf.Buf))
+				var dec ColAggregateFunction
+				require.NoError(t, dec.Infer(tt.Type))
+				require.NoError(t, dec.DecodeColumn(r, rows))
+				require.Equal(t, data, dec)
+				require.Equal(t, rows, dec.Rows())
+				dec.Reset()
+				require.Equal(t, 0, dec.Rows())
+			})
+			t.Run("Auto", func(t *testing.T) {
+				r := NewReader(bytes.NewReader(buf.Buf))
+				var dec ColAuto
+				require.NoError(t, dec.Infer(tt.Type))
+				require.NoError(t, dec.DecodeColumn(r, rows))
+				require.Equal(t, &data, dec.Data)
+			})
+			t.Run("EOF", func(t *testing.T) {
+				r := NewReader(bytes.NewReader(nil))
+				var dec ColAggregateFunction
+				require.NoError(t, dec.Infer(tt.Type))
+				require.ErrorIs(t, dec.DecodeColumn(r, rows), io.EOF)
+			})
+			t.Run("NoShortRead", func(t *testing.T) {
+				var dec ColAggregateFunction
+				require.NoError(t, dec.Infer(tt.Type))
+				requireNoShortRead(t, buf.Buf, colAware(&dec, rows))
+			})
+		})
+	}
+	t.Run("Truncated", func(t *testing.T) {
+		for _, tt := range []struct {
+			Type  ColumnType
+			State []byte
+		}{
+			{Type: "AggregateFunction(count)", State: nil},
+			{Type: "AggregateFunction(sum, UInt8)", State: []byte{1, 2}},
+			{Type: "AggregateFunction(avg, Float32)", State: []byte{1, 2, 3}},
+			{Type: "AggregateFunction(avg, Float32)", State: make([]byte, 8)},
+			{Type: "AggregateFunction(max, Int32)", State: nil},
+			{Type: "AggregateFunction(max, Int32)", State: []byte{boolTrue, 1}},
+		} {
+			var col ColAggregateFunction
+			require.NoError(t, col.Infer(tt.Type))
+			col.Append(tt.State)
+			_, err := col.State(0)
+			require.Error(t, err, "%s: %v", tt.Type, tt.State)
+		}
+	})
+	t.Run("Unsupported", func(t *testing.T) {
+		for _, v := range []ColumnType{
+			"AggregateFunction(uniq, UInt64)",
+			"AggregateFunction(max, String)",
+			"AggregateFunction(quantiles(0.5, 0.9), UInt64)",
+			"AggregateFunction(sum, UInt8, UInt8)",
+			"Int8",
+		} {
+			var dec ColAggregateFunction
+			require.Error(t, dec.Infer(v), v)
+		}
+	})
+}
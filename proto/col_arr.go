@@ -118,6 +118,10 @@ func (c *ColArr[T]) DecodeColumn(r *Reader, rows int) error {
 	return nil
 }
 
+func (c ColArr[T]) arrayData() (ColUInt64, ColInput) {
+	return c.Offsets, c.Data
+}
+
 // Reset implements ColResult.
 func (c *ColArr[T]) Reset() {
 	c.Data.Reset()
@@ -153,3 +157,18 @@ func (c *ColArr[T]) Result(column string) ResultColumn {
 func (c *ColArr[T]) Results(column string) Results {
 	return Results{c.Result(column)}
 }
+
+// Slice returns rows [start, end) of column, sharing memory with elements
+// column. Offsets are copied, because they are rebased to zero.
+func (c ColArr[T]) Slice(start, end int) ColInput {
+	offsets, from, to := sliceOffsets(c.Offsets, start, end)
+	data := sliceColumn(c.Data, from, to)
+	if data == nil {
+		return nil
+	}
+	return &colSlice{
+		t:     c.Type(),
+		rows:  end - start,
+		parts: []ColInput{offsets, data},
+	}
+}
@@ -82,6 +82,14 @@ func (c *ColAuto) Infer(t ColumnType) error {
 			c.Data = v
 			c.DataType = t
 			return nil
+		case ColumnTypeAggregateFunction:
+			v := new(ColAggregateFunction)
+			if err := v.Infer(t); err != nil {
+				return errors.Wrap(err, "aggregate function")
+			}
+			c.Data = v
+			c.DataType = t
+			return nil
 		case ColumnTypeDateTime64:
 			v := new(ColDateTime64)
 			if err := v.Infer(t); err != nil {
@@ -122,3 +130,8 @@ func (c ColAuto) Reset() {
 func (c ColAuto) EncodeColumn(b *Buffer) {
 	c.Data.EncodeColumn(b)
 }
+
+// Slice returns rows [start, end) of inferred column.
+func (c ColAuto) Slice(start, end int) ColInput {
+	return sliceColumn(c.Data, start, end)
+}
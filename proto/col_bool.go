@@ -51,3 +51,8 @@ func (c *ColBool) Nullable() *ColNullable[bool] {
 		Values: c,
 	}
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColBool) Slice(start, end int) ColInput {
+	return c[start:end]
+}
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColDate32{}
 	_ ColResult = (*ColDate32)(nil)
 	_ Column    = (*ColDate32)(nil)
+	_ Sliceable = ColDate32{}
 )
 
 // Rows returns count of rows in column.
@@ -26,3 +27,8 @@ func (c *ColDate32) Reset() {
 func (ColDate32) Type() ColumnType {
 	return ColumnTypeDate32
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDate32) Slice(start, end int) ColInput {
+	return c[start:end]
+}
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColDate{}
 	_ ColResult = (*ColDate)(nil)
 	_ Column    = (*ColDate)(nil)
+	_ Sliceable = ColDate{}
 )
 
 // Rows returns count of rows in column.
@@ -26,3 +27,8 @@ func (c *ColDate) Reset() {
 func (ColDate) Type() ColumnType {
 	return ColumnTypeDate
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDate) Slice(start, end int) ColInput {
+	return c[start:end]
+}
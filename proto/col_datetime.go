@@ -102,3 +102,11 @@ func NewArrDateTime() *ColArr[time.Time] {
 		Data: &ColDateTime{},
 	}
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDateTime) Slice(start, end int) ColInput {
+	return &ColDateTime{
+		Data:     c.Data[start:end],
+		Location: c.Location,
+	}
+}
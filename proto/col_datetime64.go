@@ -81,6 +81,8 @@ func (c *ColDateTime64) Infer(t ColumnType) error {
 			return errors.Wrap(err, "invalid location")
 		}
 		c.Location = loc
+	} else {
+		c.Location = nil
 	}
 	return nil
 }
@@ -148,3 +150,10 @@ func (c *ColDateTime64Raw) AppendArr(vs []DateTime64) {
 	}
 }
 func (c ColDateTime64Raw) Row(i int) DateTime64 { return c.Data[i] }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDateTime64) Slice(start, end int) ColInput {
+	s := c
+	s.Data = c.Data[start:end]
+	return &s
+}
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColDecimal128{}
 	_ ColResult = (*ColDecimal128)(nil)
 	_ Column    = (*ColDecimal128)(nil)
+	_ Sliceable = ColDecimal128{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColDecimal128) Type() ColumnType {
 	return ColumnTypeDecimal128
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDecimal128) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColDecimal128) Row(i int) Decimal128 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColDecimal256{}
 	_ ColResult = (*ColDecimal256)(nil)
 	_ Column    = (*ColDecimal256)(nil)
+	_ Sliceable = ColDecimal256{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColDecimal256) Type() ColumnType {
 	return ColumnTypeDecimal256
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDecimal256) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColDecimal256) Row(i int) Decimal256 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColDecimal32{}
 	_ ColResult = (*ColDecimal32)(nil)
 	_ Column    = (*ColDecimal32)(nil)
+	_ Sliceable = ColDecimal32{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColDecimal32) Type() ColumnType {
 	return ColumnTypeDecimal32
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDecimal32) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColDecimal32) Row(i int) Decimal32 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColDecimal64{}
 	_ ColResult = (*ColDecimal64)(nil)
 	_ Column    = (*ColDecimal64)(nil)
+	_ Sliceable = ColDecimal64{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColDecimal64) Type() ColumnType {
 	return ColumnTypeDecimal64
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColDecimal64) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColDecimal64) Row(i int) Decimal64 {
 	return c[i]
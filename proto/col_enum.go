@@ -84,6 +84,12 @@ func (e *ColEnum) parse(t ColumnType) error {
 	return nil
 }
 
+// hasValue reports whether v is in enum mapping.
+func (e *ColEnum) hasValue(v string) bool {
+	_, ok := e.strToRaw[v]
+	return ok
+}
+
 func (e *ColEnum) Infer(t ColumnType) error {
 	if !strings.HasPrefix(t.Base().String(), "Enum") {
 		return errors.Errorf("invalid base %q to infer enum", t.Base())
@@ -170,3 +176,14 @@ func (e *ColEnum) EncodeColumn(b *Buffer) {
 }
 
 func (e *ColEnum) Type() ColumnType { return e.t }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (e *ColEnum) Slice(start, end int) ColInput {
+	return &ColEnum{
+		t:        e.t,
+		base:     e.base,
+		rawToStr: e.rawToStr,
+		strToRaw: e.strToRaw,
+		Values:   e.Values[start:end],
+	}
+}
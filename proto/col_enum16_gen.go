@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColEnum16{}
 	_ ColResult = (*ColEnum16)(nil)
 	_ Column    = (*ColEnum16)(nil)
+	_ Sliceable = ColEnum16{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColEnum16) Type() ColumnType {
 	return ColumnTypeEnum16
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColEnum16) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColEnum16) Row(i int) Enum16 {
 	return c[i]
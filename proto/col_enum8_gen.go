@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColEnum8{}
 	_ ColResult = (*ColEnum8)(nil)
 	_ Column    = (*ColEnum8)(nil)
+	_ Sliceable = ColEnum8{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColEnum8) Type() ColumnType {
 	return ColumnTypeEnum8
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColEnum8) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColEnum8) Row(i int) Enum8 {
 	return c[i]
@@ -92,3 +92,11 @@ func (c *ColFixedStr) Array() *ColArr[[]byte] {
 		Data: c,
 	}
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr) Slice(start, end int) ColInput {
+	return ColFixedStr{
+		Buf:  c.Buf[start*c.Size : end*c.Size],
+		Size: c.Size,
+	}
+}
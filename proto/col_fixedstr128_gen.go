@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFixedStr128{}
 	_ ColResult = (*ColFixedStr128)(nil)
 	_ Column    = (*ColFixedStr128)(nil)
+	_ Sliceable = ColFixedStr128{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFixedStr128) Type() ColumnType {
 	return ColumnTypeFixedString.With("128")
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr128) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFixedStr128) Row(i int) [128]byte {
 	return c[i]
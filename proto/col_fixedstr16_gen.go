@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFixedStr16{}
 	_ ColResult = (*ColFixedStr16)(nil)
 	_ Column    = (*ColFixedStr16)(nil)
+	_ Sliceable = ColFixedStr16{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFixedStr16) Type() ColumnType {
 	return ColumnTypeFixedString.With("16")
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr16) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFixedStr16) Row(i int) [16]byte {
 	return c[i]
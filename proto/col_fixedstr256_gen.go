@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFixedStr256{}
 	_ ColResult = (*ColFixedStr256)(nil)
 	_ Column    = (*ColFixedStr256)(nil)
+	_ Sliceable = ColFixedStr256{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFixedStr256) Type() ColumnType {
 	return ColumnTypeFixedString.With("256")
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr256) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFixedStr256) Row(i int) [256]byte {
 	return c[i]
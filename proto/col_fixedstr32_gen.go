@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFixedStr32{}
 	_ ColResult = (*ColFixedStr32)(nil)
 	_ Column    = (*ColFixedStr32)(nil)
+	_ Sliceable = ColFixedStr32{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFixedStr32) Type() ColumnType {
 	return ColumnTypeFixedString.With("32")
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr32) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFixedStr32) Row(i int) [32]byte {
 	return c[i]
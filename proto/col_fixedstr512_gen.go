@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFixedStr512{}
 	_ ColResult = (*ColFixedStr512)(nil)
 	_ Column    = (*ColFixedStr512)(nil)
+	_ Sliceable = ColFixedStr512{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFixedStr512) Type() ColumnType {
 	return ColumnTypeFixedString.With("512")
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr512) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFixedStr512) Row(i int) [512]byte {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFixedStr64{}
 	_ ColResult = (*ColFixedStr64)(nil)
 	_ Column    = (*ColFixedStr64)(nil)
+	_ Sliceable = ColFixedStr64{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFixedStr64) Type() ColumnType {
 	return ColumnTypeFixedString.With("64")
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr64) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFixedStr64) Row(i int) [64]byte {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFixedStr8{}
 	_ ColResult = (*ColFixedStr8)(nil)
 	_ Column    = (*ColFixedStr8)(nil)
+	_ Sliceable = ColFixedStr8{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFixedStr8) Type() ColumnType {
 	return ColumnTypeFixedString.With("8")
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFixedStr8) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFixedStr8) Row(i int) [8]byte {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFloat32{}
 	_ ColResult = (*ColFloat32)(nil)
 	_ Column    = (*ColFloat32)(nil)
+	_ Sliceable = ColFloat32{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFloat32) Type() ColumnType {
 	return ColumnTypeFloat32
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFloat32) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFloat32) Row(i int) float32 {
 	return c[i]
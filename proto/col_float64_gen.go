@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColFloat64{}
 	_ ColResult = (*ColFloat64)(nil)
 	_ Column    = (*ColFloat64)(nil)
+	_ Sliceable = ColFloat64{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColFloat64) Type() ColumnType {
 	return ColumnTypeFloat64
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColFloat64) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColFloat64) Row(i int) float64 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColInt128{}
 	_ ColResult = (*ColInt128)(nil)
 	_ Column    = (*ColInt128)(nil)
+	_ Sliceable = ColInt128{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColInt128) Type() ColumnType {
 	return ColumnTypeInt128
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColInt128) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColInt128) Row(i int) Int128 {
 	return c[i]
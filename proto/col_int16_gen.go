@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColInt16{}
 	_ ColResult = (*ColInt16)(nil)
 	_ Column    = (*ColInt16)(nil)
+	_ Sliceable = ColInt16{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColInt16) Type() ColumnType {
 	return ColumnTypeInt16
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColInt16) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColInt16) Row(i int) int16 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColInt256{}
 	_ ColResult = (*ColInt256)(nil)
 	_ Column    = (*ColInt256)(nil)
+	_ Sliceable = ColInt256{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColInt256) Type() ColumnType {
 	return ColumnTypeInt256
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColInt256) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColInt256) Row(i int) Int256 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColInt32{}
 	_ ColResult = (*ColInt32)(nil)
 	_ Column    = (*ColInt32)(nil)
+	_ Sliceable = ColInt32{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColInt32) Type() ColumnType {
 	return ColumnTypeInt32
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColInt32) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColInt32) Row(i int) int32 {
 	return c[i]
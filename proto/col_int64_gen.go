@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColInt64{}
 	_ ColResult = (*ColInt64)(nil)
 	_ Column    = (*ColInt64)(nil)
+	_ Sliceable = ColInt64{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColInt64) Type() ColumnType {
 	return ColumnTypeInt64
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColInt64) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColInt64) Row(i int) int64 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColInt8{}
 	_ ColResult = (*ColInt8)(nil)
 	_ Column    = (*ColInt8)(nil)
+	_ Sliceable = ColInt8{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColInt8) Type() ColumnType {
 	return ColumnTypeInt8
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColInt8) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColInt8) Row(i int) int8 {
 	return c[i]
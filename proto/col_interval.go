@@ -110,3 +110,11 @@ func (c *ColInterval) Reset() {
 func (c ColInterval) EncodeColumn(b *Buffer) {
 	c.Values.EncodeColumn(b)
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColInterval) Slice(start, end int) ColInput {
+	return ColInterval{
+		Scale:  c.Scale,
+		Values: c.Values[start:end],
+	}
+}
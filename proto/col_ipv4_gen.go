@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColIPv4{}
 	_ ColResult = (*ColIPv4)(nil)
 	_ Column    = (*ColIPv4)(nil)
+	_ Sliceable = ColIPv4{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColIPv4) Type() ColumnType {
 	return ColumnTypeIPv4
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColIPv4) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColIPv4) Row(i int) IPv4 {
 	return c[i]
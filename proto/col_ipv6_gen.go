@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColIPv6{}
 	_ ColResult = (*ColIPv6)(nil)
 	_ Column    = (*ColIPv6)(nil)
+	_ Sliceable = ColIPv6{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColIPv6) Type() ColumnType {
 	return ColumnTypeIPv6
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColIPv6) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColIPv6) Row(i int) IPv6 {
 	return c[i]
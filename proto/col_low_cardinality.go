@@ -2,6 +2,7 @@ package proto
 
 import (
 	"math"
+	"reflect"
 
 	"github.com/go-faster/errors"
 )
@@ -345,3 +346,30 @@ func NewLowCardinality[T comparable](c ColumnOf[T]) *ColLowCardinality[T] {
 		index: c,
 	}
 }
+
+// Slice returns rows [start, end) of column, sharing values with it.
+//
+// Slice has own index column, so each slice is encoded with own dictionary
+// and c is not modified.
+func (c *ColLowCardinality[T]) Slice(start, end int) ColInput {
+	return &ColLowCardinality[T]{
+		Values: c.Values[start:end],
+		index:  emptyColumnOf(c.index),
+	}
+}
+
+// emptyColumnOf returns new empty column of same type as c, keeping
+// non-slice fields (like DateTime location) but no data.
+func emptyColumnOf[T any](c ColumnOf[T]) ColumnOf[T] {
+	v := reflect.ValueOf(c).Elem()
+	n := reflect.New(v.Type())
+	if v.Kind() == reflect.Struct {
+		n.Elem().Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := n.Elem().Field(i); f.Kind() == reflect.Slice && f.CanSet() {
+				f.SetZero()
+			}
+		}
+	}
+	return n.Interface().(ColumnOf[T])
+}
@@ -155,3 +155,26 @@ func (c ColLowCardinalityRaw) EncodeColumn(b *Buffer) {
 	b.PutInt64(int64(k.Rows()))
 	k.EncodeColumn(b)
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+//
+// Index is not sliced, so each slice is encoded with whole dictionary.
+func (c ColLowCardinalityRaw) Slice(start, end int) ColInput {
+	s := ColLowCardinalityRaw{
+		Index: c.Index,
+		Key:   c.Key,
+	}
+	switch c.Key {
+	case KeyUInt8:
+		s.Keys8 = c.Keys8[start:end]
+	case KeyUInt16:
+		s.Keys16 = c.Keys16[start:end]
+	case KeyUInt32:
+		s.Keys32 = c.Keys32[start:end]
+	case KeyUInt64:
+		s.Keys64 = c.Keys64[start:end]
+	default:
+		return nil
+	}
+	return &s
+}
@@ -199,3 +199,19 @@ func (c *ColMap[K, V]) Infer(t ColumnType) error {
 	}
 	return nil
 }
+
+// Slice returns rows [start, end) of column, sharing memory with keys and
+// values columns. Offsets are copied, because they are rebased to zero.
+func (c ColMap[K, V]) Slice(start, end int) ColInput {
+	offsets, from, to := sliceOffsets(c.Offsets, start, end)
+	keys := sliceColumn(c.Keys, from, to)
+	values := sliceColumn(c.Values, from, to)
+	if keys == nil || values == nil {
+		return nil
+	}
+	return &colSlice{
+		t:     c.Type(),
+		rows:  end - start,
+		parts: []ColInput{offsets, keys, values},
+	}
+}
@@ -71,3 +71,8 @@ func (c ColNothing) EncodeColumn(b *Buffer) {
 	}
 	b.PutRaw(make([]byte, c))
 }
+
+// Slice returns rows [start, end) of column.
+func (c ColNothing) Slice(start, end int) ColInput {
+	return ColNothing(end - start)
+}
@@ -133,3 +133,20 @@ func (c ColNullable[T]) IsElemNull(i int) bool {
 	}
 	return false
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColNullable[T]) Slice(start, end int) ColInput {
+	values := sliceColumn(c.Values, start, end)
+	if values == nil {
+		return nil
+	}
+	return &colSlice{
+		t:     c.Type(),
+		rows:  end - start,
+		parts: []ColInput{c.Nulls[start:end], values},
+	}
+}
+
+func (c ColNullable[T]) nullableValues() ColInput {
+	return c.Values
+}
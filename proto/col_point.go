@@ -61,3 +61,11 @@ func (c ColPoint) EncodeColumn(b *Buffer) {
 	c.X.EncodeColumn(b)
 	c.Y.EncodeColumn(b)
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColPoint) Slice(start, end int) ColInput {
+	return ColPoint{
+		X: c.X[start:end],
+		Y: c.Y[start:end],
+	}
+}
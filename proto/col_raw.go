@@ -33,3 +33,13 @@ func (c *ColRaw) Reset() {
 	c.Count = 0
 	c.Data = c.Data[:0]
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColRaw) Slice(start, end int) ColInput {
+	return &ColRaw{
+		T:     c.T,
+		Size:  c.Size,
+		Data:  c.Data[start*c.Size : end*c.Size],
+		Count: end - start,
+	}
+}
@@ -82,3 +82,8 @@ func (c *ColRawOf[X]) DecodeColumn(r *Reader, rows int) error {
 	}
 	return nil
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColRawOf[X]) Slice(start, end int) ColInput {
+	return c[start:end]
+}
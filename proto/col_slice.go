@@ -0,0 +1,76 @@
+package proto
+
+import "github.com/go-faster/errors"
+
+// Compile-time assertions for colSlice.
+var (
+	_ ColInput     = (*colSlice)(nil)
+	_ StateEncoder = (*colSlice)(nil)
+	_ Preparable   = (*colSlice)(nil)
+)
+
+// colSlice is rows range of composite column, encoded as sequence
+// of sliced sub-columns, e.g. offsets and data for Array.
+type colSlice struct {
+	t     ColumnType
+	rows  int
+	parts []ColInput
+}
+
+func (c *colSlice) Type() ColumnType { return c.t }
+func (c *colSlice) Rows() int        { return c.rows }
+
+func (c *colSlice) EncodeColumn(b *Buffer) {
+	for _, p := range c.parts {
+		p.EncodeColumn(b)
+	}
+}
+
+// EncodeState implements StateEncoder, ensuring state of sub-columns.
+func (c *colSlice) EncodeState(b *Buffer) {
+	for _, p := range c.parts {
+		if s, ok := p.(StateEncoder); ok {
+			s.EncodeState(b)
+		}
+	}
+}
+
+// Prepare ensures Preparable column propagation.
+func (c *colSlice) Prepare() error {
+	for i, p := range c.parts {
+		if v, ok := p.(Preparable); ok {
+			if err := v.Prepare(); err != nil {
+				return errors.Wrapf(err, "prepare %d", i)
+			}
+		}
+	}
+	return nil
+}
+
+// sliceColumn returns rows [start, end) of column or nil if column is
+// not Sliceable.
+func sliceColumn(c ColInput, start, end int) ColInput {
+	s, ok := c.(Sliceable)
+	if !ok {
+		return nil
+	}
+	return s.Slice(start, end)
+}
+
+// sliceOffsets returns offsets of rows [start, end) rebased to zero and
+// range of corresponding elements.
+func sliceOffsets(offsets ColUInt64, start, end int) (ColUInt64, int, int) {
+	var base uint64
+	if start > 0 {
+		base = offsets[start-1]
+	}
+	rebased := make(ColUInt64, 0, end-start)
+	for _, o := range offsets[start:end] {
+		rebased = append(rebased, o-base)
+	}
+	last := base
+	if end > start {
+		last = offsets[end-1]
+	}
+	return rebased, int(base), int(last)
+}
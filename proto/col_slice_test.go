@@ -0,0 +1,306 @@
+package proto
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeSliceTest(t testing.TB, c ColInput) []byte {
+	t.Helper()
+	var buf Buffer
+	input := []InputColumn{{Name: "v", Data: c}}
+	require.NoError(t, Block{Columns: 1, Rows: c.Rows()}.EncodeRawBlock(&buf, Version, input))
+	return buf.Buf
+}
+
+// testColumnSlice checks that slices of col are encoded same as columns
+// that are filled with corresponding rows.
+func testColumnSlice[T any](t *testing.T, col ColumnOf[T], newCol func() ColumnOf[T]) {
+	t.Helper()
+	rows := col.Rows()
+	require.Greater(t, rows, 4)
+	full := encodeSliceTest(t, col)
+	for _, r := range [][2]int{
+		{0, rows},
+		{0, 1},
+		{1, rows / 2},
+		{rows / 2, rows},
+		{rows - 1, rows},
+		{3, 3},
+	} {
+		start, end := r[0], r[1]
+		t.Run(fmt.Sprintf("%d-%d", start, end), func(t *testing.T) {
+			expected := newCol()
+			for i := start; i < end; i++ {
+				expected.Append(col.Row(i))
+			}
+			s, ok := col.(Sliceable)
+			require.True(t, ok, "%T should be Sliceable", col)
+			v := s.Slice(start, end)
+			require.NotNil(t, v)
+			require.Equal(t, end-start, v.Rows())
+			require.Equal(t, expected.Type(), v.Type())
+			require.Equal(t, encodeSliceTest(t, expected), encodeSliceTest(t, v))
+		})
+	}
+	require.Equal(t, full, encodeSliceTest(t, col), "column should not be changed")
+}
+
+func TestColumnSlice(t *testing.T) {
+	const rows = 50
+	strs := func(i int) string { return fmt.Sprintf("s%d", i%7) }
+	t.Run("Int64", func(t *testing.T) {
+		col := new(ColInt64)
+		for i := 0; i < rows; i++ {
+			col.Append(int64(i))
+		}
+		testColumnSlice[int64](t, col, func() ColumnOf[int64] { return new(ColInt64) })
+	})
+	t.Run("Str", func(t *testing.T) {
+		col := new(ColStr)
+		for i := 0; i < rows; i++ {
+			col.Append(strs(i))
+		}
+		testColumnSlice[string](t, col, func() ColumnOf[string] { return new(ColStr) })
+	})
+	t.Run("Bytes", func(t *testing.T) {
+		col := new(ColBytes)
+		for i := 0; i < rows; i++ {
+			col.Append([]byte(strs(i)))
+		}
+		testColumnSlice[[]byte](t, col, func() ColumnOf[[]byte] { return new(ColBytes) })
+	})
+	t.Run("FixedStr", func(t *testing.T) {
+		newCol := func() ColumnOf[[]byte] { return &ColFixedStr{Size: 4} }
+		col := newCol()
+		for i := 0; i < rows; i++ {
+			col.Append([]byte(fmt.Sprintf("%04d", i)))
+		}
+		testColumnSlice[[]byte](t, col, newCol)
+	})
+	t.Run("Bool", func(t *testing.T) {
+		col := new(ColBool)
+		for i := 0; i < rows; i++ {
+			col.Append(i%3 == 0)
+		}
+		testColumnSlice[bool](t, col, func() ColumnOf[bool] { return new(ColBool) })
+	})
+	t.Run("UUID", func(t *testing.T) {
+		col := new(ColUUID)
+		for i := 0; i < rows; i++ {
+			col.Append(uuid.New())
+		}
+		testColumnSlice[uuid.UUID](t, col, func() ColumnOf[uuid.UUID] { return new(ColUUID) })
+	})
+	t.Run("DateTime", func(t *testing.T) {
+		loc := time.FixedZone("Test", 3600)
+		newCol := func() ColumnOf[time.Time] { return &ColDateTime{Location: loc} }
+		col := newCol()
+		for i := 0; i < rows; i++ {
+			col.Append(time.Unix(int64(i)*3600, 0))
+		}
+		testColumnSlice[time.Time](t, col, newCol)
+	})
+	t.Run("DateTime64", func(t *testing.T) {
+		newCol := func() ColumnOf[time.Time] {
+			return new(ColDateTime64).WithPrecision(PrecisionMilli)
+		}
+		col := newCol()
+		for i := 0; i < rows; i++ {
+			col.Append(time.UnixMilli(int64(i) * 1500))
+		}
+		testColumnSlice[time.Time](t, col, newCol)
+	})
+	t.Run("Enum", func(t *testing.T) {
+		newCol := func() ColumnOf[string] {
+			e := new(ColEnum)
+			require.NoError(t, e.Infer("Enum8('a' = 1, 'b' = 2, 'c' = 3)"))
+			return e
+		}
+		col := newCol()
+		for i := 0; i < rows; i++ {
+			col.Append(string(rune('a' + i%3)))
+		}
+		testColumnSlice[string](t, col, newCol)
+	})
+	t.Run("Nothing", func(t *testing.T) {
+		col := new(ColNothing)
+		for i := 0; i < rows; i++ {
+			col.Append(Nothing{})
+		}
+		testColumnSlice[Nothing](t, col, func() ColumnOf[Nothing] { return new(ColNothing) })
+	})
+	t.Run("Point", func(t *testing.T) {
+		col := new(ColPoint)
+		for i := 0; i < rows; i++ {
+			col.Append(Point{X: float64(i), Y: -float64(i)})
+		}
+		testColumnSlice[Point](t, col, func() ColumnOf[Point] { return new(ColPoint) })
+	})
+	t.Run("Interval", func(t *testing.T) {
+		col := ColInterval{Scale: IntervalMinute}
+		for i := 0; i < rows; i++ {
+			col.Append(Interval{Scale: IntervalMinute, Value: int64(i)})
+		}
+		v := col.Slice(10, 20)
+		require.Equal(t, col.Type(), v.Type())
+		require.Equal(t,
+			encodeSliceTest(t, ColInterval{Scale: IntervalMinute, Values: col.Values[10:20]}),
+			encodeSliceTest(t, v),
+		)
+	})
+	t.Run("LowCardinality", func(t *testing.T) {
+		col := new(ColStr).LowCardinality()
+		for i := 0; i < rows; i++ {
+			col.Append(strs(i))
+		}
+		testColumnSlice[string](t, col, func() ColumnOf[string] {
+			return new(ColStr).LowCardinality()
+		})
+	})
+	t.Run("LowCardinalityLive", func(t *testing.T) {
+		col := new(ColStr).LowCardinality()
+		for i := 0; i < rows; i++ {
+			col.Append(fmt.Sprintf("s%d", i))
+		}
+		require.NoError(t, col.Prepare())
+		var full Buffer
+		col.EncodeColumn(&full)
+
+		encode := func(c ColInput) []byte {
+			var buf Buffer
+			c.EncodeColumn(&buf)
+			return buf.Buf
+		}
+		expected := func(start, end int) []byte {
+			c := new(ColStr).LowCardinality()
+			c.AppendArr(col.Values[start:end])
+			require.NoError(t, c.Prepare())
+			return encode(c)
+		}
+
+		a := col.Slice(0, 10).(*ColLowCardinality[string])
+		b := col.Slice(20, 25).(*ColLowCardinality[string])
+		require.NoError(t, a.Prepare())
+		require.NoError(t, b.Prepare())
+		require.Equal(t, expected(0, 10), encode(a))
+		require.Equal(t, expected(20, 25), encode(b))
+		require.Equal(t, full.Buf, encode(col), "column should not be changed")
+	})
+	t.Run("LowCardinalityDateTime", func(t *testing.T) {
+		loc := time.FixedZone("UTC+3", 3*60*60)
+		col := (&ColDateTime{Location: loc}).LowCardinality()
+		for i := 0; i < rows; i++ {
+			col.Append(time.Unix(int64(i%5)*3600, 0).In(loc))
+		}
+		v := col.Slice(10, 20)
+		require.Equal(t, col.Type(), v.Type())
+		testColumnSlice[time.Time](t, col, func() ColumnOf[time.Time] {
+			return (&ColDateTime{Location: loc}).LowCardinality()
+		})
+	})
+	t.Run("Nullable", func(t *testing.T) {
+		col := new(ColStr).Nullable()
+		for i := 0; i < rows; i++ {
+			v := NewNullable(strs(i))
+			if i%4 == 0 {
+				v = Null[string]()
+			}
+			col.Append(v)
+		}
+		testColumnSlice[Nullable[string]](t, col, func() ColumnOf[Nullable[string]] {
+			return new(ColStr).Nullable()
+		})
+	})
+	t.Run("Array", func(t *testing.T) {
+		col := new(ColStr).Array()
+		for i := 0; i < rows; i++ {
+			var v []string
+			for j := 0; j < i%4; j++ {
+				v = append(v, strs(i+j))
+			}
+			col.Append(v)
+		}
+		testColumnSlice[[]string](t, col, func() ColumnOf[[]string] {
+			return new(ColStr).Array()
+		})
+	})
+	t.Run("ArrayLowCardinality", func(t *testing.T) {
+		col := new(ColStr).LowCardinality().Array()
+		for i := 0; i < rows; i++ {
+			col.Append([]string{strs(i), strs(i + 1)})
+		}
+		testColumnSlice[[]string](t, col, func() ColumnOf[[]string] {
+			return new(ColStr).LowCardinality().Array()
+		})
+	})
+	t.Run("ArrayArray", func(t *testing.T) {
+		newCol := func() ColumnOf[[][]int8] {
+			return NewArray[[]int8](NewArray[int8](new(ColInt8)))
+		}
+		col := newCol()
+		for i := 0; i < rows; i++ {
+			v := make([][]int8, i%3)
+			for j := range v {
+				v[j] = make([]int8, j+i%2)
+			}
+			col.Append(v)
+		}
+		testColumnSlice[[][]int8](t, col, newCol)
+	})
+	t.Run("Map", func(t *testing.T) {
+		newCol := func() ColumnOf[map[string]int64] {
+			return NewMap[string, int64](new(ColStr).LowCardinality(), new(ColInt64))
+		}
+		col := newCol()
+		for i := 0; i < rows; i++ {
+			// Single key, so order of keys is deterministic.
+			m := map[string]int64{}
+			if i%3 != 0 {
+				m[strs(i)] = int64(i)
+			}
+			col.Append(m)
+		}
+		testColumnSlice[map[string]int64](t, col, newCol)
+	})
+	t.Run("Named", func(t *testing.T) {
+		newCol := func() ColumnOf[string] { return Named[string](new(ColStr), "name") }
+		col := newCol()
+		for i := 0; i < rows; i++ {
+			col.Append(strs(i))
+		}
+		testColumnSlice[string](t, col, newCol)
+	})
+	t.Run("Tuple", func(t *testing.T) {
+		var (
+			a = new(ColInt64)
+			b = new(ColStr)
+		)
+		for i := 0; i < rows; i++ {
+			a.Append(int64(i))
+			b.Append(strs(i))
+		}
+		v := ColTuple{a, b}.Slice(10, 20)
+		aSlice := (*a)[10:20]
+		require.Equal(t, 10, v.Rows())
+		require.Equal(t, ColumnType("Tuple(Int64, String)"), v.Type())
+		require.Equal(t,
+			encodeSliceTest(t, ColTuple{&aSlice, &ColStr{Buf: b.Buf, Pos: b.Pos[10:20]}}),
+			encodeSliceTest(t, v),
+		)
+	})
+	t.Run("NotSliceable", func(t *testing.T) {
+		col := NewArray[string](colNoSlice{new(ColStr)})
+		col.Append([]string{"foo"})
+		require.Nil(t, col.Slice(0, 1))
+	})
+}
+
+// colNoSlice hides Slice method of column.
+type colNoSlice struct {
+	ColumnOf[string]
+}
@@ -203,3 +203,11 @@ func (c *ColBytes) Nullable() *ColNullable[[]byte] {
 		Values: c,
 	}
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColStr) Slice(start, end int) ColInput {
+	return ColStr{
+		Buf: c.Buf,
+		Pos: c.Pos[start:end],
+	}
+}
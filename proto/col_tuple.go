@@ -167,3 +167,33 @@ func (c ColTuple) EncodeColumn(b *Buffer) {
 		v.EncodeColumn(b)
 	}
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColNamed[T]) Slice(start, end int) ColInput {
+	data := sliceColumn(c.ColumnOf, start, end)
+	if data == nil {
+		return nil
+	}
+	return &colSlice{
+		t:     c.Type(),
+		rows:  end - start,
+		parts: []ColInput{data},
+	}
+}
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColTuple) Slice(start, end int) ColInput {
+	parts := make([]ColInput, 0, len(c))
+	for _, v := range c {
+		data := sliceColumn(v, start, end)
+		if data == nil {
+			return nil
+		}
+		parts = append(parts, data)
+	}
+	return &colSlice{
+		t:     c.Type(),
+		rows:  end - start,
+		parts: parts,
+	}
+}
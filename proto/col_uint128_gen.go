@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColUInt128{}
 	_ ColResult = (*ColUInt128)(nil)
 	_ Column    = (*ColUInt128)(nil)
+	_ Sliceable = ColUInt128{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColUInt128) Type() ColumnType {
 	return ColumnTypeUInt128
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColUInt128) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColUInt128) Row(i int) UInt128 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColUInt16{}
 	_ ColResult = (*ColUInt16)(nil)
 	_ Column    = (*ColUInt16)(nil)
+	_ Sliceable = ColUInt16{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColUInt16) Type() ColumnType {
 	return ColumnTypeUInt16
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColUInt16) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColUInt16) Row(i int) uint16 {
 	return c[i]
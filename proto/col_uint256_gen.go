@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColUInt256{}
 	_ ColResult = (*ColUInt256)(nil)
 	_ Column    = (*ColUInt256)(nil)
+	_ Sliceable = ColUInt256{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColUInt256) Type() ColumnType {
 	return ColumnTypeUInt256
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColUInt256) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColUInt256) Row(i int) UInt256 {
 	return c[i]
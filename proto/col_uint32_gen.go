@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColUInt32{}
 	_ ColResult = (*ColUInt32)(nil)
 	_ Column    = (*ColUInt32)(nil)
+	_ Sliceable = ColUInt32{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColUInt32) Type() ColumnType {
 	return ColumnTypeUInt32
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColUInt32) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColUInt32) Row(i int) uint32 {
 	return c[i]
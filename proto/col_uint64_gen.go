@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColUInt64{}
 	_ ColResult = (*ColUInt64)(nil)
 	_ Column    = (*ColUInt64)(nil)
+	_ Sliceable = ColUInt64{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColUInt64) Type() ColumnType {
 	return ColumnTypeUInt64
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColUInt64) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColUInt64) Row(i int) uint64 {
 	return c[i]
@@ -10,6 +10,7 @@ var (
 	_ ColInput  = ColUInt8{}
 	_ ColResult = (*ColUInt8)(nil)
 	_ Column    = (*ColUInt8)(nil)
+	_ Sliceable = ColUInt8{}
 )
 
 // Rows returns count of rows in column.
@@ -27,6 +28,11 @@ func (ColUInt8) Type() ColumnType {
 	return ColumnTypeUInt8
 }
 
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColUInt8) Slice(start, end int) ColInput {
+	return c[start:end]
+}
+
 // Row returns i-th row of column.
 func (c ColUInt8) Row(i int) uint8 {
 	return c[i]
@@ -31,3 +31,8 @@ func (c *ColUUID) Nullable() *ColNullable[uuid.UUID] {
 func (c *ColUUID) Array() *ColArr[uuid.UUID] {
 	return NewArray[uuid.UUID](c)
 }
+
+// Slice returns rows [start, end) of column, sharing memory with it.
+func (c ColUUID) Slice(start, end int) ColInput {
+	return c[start:end]
+}
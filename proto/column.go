@@ -58,6 +58,16 @@ type Preparable interface {
 	Prepare() error
 }
 
+// Sliceable column can return range of its rows as separate column,
+// e.g. for splitting large input to multiple blocks.
+type Sliceable interface {
+	// Slice returns rows [start, end) of column, sharing memory with it.
+	//
+	// Returns nil if column can't be sliced, e.g. if it contains
+	// column that is not Sliceable.
+	Slice(start, end int) ColInput
+}
+
 // TODO: merge preparable with inferable?
 
 // ColumnType is type of column element.
@@ -206,6 +216,7 @@ const (
 	ColumnTypeBool           ColumnType = "Bool"
 	ColumnTypeTuple          ColumnType = "Tuple"
 	ColumnTypeNullable       ColumnType = "Nullable"
+	ColumnTypeDecimal        ColumnType = "Decimal"
 	ColumnTypeDecimal32      ColumnType = "Decimal32"
 	ColumnTypeDecimal64      ColumnType = "Decimal64"
 	ColumnTypeDecimal128     ColumnType = "Decimal128"
@@ -213,6 +224,8 @@ const (
 	ColumnTypePoint          ColumnType = "Point"
 	ColumnTypeInterval       ColumnType = "Interval"
 	ColumnTypeNothing        ColumnType = "Nothing"
+
+	ColumnTypeAggregateFunction ColumnType = "AggregateFunction"
 )
 
 // colWrap wraps Column with type t.
@@ -272,14 +285,8 @@ func (s *ColInfoInput) DecodeResult(r *Reader, version int, b Block) error {
 		if err != nil {
 			return errors.Wrapf(err, "column [%d] type", i)
 		}
-		if FeatureCustomSerialization.In(version) {
-			customSerialization, err := r.Bool()
-			if err != nil {
-				return errors.Wrapf(err, "column [%d] custom serialization", i)
-			}
-			if customSerialization {
-				return errors.Wrapf(err, "column [%d] has custom serialization (not supported)", i)
-			}
+		if _, err := decodeSerialization(r, version, ColumnType(columnTypeRaw)); err != nil {
+			return errors.Wrapf(err, "column [%d]", i)
 		}
 		*s = append(*s, ColInfo{
 			Name: columnName,
@@ -32,6 +32,17 @@ const (
 	FeatureAddendum                    Feature = 54458
 	FeatureParameters                  Feature = 54459
 	FeatureServerQueryTimeInProgress   Feature = 54460
+	FeaturePasswordComplexityRules     Feature = 54461
+	FeatureInterServerSecretV2         Feature = 54462
+	FeatureTotalBytesInProgress        Feature = 54463
+	FeatureTimezoneUpdates             Feature = 54464
+	FeatureSparseSerialization         Feature = 54465
+	FeatureSSHAuthentication           Feature = 54466
+	FeatureTableReadOnlyCheck          Feature = 54467
+	FeatureSystemKeywordsTable         Feature = 54468
+	FeatureRowsBeforeAggregation       Feature = 54469
+	FeatureChunkedPackets              Feature = 54470
+	FeatureVersionedParallelReplicas   Feature = 54471
 )
 
 // Version reports protocol version when Feature was introduced.
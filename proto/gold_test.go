@@ -2,6 +2,7 @@ package proto
 
 import (
 	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/ClickHouse/ch-go/internal/gold"
@@ -25,3 +26,13 @@ func Gold(t testing.TB, v AwareEncoder, name ...string) {
 	v.EncodeAware(&b, Version)
 	gold.Bytes(t, b.Buf, name...)
 }
+
+// GoldRevision checks golden version of v encoding for protocol revision,
+// like _golden/revision/54460/ServerHello.
+func GoldRevision(t testing.TB, v AwareEncoder, revision int) []byte {
+	t.Helper()
+	var b Buffer
+	v.EncodeAware(&b, revision)
+	gold.Bytes(t, b.Buf, "revision", strconv.Itoa(revision), typeName(v))
+	return b.Buf
+}
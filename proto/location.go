@@ -0,0 +1,66 @@
+package proto
+
+import "time"
+
+// locatable is column of time values that can use default location.
+type locatable interface {
+	setDefaultLocation(loc *time.Location)
+}
+
+func (c *ColDateTime) setDefaultLocation(loc *time.Location) {
+	if c.Location == nil {
+		c.Location = loc
+	}
+}
+
+func (c *ColDateTime64) setDefaultLocation(loc *time.Location) {
+	if c.Location == nil {
+		c.Location = loc
+	}
+}
+
+func (c *ColNullable[T]) setDefaultLocation(loc *time.Location) {
+	if v, ok := c.Values.(locatable); ok {
+		v.setDefaultLocation(loc)
+	}
+}
+
+func (c *ColArr[T]) setDefaultLocation(loc *time.Location) {
+	if v, ok := c.Data.(locatable); ok {
+		v.setDefaultLocation(loc)
+	}
+}
+
+// SetDefaultLocation sets loc as Location of DateTime and DateTime64
+// columns of result that have no explicit time zone in type and no
+// Location set, including Nullable and Array elements.
+//
+// Used to apply session time zone of query, see ServerCodeTimezoneUpdate.
+// Supports Results, ResultColumn and Results.Auto result.
+func SetDefaultLocation(r Result, loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	var columns Results
+	switch r := r.(type) {
+	case Results:
+		columns = r
+	case *Results:
+		columns = *r
+	case autoResults:
+		columns = *r.results
+	case ResultColumn:
+		columns = Results{r}
+	case *ResultColumn:
+		columns = Results{*r}
+	}
+	for _, c := range columns {
+		data := c.Data
+		if auto, ok := data.(*ColAuto); ok {
+			data = auto.Data
+		}
+		if v, ok := data.(locatable); ok {
+			v.setDefaultLocation(loc)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package proto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDefaultLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	explicit, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	var (
+		dt       ColDateTime
+		dt64     ColDateTime64
+		dtTokyo  = ColDateTime{Location: explicit}
+		nullable = new(ColDateTime).Nullable()
+		arr      = new(ColDateTime64).Array()
+		auto     = ColAuto{}
+	)
+	require.NoError(t, auto.Infer(ColumnTypeDateTime))
+	results := Results{
+		{Name: "dt", Data: &dt},
+		{Name: "dt64", Data: &dt64},
+		{Name: "dt_tokyo", Data: &dtTokyo},
+		{Name: "nullable", Data: nullable},
+		{Name: "arr", Data: arr},
+		{Name: "auto", Data: &auto},
+	}
+
+	SetDefaultLocation(results, nil)
+	require.Nil(t, dt.Location)
+
+	SetDefaultLocation(results.Auto(), loc)
+	require.Equal(t, loc, dt.Location)
+	require.Equal(t, loc, dt64.Location)
+	require.Equal(t, explicit, dtTokyo.Location)
+	require.Equal(t, loc, nullable.Values.(*ColDateTime).Location)
+	require.Equal(t, loc, arr.Data.(*ColDateTime64).Location)
+	require.Equal(t, loc, auto.Data.(*ColDateTime).Location)
+
+	t.Run("ResultColumn", func(t *testing.T) {
+		var v ColDateTime
+		SetDefaultLocation(ResultColumn{Name: "v", Data: &v}, loc)
+		require.Equal(t, loc, v.Location)
+	})
+}
+
+func TestColDateTime64_InferLocation(t *testing.T) {
+	var v ColDateTime64
+	require.NoError(t, v.Infer("DateTime64(3, 'Asia/Tokyo')"))
+	require.Equal(t, "Asia/Tokyo", v.Location.String())
+
+	// Reused column should not keep location of previous type.
+	require.NoError(t, v.Infer("DateTime64(3)"))
+	require.Nil(t, v.Location)
+}
@@ -0,0 +1,72 @@
+package proto
+
+import (
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+)
+
+// PartUUIDs is list of unique data part identifiers that were read
+// during query, sent by server as ServerPartUUIDs.
+//
+// Initiator is expected to check them for duplicates and ask replicas
+// to skip duplicated parts via IgnoredPartUUIDs.
+type PartUUIDs struct {
+	UUIDs []uuid.UUID
+}
+
+func (p PartUUIDs) Encode(b *Buffer) {
+	ServerPartUUIDs.Encode(b)
+	encodeUUIDs(b, p.UUIDs)
+}
+
+func (p *PartUUIDs) Decode(r *Reader) error {
+	v, err := decodeUUIDs(r)
+	if err != nil {
+		return errors.Wrap(err, "uuids")
+	}
+	p.UUIDs = v
+	return nil
+}
+
+// IgnoredPartUUIDs is list of data part identifiers that server should
+// skip during query, sent by client as ClientCodeIgnoredPartUUIDs.
+type IgnoredPartUUIDs struct {
+	UUIDs []uuid.UUID
+}
+
+func (p IgnoredPartUUIDs) Encode(b *Buffer) {
+	ClientCodeIgnoredPartUUIDs.Encode(b)
+	encodeUUIDs(b, p.UUIDs)
+}
+
+func (p *IgnoredPartUUIDs) Decode(r *Reader) error {
+	v, err := decodeUUIDs(r)
+	if err != nil {
+		return errors.Wrap(err, "uuids")
+	}
+	p.UUIDs = v
+	return nil
+}
+
+func encodeUUIDs(b *Buffer, v []uuid.UUID) {
+	b.PutInt(len(v))
+	ColUUID(v).EncodeColumn(b)
+}
+
+func decodeUUIDs(r *Reader) ([]uuid.UUID, error) {
+	n, err := r.Int()
+	if err != nil {
+		return nil, errors.Wrap(err, "count")
+	}
+	if n < 0 || n > maxUUIDs {
+		return nil, errors.Errorf("invalid count %d", n)
+	}
+	var c ColUUID
+	if err := c.DecodeColumn(r, n); err != nil {
+		return nil, errors.Wrap(err, "column")
+	}
+	return c, nil
+}
+
+// maxUUIDs limits allocation on malformed input.
+const maxUUIDs = 1 << 20
@@ -0,0 +1,41 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/ch-go/internal/gold"
+)
+
+func TestPartUUIDs(t *testing.T) {
+	uuids := []uuid.UUID{
+		uuid.MustParse("3c5e3e2c-2ba4-4bb2-8b3a-4b1b8ad4a5f1"),
+		uuid.MustParse("a6e0c1f4-9a57-4e0a-9d69-0d9a2b8a2f43"),
+	}
+	t.Run("Server", func(t *testing.T) {
+		v := PartUUIDs{UUIDs: uuids}
+		var b Buffer
+		v.Encode(&b)
+		gold.Bytes(t, b.Buf, "part_uuids")
+
+		buf := skipCode(t, b.Buf, int(ServerPartUUIDs))
+		var dec PartUUIDs
+		requireDecode(t, buf, &dec)
+		require.Equal(t, v, dec)
+		requireNoShortRead(t, buf, &dec)
+	})
+	t.Run("Client", func(t *testing.T) {
+		v := IgnoredPartUUIDs{UUIDs: uuids}
+		var b Buffer
+		v.Encode(&b)
+		gold.Bytes(t, b.Buf, "ignored_part_uuids")
+
+		buf := skipCode(t, b.Buf, int(ClientCodeIgnoredPartUUIDs))
+		var dec IgnoredPartUUIDs
+		requireDecode(t, buf, &dec)
+		require.Equal(t, v, dec)
+		requireNoShortRead(t, buf, &dec)
+	})
+}
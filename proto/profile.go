@@ -9,9 +9,11 @@ type Profile struct {
 	AppliedLimit              bool
 	RowsBeforeLimit           uint64
 	CalculatedRowsBeforeLimit bool
+	AppliedAggregation        bool
+	RowsBeforeAggregation     uint64
 }
 
-func (p *Profile) DecodeAware(r *Reader, _ int) error {
+func (p *Profile) DecodeAware(r *Reader, version int) error {
 	{
 		v, err := r.UVarInt()
 		if err != nil {
@@ -54,11 +56,27 @@ func (p *Profile) DecodeAware(r *Reader, _ int) error {
 		}
 		p.CalculatedRowsBeforeLimit = v
 	}
+	if FeatureRowsBeforeAggregation.In(version) {
+		{
+			v, err := r.Bool()
+			if err != nil {
+				return errors.Wrap(err, "applied aggregation")
+			}
+			p.AppliedAggregation = v
+		}
+		{
+			v, err := r.UVarInt()
+			if err != nil {
+				return errors.Wrap(err, "rows before aggregation")
+			}
+			p.RowsBeforeAggregation = v
+		}
+	}
 
 	return nil
 }
 
-func (p Profile) EncodeAware(b *Buffer, _ int) {
+func (p Profile) EncodeAware(b *Buffer, version int) {
 	ServerCodeProfile.Encode(b)
 	b.PutUVarInt(p.Rows)
 	b.PutUVarInt(p.Blocks)
@@ -66,4 +84,8 @@ func (p Profile) EncodeAware(b *Buffer, _ int) {
 	b.PutBool(p.AppliedLimit)
 	b.PutUVarInt(p.RowsBeforeLimit)
 	b.PutBool(p.CalculatedRowsBeforeLimit)
+	if FeatureRowsBeforeAggregation.In(version) {
+		b.PutBool(p.AppliedAggregation)
+		b.PutUVarInt(p.RowsBeforeAggregation)
+	}
 }
@@ -1,6 +1,7 @@
 package proto
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -14,6 +15,8 @@ func TestProfile_EncodeAware(t *testing.T) {
 		AppliedLimit:              true,
 		RowsBeforeLimit:           2341,
 		CalculatedRowsBeforeLimit: false,
+		AppliedAggregation:        true,
+		RowsBeforeAggregation:     104,
 	}
 	var b Buffer
 	p.EncodeAware(&b, Version)
@@ -27,3 +30,31 @@ func TestProfile_EncodeAware(t *testing.T) {
 		requireNoShortRead(t, buf, aware(&dec))
 	})
 }
+
+func TestProfile_Revisions(t *testing.T) {
+	for _, revision := range []int{
+		int(FeatureServerQueryTimeInProgress),
+		int(FeatureRowsBeforeAggregation),
+	} {
+		t.Run(strconv.Itoa(revision), func(t *testing.T) {
+			p := Profile{
+				Rows:                      1234,
+				Blocks:                    235123,
+				Bytes:                     424,
+				AppliedLimit:              true,
+				RowsBeforeLimit:           2341,
+				CalculatedRowsBeforeLimit: true,
+			}
+			if FeatureRowsBeforeAggregation.In(revision) {
+				p.AppliedAggregation = true
+				p.RowsBeforeAggregation = 104
+			}
+			buf := skipCode(t, GoldRevision(t, p, revision), int(ServerCodeProfile))
+
+			var dec Profile
+			requireDecode(t, buf, awareOf(&dec, revision))
+			require.Equal(t, p, dec)
+			requireNoShortRead(t, buf, awareOf(&dec, revision))
+		})
+	}
+}
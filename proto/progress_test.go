@@ -1,6 +1,7 @@
 package proto
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +15,7 @@ func TestProgress_EncodeAware(t *testing.T) {
 		Rows:       100,
 		Bytes:      608120,
 		TotalRows:  1000,
+		TotalBytes: 6081200,
 		WroteRows:  441,
 		WroteBytes: 91023,
 	}
@@ -27,3 +29,30 @@ func TestProgress_EncodeAware(t *testing.T) {
 		requireNoShortRead(t, b.Buf, aware(&dec))
 	})
 }
+
+func TestProgress_Revisions(t *testing.T) {
+	for _, revision := range []int{
+		int(FeatureServerQueryTimeInProgress),
+		int(FeatureTotalBytesInProgress),
+	} {
+		t.Run(strconv.Itoa(revision), func(t *testing.T) {
+			v := Progress{
+				Rows:       100,
+				Bytes:      608120,
+				TotalRows:  1000,
+				WroteRows:  441,
+				WroteBytes: 91023,
+				ElapsedNs:  1523000,
+			}
+			if FeatureTotalBytesInProgress.In(revision) {
+				v.TotalBytes = 6081200
+			}
+			buf := GoldRevision(t, v, revision)
+
+			var dec Progress
+			requireDecode(t, buf, awareOf(&dec, revision))
+			assert.Equal(t, v, dec)
+			requireNoShortRead(t, buf, awareOf(&dec, revision))
+		})
+	}
+}
@@ -20,14 +20,19 @@ func TestMain(m *testing.M) {
 
 type staticAware struct {
 	AwareDecoder
+	version int
 }
 
 func (s staticAware) Decode(r *Reader) error {
-	return s.AwareDecoder.DecodeAware(r, Version)
+	return s.AwareDecoder.DecodeAware(r, s.version)
 }
 
 func aware(v AwareDecoder) Decoder {
-	return staticAware{AwareDecoder: v}
+	return awareOf(v, Version)
+}
+
+func awareOf(v AwareDecoder, version int) Decoder {
+	return staticAware{AwareDecoder: v, version: version}
 }
 
 type columnAware struct {
@@ -86,6 +86,40 @@ func TestQuery_EncodeAware(t *testing.T) {
 	requireNoShortRead(t, b, aware(&dec))
 }
 
+func TestQuery_EncodeAwareStage(t *testing.T) {
+	for _, tt := range []struct {
+		Name  string
+		Stage Stage
+		Value uint64
+	}{
+		{"Complete", StageComplete, 2},
+		{"WithMergeableState", StageWithMergeableState, 1},
+		{"FetchColumns", StageFetchColumns, 0},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			q := queryCreateDatabase
+			q.Stage = tt.Stage
+
+			buf := new(Buffer)
+			q.EncodeAware(buf, queryProtoVersion)
+
+			r := NewReader(bytes.NewReader(buf.Buf))
+			v, err := r.UVarInt()
+			require.NoError(t, err)
+			require.Equal(t, ClientCodeQuery, ClientCode(v))
+
+			var dec Query
+			require.NoError(t, dec.DecodeAware(r, queryProtoVersion))
+			require.Equal(t, tt.Stage, dec.Stage)
+
+			// Protocol value of stage.
+			b := new(Buffer)
+			tt.Stage.Encode(b)
+			require.Equal(t, tt.Value, uint64(b.Buf[0]))
+		})
+	}
+}
+
 func TestQuery_EncodeAwareOTEL(t *testing.T) {
 	buf := new(Buffer)
 	q := Query{
@@ -0,0 +1,38 @@
+package proto
+
+import "github.com/go-faster/errors"
+
+// ClusterProcessingProtocolVersion is version of distributed processing
+// protocol that is used in ReadTaskResponse.
+const ClusterProcessingProtocolVersion = 1
+
+// ReadTaskResponse is sent by client as ClientCodeReadTaskResponse in
+// response to ServerReadTaskRequest, which has no payload.
+//
+// Server requests next task (e.g. file name for s3Cluster) from initiator
+// until empty task is received.
+type ReadTaskResponse struct {
+	Task string
+}
+
+func (r ReadTaskResponse) Encode(b *Buffer) {
+	ClientCodeReadTaskResponse.Encode(b)
+	b.PutInt(ClusterProcessingProtocolVersion)
+	b.PutString(r.Task)
+}
+
+func (r *ReadTaskResponse) Decode(rd *Reader) error {
+	v, err := rd.Int()
+	if err != nil {
+		return errors.Wrap(err, "version")
+	}
+	if v != ClusterProcessingProtocolVersion {
+		return errors.Errorf("unsupported cluster processing protocol version %d", v)
+	}
+	task, err := rd.Str()
+	if err != nil {
+		return errors.Wrap(err, "task")
+	}
+	r.Task = task
+	return nil
+}
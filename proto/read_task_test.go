@@ -0,0 +1,30 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/ch-go/internal/gold"
+)
+
+func TestReadTaskResponse(t *testing.T) {
+	v := ReadTaskResponse{Task: "data/2023-01-01.parquet"}
+	var b Buffer
+	v.Encode(&b)
+	gold.Bytes(t, b.Buf, "read_task_response")
+
+	buf := skipCode(t, b.Buf, int(ClientCodeReadTaskResponse))
+	var dec ReadTaskResponse
+	requireDecode(t, buf, &dec)
+	require.Equal(t, v, dec)
+	requireNoShortRead(t, buf, &dec)
+
+	t.Run("BadVersion", func(t *testing.T) {
+		var e Buffer
+		e.PutInt(ClusterProcessingProtocolVersion + 1)
+		e.PutString("")
+		require.ErrorContains(t, dec.Decode(NewReader(bytes.NewReader(e.Buf))), "version")
+	})
+}
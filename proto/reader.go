@@ -24,11 +24,12 @@ type AwareDecoder interface {
 // Reader implements ClickHouse protocol decoding from buffered reader.
 // Not goroutine-safe.
 type Reader struct {
-	raw  *bufio.Reader // raw bytes, e.g. on the wire
-	data io.Reader     // data, decompressed or same as raw
-	b    *Buffer       // internal buffer
+	raw     *bufio.Reader  // raw bytes, e.g. on the wire
+	chunked *chunkedReader // raw bytes without chunked framing, from raw
+	data    io.Reader      // data, decompressed or same as chunked
+	b       *Buffer        // internal buffer
 
-	decompressed io.Reader // decompressed data stream, from raw
+	decompressed io.Reader // decompressed data stream, from chunked
 }
 
 func (r *Reader) ReadByte() (byte, error) {
@@ -45,7 +46,13 @@ func (r *Reader) EnableCompression() {
 
 // DisableCompression makes next read use raw source of data.
 func (r *Reader) DisableCompression() {
-	r.data = r.raw
+	r.data = r.chunked
+}
+
+// EnableChunked makes next reads strip chunked framing of packets from raw
+// source of data, should be called after handshake if negotiated.
+func (r *Reader) EnableChunked() {
+	r.chunked.enabled = true
 }
 
 func (r *Reader) Read(p []byte) (n int, err error) {
@@ -281,10 +288,12 @@ const defaultReaderSize = 1024 * 128 // 128kb
 // NewReader initializes new Reader from provided io.Reader.
 func NewReader(r io.Reader) *Reader {
 	c := bufio.NewReaderSize(r, defaultReaderSize)
+	chunked := &chunkedReader{r: c}
 	return &Reader{
 		raw:          c,
-		data:         c,
+		chunked:      chunked,
+		data:         chunked,
 		b:            &Buffer{},
-		decompressed: compress.NewReader(c),
+		decompressed: compress.NewReader(chunked),
 	}
 }
@@ -43,31 +43,20 @@ func (s *Results) decodeAuto(r *Reader, version int, b Block) error {
 		if err != nil {
 			return errors.Wrapf(err, "column [%d] type", i)
 		}
-		var customSerialization bool
-		if FeatureCustomSerialization.In(version) {
-			if customSerialization, err = r.Bool(); err != nil {
-				return errors.Wrapf(err, "column [%d] custom serialization", i)
-			}
-			if customSerialization {
-				// Not implemented.
-				return errors.Wrapf(err, "column [%d] has custom serialization (not supported)", i)
-			}
-		}
 		var (
 			colType = ColumnType(columnTypeRaw)
 			col     = &ColAuto{}
 		)
+		ser, err := decodeSerialization(r, version, colType)
+		if err != nil {
+			return errors.Wrapf(err, "column [%d]", i)
+		}
 		if err := col.Infer(colType); err != nil {
 			return errors.Wrap(err, "column type inference")
 		}
 		col.Data.Reset()
 		if b.Rows != 0 {
-			if s, ok := col.Data.(Stateful); ok {
-				if err := s.DecodeState(r); err != nil {
-					return errors.Wrapf(err, "%s state", columnName)
-				}
-			}
-			if err := col.Data.DecodeColumn(r, b.Rows); err != nil {
+			if err := decodeColumn(r, col.Data, colType, ser, b.Rows); err != nil {
 				return errors.Wrap(err, columnName)
 			}
 		}
@@ -98,15 +87,9 @@ func (s Results) DecodeResult(r *Reader, version int, b Block) error {
 		if err != nil {
 			return errors.Wrapf(err, "column [%d] type", i)
 		}
-		if FeatureCustomSerialization.In(version) {
-			customSerialization, err := r.Bool()
-			if err != nil {
-				return errors.Wrapf(err, "column [%d] custom serialization", i)
-			}
-			if customSerialization {
-				// Not implemented.
-				return errors.Wrapf(err, "column [%d] has custom serialization (not supported)", i)
-			}
+		ser, err := decodeSerialization(r, version, ColumnType(columnType))
+		if err != nil {
+			return errors.Wrapf(err, "column [%d]", i)
 		}
 		if noTarget {
 			// Just reading types and names.
@@ -139,15 +122,53 @@ func (s Results) DecodeResult(r *Reader, version int, b Block) error {
 		if b.Rows == 0 {
 			continue
 		}
-		if s, ok := t.Data.(StateDecoder); ok {
-			if err := s.DecodeState(r); err != nil {
-				return errors.Wrapf(err, "%s state", columnName)
-			}
-		}
-		if err := t.Data.DecodeColumn(r, b.Rows); err != nil {
+		if err := decodeColumn(r, t.Data, gotType, ser, b.Rows); err != nil {
 			return errors.Wrap(err, columnName)
 		}
 	}
 
 	return nil
 }
+
+// decodeColumn decodes rows of column of type t to col, densifying sparse
+// serialization if needed.
+func decodeColumn(r *Reader, col ColResult, t ColumnType, s serialization, rows int) error {
+	if v, ok := col.(StateDecoder); ok {
+		if err := v.DecodeState(r); err != nil {
+			return errors.Wrap(err, "state")
+		}
+	}
+	return decodeColumnData(r, col, t, s, rows)
+}
+
+// decodeColumnData decodes rows of column with serialization s, state
+// prefix should be already decoded.
+func decodeColumnData(r *Reader, col ColResult, t ColumnType, s serialization, rows int) error {
+	switch {
+	case s.sparse:
+		dense, err := decodeSparse(r, t, rows)
+		if err != nil {
+			return errors.Wrap(err, "sparse")
+		}
+		return col.DecodeColumn(dense, rows)
+	case s.custom():
+		// Tuple elements are serialized one after another, each with
+		// its own serialization kind.
+		tuple, ok := col.(ColTuple)
+		if !ok {
+			return errors.Errorf("custom serialization of %T elements is not supported", col)
+		}
+		types := tupleElems(t)
+		if len(tuple) != len(s.elems) || len(types) != len(s.elems) {
+			return errors.Errorf("got %d tuple elements instead of %d", len(s.elems), len(tuple))
+		}
+		for i, v := range tuple {
+			if err := decodeColumnData(r, v, types[i], s.elems[i], rows); err != nil {
+				return errors.Wrapf(err, "[%d]", i)
+			}
+		}
+		return nil
+	default:
+		return col.DecodeColumn(r, rows)
+	}
+}
@@ -22,10 +22,18 @@ const (
 	ServerPartUUIDs        ServerCode = 12 // list of unique parts ids.
 	ServerReadTaskRequest  ServerCode = 13 // String (UUID) describes a request for which next task is needed
 	ServerProfileEvents    ServerCode = 14 // Packet with profile events from server
+
+	ServerCodeMergeTreeAllRangesAnnouncement ServerCode = 15 // parallel replicas ranges announcement
+	ServerCodeMergeTreeReadTaskRequest       ServerCode = 16 // parallel replicas read task request
+	ServerCodeTimezoneUpdate                 ServerCode = 17 // session timezone update
+	ServerCodeSSHChallenge                   ServerCode = 18 // challenge for SSH signature
 )
 
 // Encode to buffer.
-func (c ServerCode) Encode(b *Buffer) { b.PutByte(byte(c)) }
+func (c ServerCode) Encode(b *Buffer) {
+	b.StartPacket()
+	b.PutByte(byte(c))
+}
 
 // Compressible reports whether message can be compressed.
 func (c ServerCode) Compressible() bool {
@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _ServerCodeName = "HelloDataExceptionProgressPongEndOfStreamProfileTotalsExtremesTablesStatusLogTableColumnsServerPartUUIDsServerReadTaskRequestServerProfileEvents"
+const _ServerCodeName = "HelloDataExceptionProgressPongEndOfStreamProfileTotalsExtremesTablesStatusLogTableColumnsServerPartUUIDsServerReadTaskRequestServerProfileEventsMergeTreeAllRangesAnnouncementMergeTreeReadTaskRequestTimezoneUpdateSSHChallenge"
 
-var _ServerCodeIndex = [...]uint8{0, 5, 9, 18, 26, 30, 41, 48, 54, 62, 74, 77, 89, 104, 125, 144}
+var _ServerCodeIndex = [...]uint8{0, 5, 9, 18, 26, 30, 41, 48, 54, 62, 74, 77, 89, 104, 125, 144, 174, 198, 212, 224}
 
-const _ServerCodeLowerName = "hellodataexceptionprogresspongendofstreamprofiletotalsextremestablesstatuslogtablecolumnsserverpartuuidsserverreadtaskrequestserverprofileevents"
+const _ServerCodeLowerName = "hellodataexceptionprogresspongendofstreamprofiletotalsextremestablesstatuslogtablecolumnsserverpartuuidsserverreadtaskrequestserverprofileeventsmergetreeallrangesannouncementmergetreereadtaskrequesttimezoneupdatesshchallenge"
 
 func (i ServerCode) String() string {
 	if i >= ServerCode(len(_ServerCodeIndex)-1) {
@@ -39,9 +39,13 @@ func _ServerCodeNoOp() {
 	_ = x[ServerPartUUIDs-(12)]
 	_ = x[ServerReadTaskRequest-(13)]
 	_ = x[ServerProfileEvents-(14)]
+	_ = x[ServerCodeMergeTreeAllRangesAnnouncement-(15)]
+	_ = x[ServerCodeMergeTreeReadTaskRequest-(16)]
+	_ = x[ServerCodeTimezoneUpdate-(17)]
+	_ = x[ServerCodeSSHChallenge-(18)]
 }
 
-var _ServerCodeValues = []ServerCode{ServerCodeHello, ServerCodeData, ServerCodeException, ServerCodeProgress, ServerCodePong, ServerCodeEndOfStream, ServerCodeProfile, ServerCodeTotals, ServerCodeExtremes, ServerCodeTablesStatus, ServerCodeLog, ServerCodeTableColumns, ServerPartUUIDs, ServerReadTaskRequest, ServerProfileEvents}
+var _ServerCodeValues = []ServerCode{ServerCodeHello, ServerCodeData, ServerCodeException, ServerCodeProgress, ServerCodePong, ServerCodeEndOfStream, ServerCodeProfile, ServerCodeTotals, ServerCodeExtremes, ServerCodeTablesStatus, ServerCodeLog, ServerCodeTableColumns, ServerPartUUIDs, ServerReadTaskRequest, ServerProfileEvents, ServerCodeMergeTreeAllRangesAnnouncement, ServerCodeMergeTreeReadTaskRequest, ServerCodeTimezoneUpdate, ServerCodeSSHChallenge}
 
 var _ServerCodeNameToValueMap = map[string]ServerCode{
 	_ServerCodeName[0:5]:          ServerCodeHello,
@@ -74,6 +78,14 @@ var _ServerCodeNameToValueMap = map[string]ServerCode{
 	_ServerCodeLowerName[104:125]: ServerReadTaskRequest,
 	_ServerCodeName[125:144]:      ServerProfileEvents,
 	_ServerCodeLowerName[125:144]: ServerProfileEvents,
+	_ServerCodeName[144:174]:      ServerCodeMergeTreeAllRangesAnnouncement,
+	_ServerCodeLowerName[144:174]: ServerCodeMergeTreeAllRangesAnnouncement,
+	_ServerCodeName[174:198]:      ServerCodeMergeTreeReadTaskRequest,
+	_ServerCodeLowerName[174:198]: ServerCodeMergeTreeReadTaskRequest,
+	_ServerCodeName[198:212]:      ServerCodeTimezoneUpdate,
+	_ServerCodeLowerName[198:212]: ServerCodeTimezoneUpdate,
+	_ServerCodeName[212:224]:      ServerCodeSSHChallenge,
+	_ServerCodeLowerName[212:224]: ServerCodeSSHChallenge,
 }
 
 var _ServerCodeNames = []string{
@@ -92,6 +104,10 @@ var _ServerCodeNames = []string{
 	_ServerCodeName[89:104],
 	_ServerCodeName[104:125],
 	_ServerCodeName[125:144],
+	_ServerCodeName[144:174],
+	_ServerCodeName[174:198],
+	_ServerCodeName[198:212],
+	_ServerCodeName[212:224],
 }
 
 // ServerCodeString retrieves an enum value from the enum constants string name.
@@ -16,6 +16,24 @@ type ServerHello struct {
 	Timezone    string
 	DisplayName string
 	Patch       int
+
+	// ParallelReplicasProtocol is version of parallel replicas protocol.
+	ParallelReplicasProtocol uint64
+	// ChunkedSend and ChunkedRecv are server settings of chunked packets
+	// framing, like "notchunked_optional".
+	ChunkedSend string
+	ChunkedRecv string
+	// PasswordComplexityRules are rules that are checked by server on
+	// setting password.
+	PasswordComplexityRules []PasswordComplexityRule
+	// Nonce is used by server to sign inter-server queries.
+	Nonce uint64
+}
+
+// PasswordComplexityRule is rule of password complexity reported by server.
+type PasswordComplexityRule struct {
+	Pattern string
+	Message string
 }
 
 // Features implemented by server.
@@ -77,6 +95,13 @@ func (s *ServerHello) DecodeAware(r *Reader, v int) error {
 
 	s.Major, s.Minor, s.Revision = major, minor, revision
 
+	if FeatureVersionedParallelReplicas.In(v) {
+		v, err := r.UVarInt()
+		if err != nil {
+			return errors.Wrap(err, "parallel replicas protocol")
+		}
+		s.ParallelReplicasProtocol = v
+	}
 	if FeatureTimezone.In(v) {
 		v, err := r.Str()
 		if err != nil {
@@ -98,6 +123,41 @@ func (s *ServerHello) DecodeAware(r *Reader, v int) error {
 		}
 		s.Patch = path
 	}
+	if FeatureChunkedPackets.In(v) {
+		send, err := r.Str()
+		if err != nil {
+			return errors.Wrap(err, "chunked send")
+		}
+		recv, err := r.Str()
+		if err != nil {
+			return errors.Wrap(err, "chunked recv")
+		}
+		s.ChunkedSend, s.ChunkedRecv = send, recv
+	}
+	if FeaturePasswordComplexityRules.In(v) {
+		n, err := r.Int()
+		if err != nil {
+			return errors.Wrap(err, "password complexity rules")
+		}
+		s.PasswordComplexityRules = s.PasswordComplexityRules[:0]
+		for i := 0; i < n; i++ {
+			var rule PasswordComplexityRule
+			if rule.Pattern, err = r.Str(); err != nil {
+				return errors.Wrapf(err, "password complexity rule [%d]: pattern", i)
+			}
+			if rule.Message, err = r.Str(); err != nil {
+				return errors.Wrapf(err, "password complexity rule [%d]: message", i)
+			}
+			s.PasswordComplexityRules = append(s.PasswordComplexityRules, rule)
+		}
+	}
+	if FeatureInterServerSecretV2.In(v) {
+		nonce, err := r.UInt64()
+		if err != nil {
+			return errors.Wrap(err, "nonce")
+		}
+		s.Nonce = nonce
+	}
 
 	return nil
 }
@@ -108,6 +168,9 @@ func (s *ServerHello) EncodeAware(b *Buffer, v int) {
 	b.PutInt(s.Major)
 	b.PutInt(s.Minor)
 	b.PutInt(s.Revision)
+	if FeatureVersionedParallelReplicas.In(v) {
+		b.PutUVarInt(s.ParallelReplicasProtocol)
+	}
 	if FeatureTimezone.In(v) {
 		b.PutString(s.Timezone)
 	}
@@ -117,4 +180,18 @@ func (s *ServerHello) EncodeAware(b *Buffer, v int) {
 	if FeatureVersionPatch.In(v) {
 		b.PutInt(s.Patch)
 	}
+	if FeatureChunkedPackets.In(v) {
+		b.PutString(s.ChunkedSend)
+		b.PutString(s.ChunkedRecv)
+	}
+	if FeaturePasswordComplexityRules.In(v) {
+		b.PutInt(len(s.PasswordComplexityRules))
+		for _, rule := range s.PasswordComplexityRules {
+			b.PutString(rule.Pattern)
+			b.PutString(rule.Message)
+		}
+	}
+	if FeatureInterServerSecretV2.In(v) {
+		b.PutUInt64(s.Nonce)
+	}
 }
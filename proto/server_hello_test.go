@@ -3,6 +3,7 @@ package proto
 import (
 	"bytes"
 	"encoding/hex"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,7 +16,7 @@ func TestServerHello_DecodeAware(t *testing.T) {
 
 	r := NewReader(bytes.NewReader(data))
 	var v ServerHello
-	require.NoError(t, v.DecodeAware(r, Version))
+	require.NoError(t, v.DecodeAware(r, 54450))
 	require.Equal(t, ServerHello{
 		Name:        "ClickHouse server",
 		Major:       21,
@@ -46,6 +47,15 @@ func TestServerHello_EncodeAware(t *testing.T) {
 		Revision:    54450,
 		Timezone:    "Europe/Moscow",
 		DisplayName: "alpha",
+
+		ParallelReplicasProtocol: ParallelReplicasProtocolVersion,
+		ChunkedSend:              "notchunked_optional",
+		ChunkedRecv:              "notchunked_optional",
+		PasswordComplexityRules: []PasswordComplexityRule{
+			{Pattern: ".{12}", Message: "be at least 12 characters long"},
+			{Pattern: "\\p{N}", Message: "contain at least 1 numeric character"},
+		},
+		Nonce: 0xd1c3ac1d2e6d3f9a,
 	}
 	Gold(t, &v)
 	v.EncodeAware(&b, Version)
@@ -58,6 +68,49 @@ func TestServerHello_EncodeAware(t *testing.T) {
 	})
 }
 
+func TestServerHello_Revisions(t *testing.T) {
+	for _, revision := range []int{
+		int(FeatureServerQueryTimeInProgress),
+		int(FeaturePasswordComplexityRules),
+		int(FeatureInterServerSecretV2),
+		int(FeatureChunkedPackets),
+		int(FeatureVersionedParallelReplicas),
+	} {
+		t.Run(strconv.Itoa(revision), func(t *testing.T) {
+			v := ServerHello{
+				Name:        "ClickHouse",
+				Major:       24,
+				Minor:       8,
+				Patch:       4,
+				Revision:    revision,
+				Timezone:    "UTC",
+				DisplayName: "clickhouse-01",
+			}
+			if FeatureVersionedParallelReplicas.In(revision) {
+				v.ParallelReplicasProtocol = ParallelReplicasProtocolVersion
+			}
+			if FeatureChunkedPackets.In(revision) {
+				v.ChunkedSend = NotChunked
+				v.ChunkedRecv = NotChunked
+			}
+			if FeaturePasswordComplexityRules.In(revision) {
+				v.PasswordComplexityRules = []PasswordComplexityRule{
+					{Pattern: ".{12}", Message: "be at least 12 characters long"},
+				}
+			}
+			if FeatureInterServerSecretV2.In(revision) {
+				v.Nonce = 0x5ac3d1
+			}
+			buf := skipCode(t, GoldRevision(t, &v, revision), int(ServerCodeHello))
+
+			var dec ServerHello
+			requireDecode(t, buf, awareOf(&dec, revision))
+			require.Equal(t, v, dec)
+			requireNoShortRead(t, buf, awareOf(&dec, revision))
+		})
+	}
+}
+
 func BenchmarkServerHello_Decode(b *testing.B) {
 	var raw Buffer
 	raw.PutString("ClickHouse server")
@@ -82,7 +135,7 @@ func BenchmarkServerHello_Decode(b *testing.B) {
 			buf.Reset(raw.Buf)
 			r.raw.Reset(buf)
 
-			if err := serverHello.DecodeAware(r, Version); err != nil {
+			if err := serverHello.DecodeAware(r, 54450); err != nil {
 				b.Fatal(err)
 			}
 		}
@@ -13,6 +13,19 @@ type Log struct {
 	Priority int8      `json:"priority"`
 }
 
+// Possible values of Log.Priority, same as in Poco logger.
+const (
+	LogPriorityFatal int8 = iota + 1
+	LogPriorityCritical
+	LogPriorityError
+	LogPriorityWarning
+	LogPriorityNotice
+	LogPriorityInformation
+	LogPriorityDebug
+	LogPriorityTrace
+	LogPriorityTest
+)
+
 // Logs from ServerCodeLog packet.
 type Logs struct {
 	Time      ColDateTime
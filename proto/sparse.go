@@ -0,0 +1,220 @@
+package proto
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// Kinds of column serialization.
+const (
+	serializationDefault byte = 0
+	serializationSparse  byte = 1
+)
+
+// sparseEndOfGranule is set on last group size of sparse offsets.
+const sparseEndOfGranule = 1 << 62
+
+// serialization is custom serialization info of column.
+type serialization struct {
+	sparse bool
+	elems  []serialization // of tuple elements
+}
+
+// custom reports whether column or any of its elements is not in default
+// serialization.
+func (s serialization) custom() bool {
+	if s.sparse {
+		return true
+	}
+	for _, e := range s.elems {
+		if e.custom() {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSerialization decodes custom serialization info of column with
+// type t that goes after column name and type.
+func decodeSerialization(r *Reader, version int, t ColumnType) (serialization, error) {
+	if !FeatureCustomSerialization.In(version) {
+		return serialization{}, nil
+	}
+	custom, err := r.Bool()
+	if err != nil {
+		return serialization{}, errors.Wrap(err, "custom serialization")
+	}
+	if !custom {
+		return serialization{}, nil
+	}
+	return decodeSerializationKinds(r, version, t)
+}
+
+// decodeSerializationKinds decodes serialization kind of column with type t,
+// followed by kinds of each element if t is Tuple.
+func decodeSerializationKinds(r *Reader, version int, t ColumnType) (serialization, error) {
+	kind, err := r.Byte()
+	if err != nil {
+		return serialization{}, errors.Wrap(err, "serialization kind")
+	}
+	var s serialization
+	switch kind {
+	case serializationDefault:
+	case serializationSparse:
+		if !FeatureSparseSerialization.In(version) {
+			return s, errors.New("unexpected sparse serialization")
+		}
+		s.sparse = true
+	default:
+		return s, errors.Errorf("serialization kind %d is not supported", kind)
+	}
+	if t.Base() != ColumnTypeTuple {
+		return s, nil
+	}
+	if s.sparse {
+		return s, errors.New("sparse tuple is not supported")
+	}
+	for i, e := range tupleElems(t) {
+		v, err := decodeSerializationKinds(r, version, e)
+		if err != nil {
+			return s, errors.Wrapf(err, "tuple element [%d]", i)
+		}
+		s.elems = append(s.elems, v)
+	}
+	return s, nil
+}
+
+// tupleElems returns types of Tuple elements, trimming names of named
+// tuple elements, like Tuple(a String, b UInt8) -> [String, UInt8].
+func tupleElems(t ColumnType) []ColumnType {
+	v := string(t.Elem())
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	var (
+		out   []ColumnType
+		depth int
+		start int
+	)
+	add := func(e string) {
+		e = strings.TrimSpace(e)
+		if idx := strings.IndexAny(e, " ("); idx > 0 && e[idx] == ' ' {
+			e = strings.TrimSpace(e[idx:])
+		}
+		out = append(out, ColumnType(e))
+	}
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\'':
+			// Skipping enum names and timezones.
+			for i++; i < len(v) && v[i] != '\''; i++ {
+				if v[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				add(v[start:i])
+				start = i + 1
+			}
+		}
+	}
+	add(v[start:])
+	return out
+}
+
+// decodeSparse decodes rows of sparse column with type t, returning Reader
+// of dense (default) serialization of same column.
+//
+// Only fixed size types and strings are supported, the default value is
+// zero or empty string.
+func decodeSparse(r *Reader, t ColumnType, rows int) (*Reader, error) {
+	size := fixedSize(t)
+	if t.Base() == ColumnTypeFixedString {
+		n, err := strconv.Atoi(string(t.Elem()))
+		if err != nil {
+			return nil, errors.Wrap(err, "fixed string size")
+		}
+		size = n
+	}
+	if size == 0 && t != ColumnTypeString {
+		return nil, errors.Errorf("sparse %s is not supported", t)
+	}
+
+	// Offsets of non-default values.
+	var (
+		offsets []int
+		total   int
+	)
+	for {
+		v, err := r.UVarInt()
+		if err != nil {
+			return nil, errors.Wrap(err, "sparse offsets")
+		}
+		end := v&sparseEndOfGranule != 0
+		v &^= sparseEndOfGranule
+		if v > uint64(rows-total) {
+			return nil, errors.Errorf("sparse group of %d rows is out of range", v)
+		}
+		total += int(v)
+		if end {
+			break
+		}
+		if total >= rows {
+			return nil, errors.New("sparse offset is out of range")
+		}
+		offsets = append(offsets, total)
+		total++
+	}
+	if total != rows {
+		return nil, errors.Errorf("got %d sparse rows instead of %d", total, rows)
+	}
+
+	var (
+		b    Buffer
+		last = 0
+	)
+	for _, offset := range offsets {
+		for ; last < offset; last++ {
+			putDefault(&b, size)
+		}
+		if size > 0 {
+			v, err := r.ReadRaw(size)
+			if err != nil {
+				return nil, errors.Wrap(err, "sparse value")
+			}
+			b.PutRaw(v)
+		} else {
+			v, err := r.StrRaw()
+			if err != nil {
+				return nil, errors.Wrap(err, "sparse value")
+			}
+			b.PutLen(len(v))
+			b.PutRaw(v)
+		}
+		last++
+	}
+	for ; last < rows; last++ {
+		putDefault(&b, size)
+	}
+
+	return NewReader(bytes.NewReader(b.Buf)), nil
+}
+
+// putDefault puts zero value of size bytes or empty string if size is zero.
+func putDefault(b *Buffer, size int) {
+	if size == 0 {
+		b.PutLen(0)
+		return
+	}
+	for i := 0; i < size; i++ {
+		b.Buf = append(b.Buf, 0)
+	}
+}
@@ -0,0 +1,140 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResults_DecodeSparse(t *testing.T) {
+	var b Buffer
+	b.PutString("v")
+	b.PutString("UInt64")
+	b.PutBool(true) // custom serialization
+	b.PutByte(serializationSparse)
+	// Non-default values at 1 and 4 of 6 rows.
+	b.PutUVarInt(1)
+	b.PutUVarInt(2)
+	b.PutUVarInt(1 | sparseEndOfGranule)
+	b.PutUInt64(10)
+	b.PutUInt64(40)
+
+	b.PutString("s")
+	b.PutString("String")
+	b.PutBool(true)
+	b.PutByte(serializationSparse)
+	// Non-default value at 5 of 6 rows.
+	b.PutUVarInt(5)
+	b.PutUVarInt(0 | sparseEndOfGranule)
+	b.PutString("foo")
+
+	b.PutString("f")
+	b.PutString("Int8")
+	b.PutBool(true)
+	b.PutByte(serializationDefault)
+	b.PutRaw([]byte{1, 2, 3, 4, 5, 6})
+
+	block := Block{Columns: 3, Rows: 6}
+	t.Run("Results", func(t *testing.T) {
+		var (
+			v   ColUInt64
+			s   ColStr
+			f   ColInt8
+			res = Results{
+				{Name: "v", Data: &v},
+				{Name: "s", Data: &s},
+				{Name: "f", Data: &f},
+			}
+		)
+		r := NewReader(bytes.NewReader(b.Buf))
+		require.NoError(t, res.DecodeResult(r, Version, block))
+		require.Equal(t, ColUInt64{0, 10, 0, 0, 40, 0}, v)
+		var str []string
+		for i := 0; i < s.Rows(); i++ {
+			str = append(str, s.Row(i))
+		}
+		require.Equal(t, []string{"", "", "", "", "", "foo"}, str)
+		require.Equal(t, ColInt8{1, 2, 3, 4, 5, 6}, f)
+	})
+	t.Run("Auto", func(t *testing.T) {
+		var res Results
+		r := NewReader(bytes.NewReader(b.Buf))
+		require.NoError(t, res.Auto().DecodeResult(r, Version, block))
+		require.Len(t, res, 3)
+		require.Equal(t, uint64(40), res[0].Data.(*ColUInt64).Row(4))
+	})
+	t.Run("NoShortRead", func(t *testing.T) {
+		for i := 0; i < len(b.Buf); i++ {
+			var res Results
+			r := NewReader(bytes.NewReader(b.Buf[:i]))
+			require.Error(t, res.Auto().DecodeResult(r, Version, block))
+		}
+	})
+	t.Run("OutOfRange", func(t *testing.T) {
+		var c Buffer
+		c.PutString("v")
+		c.PutString("UInt64")
+		c.PutBool(true)
+		c.PutByte(serializationSparse)
+		c.PutUVarInt(7 | sparseEndOfGranule)
+
+		var res Results
+		r := NewReader(bytes.NewReader(c.Buf))
+		require.Error(t, res.Auto().DecodeResult(r, Version, Block{Columns: 1, Rows: 6}))
+	})
+	t.Run("Unsupported", func(t *testing.T) {
+		var c Buffer
+		c.PutString("v")
+		c.PutString("Array(UInt8)")
+		c.PutBool(true)
+		c.PutByte(serializationSparse)
+		c.PutUVarInt(0 | sparseEndOfGranule)
+
+		var res Results
+		r := NewReader(bytes.NewReader(c.Buf))
+		require.Error(t, res.Auto().DecodeResult(r, Version, Block{Columns: 1, Rows: 1}))
+	})
+	t.Run("Tuple", func(t *testing.T) {
+		var c Buffer
+		c.PutString("t")
+		c.PutString("Tuple(a UInt8, b String, Tuple(Int32, String))")
+		c.PutBool(true)
+		c.PutByte(serializationDefault) // tuple
+		c.PutByte(serializationDefault) // a
+		c.PutByte(serializationSparse)  // b
+		c.PutByte(serializationDefault) // c
+		c.PutByte(serializationSparse)  // c.1
+		c.PutByte(serializationDefault) // c.2
+		// a
+		c.PutRaw([]byte{1, 2, 3})
+		// b, non-default value at 1 of 3 rows.
+		c.PutUVarInt(1)
+		c.PutUVarInt(1 | sparseEndOfGranule)
+		c.PutString("foo")
+		// c.1, non-default value at 2 of 3 rows.
+		c.PutUVarInt(2)
+		c.PutUVarInt(0 | sparseEndOfGranule)
+		c.PutInt32(-5)
+		// c.2
+		c.PutString("x")
+		c.PutString("y")
+		c.PutString("z")
+
+		var (
+			a   ColUInt8
+			b   ColStr
+			c1  ColInt32
+			c2  ColStr
+			res = Results{
+				{Name: "t", Data: ColTuple{Named[uint8](&a, "a"), Named[string](&b, "b"), ColTuple{&c1, &c2}}},
+			}
+		)
+		r := NewReader(bytes.NewReader(c.Buf))
+		require.NoError(t, res.DecodeResult(r, Version, Block{Columns: 1, Rows: 3}))
+		require.Equal(t, ColUInt8{1, 2, 3}, a)
+		require.Equal(t, []string{"", "foo", ""}, []string{b.Row(0), b.Row(1), b.Row(2)})
+		require.Equal(t, ColInt32{0, 0, -5}, c1)
+		require.Equal(t, []string{"x", "y", "z"}, []string{c2.Row(0), c2.Row(1), c2.Row(2)})
+	})
+}
@@ -0,0 +1,66 @@
+package proto
+
+import (
+	"strconv"
+
+	"github.com/go-faster/errors"
+)
+
+// SSHKeyAuthMarker is prefix of ClientHello.User that requests
+// authentication with SSH key instead of password.
+//
+// Client sends ClientCodeSSHChallengeRequest after ClientHello, and server
+// answers with SSHChallenge that is signed by client and sent back
+// as SSHChallengeResponse.
+const SSHKeyAuthMarker = " SSH KEY AUTHENTICATION "
+
+// SSHChallenge is sent by server as ServerCodeSSHChallenge.
+type SSHChallenge struct {
+	Challenge string
+}
+
+func (c SSHChallenge) Encode(b *Buffer) {
+	ServerCodeSSHChallenge.Encode(b)
+	b.PutString(c.Challenge)
+}
+
+func (c *SSHChallenge) Decode(r *Reader) error {
+	v, err := r.Str()
+	if err != nil {
+		return errors.Wrap(err, "challenge")
+	}
+	c.Challenge = v
+	return nil
+}
+
+// Message returns message to sign for challenge, where protocol version,
+// database and user (without SSHKeyAuthMarker) are from ClientHello.
+func (c SSHChallenge) Message(protocolVersion int, database, user string) []byte {
+	var b []byte
+	b = strconv.AppendInt(b, int64(protocolVersion), 10)
+	b = append(b, database...)
+	b = append(b, user...)
+	b = append(b, c.Challenge...)
+	return b
+}
+
+// SSHChallengeResponse is sent by client as ClientCodeSSHChallengeResponse.
+type SSHChallengeResponse struct {
+	// Signature is SSH wire format of signature of SSHChallenge.Message.
+	Signature []byte
+}
+
+func (c SSHChallengeResponse) Encode(b *Buffer) {
+	ClientCodeSSHChallengeResponse.Encode(b)
+	b.PutLen(len(c.Signature))
+	b.PutRaw(c.Signature)
+}
+
+func (c *SSHChallengeResponse) Decode(r *Reader) error {
+	v, err := r.StrBytes()
+	if err != nil {
+		return errors.Wrap(err, "signature")
+	}
+	c.Signature = v
+	return nil
+}
@@ -0,0 +1,37 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/ch-go/internal/gold"
+)
+
+func TestSSHChallenge(t *testing.T) {
+	v := SSHChallenge{Challenge: "8c1bd0f5e1a9"}
+	var b Buffer
+	v.Encode(&b)
+	gold.Bytes(t, b.Buf, "ssh_challenge")
+
+	buf := skipCode(t, b.Buf, int(ServerCodeSSHChallenge))
+	var dec SSHChallenge
+	requireDecode(t, buf, &dec)
+	require.Equal(t, v, dec)
+	requireNoShortRead(t, buf, &dec)
+
+	require.Equal(t, "54471defaultuser8c1bd0f5e1a9", string(v.Message(54471, "default", "user")))
+}
+
+func TestSSHChallengeResponse(t *testing.T) {
+	v := SSHChallengeResponse{Signature: []byte{0, 0, 0, 11, 's', 's', 'h', '-', 'e', 'd', '2', '5', '5', '1', '9'}}
+	var b Buffer
+	v.Encode(&b)
+	gold.Bytes(t, b.Buf, "ssh_challenge_response")
+
+	buf := skipCode(t, b.Buf, int(ClientCodeSSHChallengeResponse))
+	var dec SSHChallengeResponse
+	requireDecode(t, buf, &dec)
+	require.Equal(t, v, dec)
+	requireNoShortRead(t, buf, &dec)
+}
@@ -0,0 +1,277 @@
+package proto
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// Row accessors that are used to validate values.
+type (
+	stringRows  interface{ Row(i int) string }
+	bytesRows   interface{ Row(i int) []byte }
+	timeRows    interface{ Row(i int) time.Time }
+	float32Rows interface{ Row(i int) float32 }
+	float64Rows interface{ Row(i int) float64 }
+	enum8Rows   interface{ Row(i int) Enum8 }
+	enum16Rows  interface{ Row(i int) Enum16 }
+)
+
+// nullableColumn is Nullable(T) column.
+type nullableColumn interface {
+	IsElemNull(i int) bool
+	nullableValues() ColInput
+}
+
+// arrayColumn is Array(T) column.
+type arrayColumn interface {
+	arrayData() (ColUInt64, ColInput)
+}
+
+// Ranges of date and time types, as documented by ClickHouse.
+var (
+	minDate       = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDate       = time.Date(2149, 6, 6, 0, 0, 0, 0, time.UTC)
+	minDate32     = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDate32     = time.Date(2299, 12, 31, 0, 0, 0, 0, time.UTC)
+	minDateTime   = time.Unix(0, 0).UTC()
+	maxDateTime   = time.Unix(math.MaxUint32, 0).UTC()
+	minDateTime64 = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDateTime64 = time.Date(2299, 12, 31, 23, 59, 59, 999999999, time.UTC)
+)
+
+// ValidateColumn checks rows of column c against column type t of table,
+// calling f for each invalid row, e.g. for too long FixedString value,
+// unknown Enum value or DateTime out of range.
+//
+// Type of c can differ from t if server is able to convert values, e.g.
+// String to FixedString(N). Returns error if whole column is invalid,
+// e.g. if types conflict.
+//
+// Out of range time can't be detected in ColDate and ColDateTime, because
+// it wraps on Append (e.g. 1960 becomes 2096 in DateTime), so only values
+// that don't fit t (e.g. Date value after 2106 for DateTime) are reported
+// for them. Use ValidateTime before Append to check range of time.Time
+// values of such columns.
+func ValidateColumn(c ColInput, t ColumnType, f func(row int, err error)) error {
+	switch v := c.(type) {
+	case ColAuto:
+		return ValidateColumn(v.Data, t, f)
+	case *ColAuto:
+		return ValidateColumn(v.Data, t, f)
+	}
+	ct := c.Type()
+	if ct.Base() == ColumnTypeLowCardinality {
+		ct = ct.Elem()
+	}
+	if t.Base() == ColumnTypeLowCardinality {
+		t = t.Elem()
+	}
+	if v, ok := c.(nullableColumn); ok {
+		if t.Base() == ColumnTypeNullable {
+			return ValidateColumn(v.nullableValues(), t.Elem(), func(row int, err error) {
+				if !v.IsElemNull(row) {
+					f(row, err)
+				}
+			})
+		}
+		for i := 0; i < c.Rows(); i++ {
+			if v.IsElemNull(i) {
+				f(i, errors.Errorf("NULL value for %s", t))
+			}
+		}
+		return ValidateColumn(v.nullableValues(), t, func(row int, err error) {
+			if !v.IsElemNull(row) {
+				f(row, err)
+			}
+		})
+	}
+	if t.Base() == ColumnTypeNullable {
+		// Server wraps values to Nullable.
+		t = t.Elem()
+	}
+	if v, ok := c.(arrayColumn); ok && t.Base() == ColumnTypeArray {
+		offsets, data := v.arrayData()
+		return ValidateColumn(data, t.Elem(), func(i int, err error) {
+			// Searching row of i-th element.
+			row := sort.Search(len(offsets), func(j int) bool {
+				return offsets[j] > uint64(i)
+			})
+			f(row, err)
+		})
+	}
+	check := valueCheck(c, t)
+	if check == nil {
+		if ct.Conflicts(t) && !(isNumeric(ct) && isNumeric(t)) && !(isTime(ct) && isTime(t)) {
+			return errors.Errorf("%s conflicts with %s", ct, t)
+		}
+		return nil
+	}
+	for i := 0; i < c.Rows(); i++ {
+		if err := check(i); err != nil {
+			f(i, err)
+		}
+	}
+	return nil
+}
+
+// valueCheck returns function that checks i-th row of c to be valid
+// value of t or nil if rows of c can't be checked.
+func valueCheck(c ColInput, t ColumnType) func(i int) error {
+	switch t.Base() {
+	case ColumnTypeFixedString:
+		n, err := strconv.Atoi(string(t.Elem()))
+		if err != nil {
+			return nil
+		}
+		checkLen := func(l int) error {
+			if l > n {
+				return errors.Errorf("value of %d bytes is too long for %s", l, t)
+			}
+			return nil
+		}
+		switch v := c.(type) {
+		case stringRows:
+			return func(i int) error { return checkLen(len(v.Row(i))) }
+		case bytesRows:
+			return func(i int) error { return checkLen(len(v.Row(i))) }
+		}
+	case ColumnTypeEnum8, ColumnTypeEnum16:
+		var e ColEnum
+		if err := e.parse(t); err != nil {
+			return nil
+		}
+		checkRaw := func(raw int) error {
+			if _, ok := e.rawToStr[raw]; !ok {
+				return errors.Errorf("unknown value %d of %s", raw, t)
+			}
+			return nil
+		}
+		switch v := c.(type) {
+		case stringRows:
+			return func(i int) error {
+				if s := v.Row(i); !e.hasValue(s) {
+					return errors.Errorf("unknown value %q of %s", s, t)
+				}
+				return nil
+			}
+		case enum8Rows:
+			return func(i int) error { return checkRaw(int(v.Row(i))) }
+		case enum16Rows:
+			return func(i int) error { return checkRaw(int(v.Row(i))) }
+		}
+	case ColumnTypeDate, ColumnTypeDate32, ColumnTypeDateTime, ColumnTypeDateTime64:
+		switch v := c.(type) {
+		case ColDateTime64:
+			if !v.PrecisionSet {
+				return nil
+			}
+		case *ColDateTime64:
+			if !v.PrecisionSet {
+				return nil
+			}
+		}
+		v, ok := c.(timeRows)
+		if !ok {
+			return nil
+		}
+		start, end := timeRange(t)
+		// Values of Date and DateTime columns wrap on Append, so they are
+		// always in range of column type and only conversion to narrower
+		// type can be checked.
+		var (
+			colStart, colEnd time.Time
+			wraps            = true
+		)
+		switch c.(type) {
+		case ColDate, *ColDate:
+			colStart, colEnd = timeRange(ColumnTypeDate)
+		case ColDateTime, *ColDateTime:
+			colStart, colEnd = timeRange(ColumnTypeDateTime)
+		default:
+			wraps = false
+		}
+		if wraps && !colStart.Before(start) && !colEnd.After(end) {
+			return nil
+		}
+		return func(i int) error { return ValidateTime(t, v.Row(i)) }
+	}
+	if isNumeric(t) && t.Base() != ColumnTypeFloat32 && t.Base() != ColumnTypeFloat64 {
+		checkFloat := func(f float64) error {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return errors.Errorf("%v can't be converted to %s", f, t)
+			}
+			return nil
+		}
+		switch v := c.(type) {
+		case float32Rows:
+			return func(i int) error { return checkFloat(float64(v.Row(i))) }
+		case float64Rows:
+			return func(i int) error { return checkFloat(v.Row(i)) }
+		}
+	}
+	return nil
+}
+
+// ValidateTime checks that v is in range of date or time type t, like
+// Date or DateTime64(3), returning error if not.
+//
+// Can be used before appending v to ColDate or ColDateTime, which wrap
+// out of range values, see ValidateColumn.
+func ValidateTime(t ColumnType, v time.Time) error {
+	if t.Base() == ColumnTypeNullable || t.Base() == ColumnTypeLowCardinality {
+		t = t.Elem()
+	}
+	if !isTime(t) {
+		return errors.Errorf("%s is not date or time type", t)
+	}
+	if start, end := timeRange(t); v.Before(start) || v.After(end) {
+		return errors.Errorf("%s is out of %s range", v.Format(time.RFC3339Nano), t)
+	}
+	return nil
+}
+
+// timeRange returns range of values of date or time type t.
+func timeRange(t ColumnType) (start, end time.Time) {
+	switch t.Base() {
+	case ColumnTypeDate:
+		return minDate, maxDate
+	case ColumnTypeDate32:
+		return minDate32, maxDate32
+	case ColumnTypeDateTime:
+		return minDateTime, maxDateTime
+	default:
+		return minDateTime64, maxDateTime64
+	}
+}
+
+// isTime reports whether t is date or time type, so values can be
+// converted by server to other date or time type.
+func isTime(t ColumnType) bool {
+	switch t.Base() {
+	case ColumnTypeDate, ColumnTypeDate32, ColumnTypeDateTime, ColumnTypeDateTime64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNumeric reports whether t is numeric type, so values can be
+// converted by server to other numeric type.
+func isNumeric(t ColumnType) bool {
+	switch t.Base() {
+	case ColumnTypeInt8, ColumnTypeInt16, ColumnTypeInt32, ColumnTypeInt64,
+		ColumnTypeInt128, ColumnTypeInt256,
+		ColumnTypeUInt8, ColumnTypeUInt16, ColumnTypeUInt32, ColumnTypeUInt64,
+		ColumnTypeUInt128, ColumnTypeUInt256,
+		ColumnTypeFloat32, ColumnTypeFloat64,
+		ColumnTypeDecimal, ColumnTypeDecimal32, ColumnTypeDecimal64,
+		ColumnTypeDecimal128, ColumnTypeDecimal256, ColumnTypeBool:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,256 @@
+package proto
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateColumn(t *testing.T) {
+	dt64 := func(values ...time.Time) ColInput {
+		c := new(ColDateTime64).WithPrecision(PrecisionMilli)
+		c.AppendArr(values)
+		return c
+	}
+	date := func(values ...time.Time) ColInput {
+		c := new(ColDate)
+		c.AppendArr(values)
+		return c
+	}
+	date32 := func(values ...time.Time) ColInput {
+		c := new(ColDate32)
+		c.AppendArr(values)
+		return c
+	}
+	dt := func(values ...time.Time) ColInput {
+		c := new(ColDateTime)
+		c.AppendArr(values)
+		return c
+	}
+	nullable := func(values ...Nullable[string]) ColInput {
+		c := new(ColStr).Nullable()
+		c.AppendArr(values)
+		return c
+	}
+	for _, tt := range []struct {
+		Name  string
+		Input ColInput
+		Type  ColumnType
+		Rows  []int
+		Error bool
+	}{
+		{
+			Name:  "Same",
+			Input: ColInt64{1, 2},
+			Type:  ColumnTypeInt64,
+		},
+		{
+			Name:  "Wrapped",
+			Input: &ColStr{Buf: []byte("foo"), Pos: []Position{{0, 3}}},
+			Type:  "LowCardinality(Nullable(String))",
+		},
+		{
+			Name:  "Numeric",
+			Input: ColInt32{1, 2},
+			Type:  "Decimal(10, 2)",
+		},
+		{
+			Name:  "Conflict",
+			Input: ColInt64{1, 2},
+			Type:  ColumnTypeString,
+			Error: true,
+		},
+		{
+			Name:  "FixedString",
+			Input: &ColStr{Buf: []byte("abcabcde"), Pos: []Position{{0, 3}, {3, 8}}},
+			Type:  "FixedString(4)",
+			Rows:  []int{1},
+		},
+		{
+			Name:  "FixedStringSize",
+			Input: &ColFixedStr{Buf: []byte("abcdefgh"), Size: 8},
+			Type:  "FixedString(4)",
+			Rows:  []int{0},
+		},
+		{
+			Name: "EnumString",
+			Input: func() ColInput {
+				c := new(ColStr).LowCardinality()
+				c.AppendArr([]string{"a", "c", "b"})
+				return c
+			}(),
+			Type: "Enum8('a' = 1, 'b' = 2)",
+			Rows: []int{1},
+		},
+		{
+			Name:  "EnumRaw",
+			Input: ColEnum8{1, 5, 2},
+			Type:  "Enum8('a' = 1, 'b' = 2)",
+			Rows:  []int{1},
+		},
+		{
+			Name: "DateTimeWraps",
+			// 1960 wraps to 2096 on Append and can't be detected.
+			Input: dt(
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: ColumnTypeDateTime,
+		},
+		{
+			Name: "DateTimeLowCardinality",
+			Input: func() ColInput {
+				c := new(ColDateTime).LowCardinality()
+				c.AppendArr([]time.Time{
+					time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC),
+					time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				})
+				return c
+			}(),
+			Type: ColumnTypeDateTime,
+			Rows: []int{0},
+		},
+		{
+			Name: "DateTimeToDate",
+			Input: dt(
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: ColumnTypeDate,
+		},
+		{
+			Name: "DateToDateTime",
+			Input: date(
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2120, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: ColumnTypeDateTime,
+			Rows: []int{1},
+		},
+		{
+			Name: "DateWraps",
+			Input: date(
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: ColumnTypeDate,
+		},
+		{
+			Name: "Date32ToDate",
+			Input: date32(
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: ColumnTypeDate,
+			Rows: []int{1, 2},
+		},
+		{
+			Name: "Date32",
+			Input: date32(
+				time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: ColumnTypeDate32,
+			Rows: []int{0},
+		},
+		{
+			Name: "DateTime64ToDateTime",
+			Input: dt64(
+				time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: ColumnTypeDateTime,
+			Rows: []int{1, 2},
+		},
+		{
+			Name: "DateTime64",
+			Input: dt64(
+				time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC),
+			),
+			Type: "DateTime64(3)",
+			Rows: []int{1},
+		},
+		{
+			Name:  "NaN",
+			Input: ColFloat64{1, math.NaN(), math.Inf(1)},
+			Type:  ColumnTypeInt64,
+			Rows:  []int{1, 2},
+		},
+		{
+			Name:  "Float",
+			Input: ColFloat64{1, math.NaN()},
+			Type:  ColumnTypeFloat32,
+		},
+		{
+			Name:  "Nullable",
+			Input: nullable(NewNullable("abc"), Null[string](), NewNullable("a")),
+			Type:  "Nullable(FixedString(2))",
+			Rows:  []int{0},
+		},
+		{
+			Name:  "NotNullable",
+			Input: nullable(NewNullable("a"), Null[string]()),
+			Type:  ColumnTypeString,
+			Rows:  []int{1},
+		},
+		{
+			Name: "Array",
+			Input: func() ColInput {
+				c := new(ColStr).Array()
+				c.Append([]string{"a"})
+				c.Append(nil)
+				c.Append([]string{"abc", "a", "abcd"})
+				c.Append([]string{"ab"})
+				return c
+			}(),
+			Type: "Array(LowCardinality(FixedString(2)))",
+			Rows: []int{2, 2},
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rows []int
+			err := ValidateColumn(tt.Input, tt.Type, func(row int, err error) {
+				require.Error(t, err)
+				rows = append(rows, row)
+			})
+			if tt.Error {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.Rows, rows)
+		})
+	}
+}
+
+func TestValidateTime(t *testing.T) {
+	for _, tt := range []struct {
+		Type  ColumnType
+		Value time.Time
+		Error bool
+	}{
+		{Type: ColumnTypeDate, Value: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: ColumnTypeDate, Value: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), Error: true},
+		{Type: ColumnTypeDate, Value: time.Date(2150, 1, 1, 0, 0, 0, 0, time.UTC), Error: true},
+		{Type: ColumnTypeDate32, Value: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: ColumnTypeDateTime, Value: time.Unix(0, 0)},
+		{Type: ColumnTypeDateTime, Value: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), Error: true},
+		{Type: "DateTime('UTC')", Value: time.Date(2107, 1, 1, 0, 0, 0, 0, time.UTC), Error: true},
+		{Type: "Nullable(DateTime)", Value: time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC), Error: true},
+		{Type: "DateTime64(3)", Value: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: "DateTime64(3)", Value: time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC), Error: true},
+		{Type: ColumnTypeString, Value: time.Now(), Error: true},
+	} {
+		err := ValidateTime(tt.Type, tt.Value)
+		if tt.Error {
+			require.Error(t, err, "%s: %s", tt.Type, tt.Value)
+		} else {
+			require.NoError(t, err, "%s: %s", tt.Type, tt.Value)
+		}
+	}
+}
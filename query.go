@@ -16,6 +16,7 @@ import (
 	"go.uber.org/atomic"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/ClickHouse/ch-go/compress"
@@ -36,6 +37,10 @@ func (c *Client) cancelQuery() error {
 		Buf: make([]byte, 1),
 	}
 	proto.ClientCodeCancel.Encode(&b)
+	// Unblocking pending write, if any, so write lock can be acquired.
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.SetWriteDeadline(deadline)
+	}
 	if err := c.flushBuf(ctx, &b); err != nil {
 		return errors.Wrap(err, "flush")
 	}
@@ -67,8 +72,8 @@ func (c *Client) querySettings(q Query) []proto.Setting {
 	return result
 }
 
-// sendQuery starts query.
-func (c *Client) sendQuery(ctx context.Context, q Query) error {
+// sendQuery starts query, propagating span context sc to server.
+func (c *Client) sendQuery(ctx context.Context, q Query, sc trace.SpanContext) error {
 	if ce := c.lg.Check(zap.DebugLevel, "sendQuery"); ce != nil {
 		ce.Write(
 			zap.String("query", q.Body),
@@ -82,7 +87,7 @@ func (c *Client) sendQuery(ctx context.Context, q Query) error {
 		ID:          q.QueryID,
 		Body:        q.Body,
 		Secret:      q.Secret,
-		Stage:       proto.StageComplete,
+		Stage:       q.Stage.protoStage(),
 		Compression: c.compression,
 		Settings:    c.querySettings(q),
 		Parameters:  q.Parameters,
@@ -101,19 +106,23 @@ func (c *Client) sendQuery(ctx context.Context, q Query) error {
 			ClientHostname: "",
 			ClientName:     c.version.Name,
 
-			Span:     trace.SpanContextFromContext(ctx),
+			Span:     sc,
 			QuotaKey: q.QuotaKey,
 		},
 	})
 
 	// Encoding external data if provided.
-	if len(q.ExternalData) > 0 {
-		if q.ExternalTable == "" {
-			// Resembling behavior of clickhouse-client.
-			q.ExternalTable = "_data"
+	//
+	// Each table is sent as separate named data blocks.
+	for _, t := range q.externalTables() {
+		if t.Name == "" {
+			return errors.New("external table name is blank")
+		}
+		if len(t.Data) == 0 {
+			return errors.Errorf("external table %q: no columns", t.Name)
 		}
-		if err := c.encodeBlock(ctx, q.ExternalTable, q.ExternalData); err != nil {
-			return errors.Wrap(err, "external data")
+		if err := c.encodeStream(ctx, t.Name, t.Data, t.OnInput, inputOptions{Header: true}); err != nil {
+			return errors.Wrapf(err, "external table %q", t.Name)
 		}
 	}
 	// End of external data.
@@ -124,6 +133,34 @@ func (c *Client) sendQuery(ctx context.Context, q Query) error {
 	return nil
 }
 
+// Stage of query processing.
+type Stage byte
+
+// Possible stages of query processing.
+const (
+	// StageComplete is complete query processing, default.
+	StageComplete Stage = iota
+	// StageFetchColumns only fetches columns of query result.
+	StageFetchColumns
+	// StageWithMergeableState returns intermediate states of aggregate
+	// functions that can be merged, like ones received from shards
+	// by Distributed query initiator.
+	//
+	// See proto.ColAggregateFunction.
+	StageWithMergeableState
+)
+
+func (s Stage) protoStage() proto.Stage {
+	switch s {
+	case StageFetchColumns:
+		return proto.StageFetchColumns
+	case StageWithMergeableState:
+		return proto.StageWithMergeableState
+	default:
+		return proto.StageComplete
+	}
+}
+
 // Query to ClickHouse.
 type Query struct {
 	// Body of query, like "SELECT 1".
@@ -142,6 +179,36 @@ type Query struct {
 	// Optional, single block is ingested from Input if not provided,
 	// but query will fail if Input is set but has zero rows.
 	OnInput func(ctx context.Context) error
+	// MaxBlockRows is maximum number of rows in single block of Input,
+	// optional. Larger input is split to multiple blocks.
+	//
+	// Input columns should be proto.Sliceable. Note that atomicity is
+	// guaranteed only within single block.
+	MaxBlockRows int
+	// MaxBlockBytes is maximum uncompressed size of single block of Input,
+	// optional. Larger input is split to multiple blocks, but each block
+	// contains at least one row.
+	//
+	// Same as MaxBlockRows, requires proto.Sliceable columns.
+	MaxBlockBytes int
+	// ValidateInput enables validation of each Input block against column
+	// types of table before it is sent, e.g. for FixedString length, Enum
+	// values and DateTime64 range (see proto.ValidateColumn), reporting
+	// invalid rows as *ValidationError instead of server exception for whole
+	// block.
+	//
+	// Out of range values of proto.ColDate and proto.ColDateTime are wrapped
+	// on Append and can't be detected, use proto.ValidateTime before Append.
+	ValidateInput bool
+	// OnInvalidInput is called with errors of Input block if ValidateInput
+	// is set, before block is sent. Invalid rows are skipped if nil is
+	// returned, e.g. after sending them to dead-letter queue, otherwise query
+	// fails with returned error. Skipping rows requires proto.Sliceable
+	// columns.
+	//
+	// Input should not be modified by OnInvalidInput. Optional, query fails
+	// with *ValidationError if not provided.
+	OnInvalidInput func(ctx context.Context, err *ValidationError) error
 
 	// Result columns for SELECT operations.
 	Result proto.Result
@@ -170,11 +237,26 @@ type Query struct {
 	OnLog func(ctx context.Context, l Log) error
 	// OnLogs is optional handler for server log events.
 	OnLogs func(ctx context.Context, l []Log) error
+	// OnPartUUIDs is optional handler for unique identifiers of data parts
+	// that were read by server, e.g. when part UUID deduplication is enabled.
+	OnPartUUIDs func(ctx context.Context, uuids []uuid.UUID) error
+	// Stats is optionally filled with summary of query execution,
+	// e.g. for slow query logging. Reset on start of query.
+	Stats *QueryStats
+
+	// OnReadTask is optional handler for server request of next read task,
+	// e.g. file name for s3Cluster query. Empty task reports that no
+	// more tasks are available.
+	//
+	// Empty task is sent if not provided.
+	OnReadTask func(ctx context.Context) (string, error)
 
 	// Settings are optional query-scoped settings. Can override client settings.
 	Settings []Setting
 
 	// EXPERIMENTAL: parameters for query.
+	//
+	// See EncodeParameters for building typed parameters.
 	Parameters []proto.Parameter
 
 	// Secret is optional inter-server per-cluster secret for Distributed queries.
@@ -191,9 +273,57 @@ type Query struct {
 	ExternalData []proto.InputColumn
 	// ExternalTable name. Defaults to _data.
 	ExternalTable string
+	// ExternalTables are optional temporary tables for server to load,
+	// sent after ExternalData if both are set.
+	//
+	// https://clickhouse.com/docs/en/engines/table-engines/special/external-data/
+	ExternalTables []ExternalTable
+
+	// Stage of query processing, defaults to StageComplete.
+	//
+	// Advanced option for custom merging of intermediate results, e.g.
+	// StageWithMergeableState for fetching aggregate function states
+	// from shards.
+	Stage Stage
 
 	// Logger for query, optional, defaults to client logger with `query_id` field.
 	Logger *zap.Logger
+
+	// noTrace disables instrumentation of internal queries.
+	noTrace bool
+}
+
+// ExternalTable is temporary table that is sent to server with query.
+type ExternalTable struct {
+	// Name of table, like "_data".
+	Name string
+	// Data columns of table.
+	Data []proto.InputColumn
+	// OnInput is called to allow ingesting more data to Data, same as
+	// Query.OnInput.
+	//
+	// The io.EOF reports that no more data should be ingested.
+	//
+	// Optional, single block is sent from Data if not provided.
+	OnInput func(ctx context.Context) error
+}
+
+// externalTables returns all external tables of query.
+func (q Query) externalTables() []ExternalTable {
+	if len(q.ExternalData) == 0 {
+		return q.ExternalTables
+	}
+	name := q.ExternalTable
+	if name == "" {
+		// Resembling behavior of clickhouse-client.
+		name = "_data"
+	}
+	tables := make([]ExternalTable, 0, len(q.ExternalTables)+1)
+	tables = append(tables, ExternalTable{
+		Name: name,
+		Data: q.ExternalData,
+	})
+	return append(tables, q.ExternalTables...)
 }
 
 // CorruptedDataErr means that provided hash mismatch with calculated.
@@ -269,6 +399,16 @@ func (c *Client) decodeBlock(ctx context.Context, opt decodeOptions) error {
 // If input length is zero, blank block will be encoded, which is special case
 // for "end of data".
 func (c *Client) encodeBlock(ctx context.Context, tableName string, input []proto.InputColumn) error {
+	_, err := c.encodeLimitedBlock(ctx, tableName, input, 0)
+	return err
+}
+
+// encodeLimitedBlock is encodeBlock that discards block with more than one
+// row if its uncompressed size exceeds maxBytes, returning size of
+// discarded block. Zero maxBytes is unlimited.
+func (c *Client) encodeLimitedBlock(ctx context.Context, tableName string, input []proto.InputColumn, maxBytes int) (int, error) {
+	// Saving offset of packet to discard it if needed.
+	packetStart := len(c.buf.Buf)
 	proto.ClientCodeData.Encode(c.buf)
 	clientData := proto.ClientData{
 		// External data table name.
@@ -283,7 +423,6 @@ func (c *Client) encodeBlock(ctx context.Context, tableName string, input []prot
 		Columns: len(input),
 	}
 	if len(input) > 0 {
-		c.metricsInc(ctx, queryMetrics{BlocksSent: 1})
 		b.Rows = input[0].Data.Rows()
 		b.Info = proto.BlockInfo{
 			// TODO(ernado): investigate and document
@@ -291,7 +430,17 @@ func (c *Client) encodeBlock(ctx context.Context, tableName string, input []prot
 		}
 	}
 	if err := b.EncodeBlock(c.buf, c.protocolVersion, input); err != nil {
-		return errors.Wrap(err, "encode")
+		return 0, errors.Wrap(err, "encode")
+	}
+	if size := len(c.buf.Buf) - start; maxBytes > 0 && b.Rows > 1 && size > maxBytes {
+		c.buf.Buf = c.buf.Buf[:packetStart]
+		return size, nil
+	}
+	if len(input) > 0 {
+		c.metricsInc(ctx, queryMetrics{BlocksSent: 1})
+		if !c.noMetrics.Load() {
+			c.metrics.rowsSent.Add(ctx, int64(b.Rows), c.metrics.with())
+		}
 	}
 
 	// Performing compression.
@@ -301,11 +450,134 @@ func (c *Client) encodeBlock(ctx context.Context, tableName string, input []prot
 	if c.compression == proto.CompressionEnabled {
 		data := c.buf.Buf[start:]
 		if err := c.compressor.Compress(c.compressionMethod, data); err != nil {
-			return errors.Wrap(err, "compress")
+			return 0, errors.Wrap(err, "compress")
+		}
+		if len(input) > 0 && len(c.compressor.Data) > 0 && !c.noMetrics.Load() {
+			c.metrics.compressionRatio.Record(ctx,
+				float64(len(data))/float64(len(c.compressor.Data)),
+				c.metrics.with(otelch.CompressionMethod(c.compressionMethod.String())),
+			)
 		}
 		c.buf.Buf = append(c.buf.Buf[:start], c.compressor.Data...)
 	}
 
+	return 0, nil
+}
+
+// blockLimit limits size of encoded input blocks.
+type blockLimit struct {
+	Rows  int
+	Bytes int
+}
+
+// inputOptions of encoded input stream.
+type inputOptions struct {
+	Limit blockLimit
+	// Validate input block, optional. Returns ranges of rows to encode.
+	Validate func(ctx context.Context, input proto.Input) ([]rowRange, error)
+	// Header requires block with columns to be encoded even if
+	// initial input is blank.
+	Header bool
+}
+
+// encodeInput encodes input as one or more data blocks of table.
+func (c *Client) encodeInput(ctx context.Context, tableName string, input proto.Input, opt inputOptions) error {
+	all := rowRange{End: input[0].Data.Rows()}
+	if opt.Validate == nil {
+		return c.encodeRows(ctx, tableName, input, all, opt.Limit)
+	}
+	ranges, err := opt.Validate(ctx, input)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		if err := c.encodeRows(ctx, tableName, input, r, opt.Limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockProbeRows is number of rows encoded to estimate rows count of block
+// limited by size.
+const blockProbeRows = 256
+
+// sliceInput sets columns of chunk to rows [start, end) of input columns.
+func sliceInput(chunk, input proto.Input, start, end int) error {
+	for i, col := range input {
+		var data proto.ColInput
+		if s, ok := col.Data.(proto.Sliceable); ok {
+			data = s.Slice(start, end)
+		}
+		if data == nil {
+			return errors.Errorf("column %q: %s can't be split to blocks", col.Name, col.Data.Type())
+		}
+		chunk[i] = proto.InputColumn{Name: col.Name, Data: data}
+	}
+	return nil
+}
+
+// encodeRows encodes rows of input as one or more data blocks of table,
+// splitting them to blocks that satisfy limit.
+func (c *Client) encodeRows(ctx context.Context, tableName string, input proto.Input, r rowRange, limit blockLimit) error {
+	rows := input[0].Data.Rows()
+	if r.Start == 0 && r.End == rows &&
+		(rows == 0 || (limit.Bytes <= 0 && (limit.Rows <= 0 || rows <= limit.Rows))) {
+		return c.encodeBlock(ctx, tableName, input)
+	}
+	step := r.End - r.Start
+	if limit.Rows > 0 && limit.Rows < step {
+		step = limit.Rows
+	}
+	chunk := make(proto.Input, len(input))
+	if limit.Bytes > 0 && step > blockProbeRows {
+		// Estimating rows count from size of first rows, so whole input
+		// is not encoded at once.
+		if err := sliceInput(chunk, input, r.Start, r.Start+blockProbeRows); err != nil {
+			return err
+		}
+		var (
+			b     proto.Buffer
+			probe = proto.Block{Columns: len(chunk), Rows: blockProbeRows}
+		)
+		if err := probe.EncodeRawBlock(&b, c.protocolVersion, chunk); err != nil {
+			return errors.Wrap(err, "encode probe")
+		}
+		if n := blockProbeRows * limit.Bytes / (len(b.Buf) + 1); n < step {
+			step = n
+			if step < 1 {
+				step = 1
+			}
+		}
+	}
+	for start := r.Start; start < r.End; {
+		end := start + step
+		if end > r.End {
+			end = r.End
+		}
+		if err := sliceInput(chunk, input, start, end); err != nil {
+			return err
+		}
+		size, err := c.encodeLimitedBlock(ctx, tableName, chunk, limit.Bytes)
+		if err != nil {
+			return err
+		}
+		if size > 0 {
+			// Block is too large, estimating rows count from its size.
+			step = (end - start) * limit.Bytes / size
+			if step < 1 {
+				step = 1
+			}
+			continue
+		}
+		start = end
+		if start < rows {
+			// Flushing the buffer to prevent high memory consumption.
+			if err := c.flush(ctx); err != nil {
+				return errors.Wrap(err, "flush")
+			}
+		}
+	}
 	return nil
 }
 
@@ -345,15 +617,44 @@ func (c *Client) sendInput(ctx context.Context, info proto.ColInfoInput, q Query
 	if inferenceDebug != nil && len(inferenceColumns) > 0 {
 		inferenceDebug.Write(zap.Any("columns", inferenceColumns))
 	}
-	var (
-		rows = q.Input[0].Data.Rows()
-		f    = q.OnInput
-	)
+	opt := inputOptions{
+		Limit: blockLimit{
+			Rows:  q.MaxBlockRows,
+			Bytes: q.MaxBlockBytes,
+		},
+	}
+	if q.ValidateInput {
+		opt.Validate = q.inputValidator(info)
+	}
+	if err := c.encodeStream(ctx, "", q.Input, q.OnInput, opt); err != nil {
+		return err
+	}
+	// End of input stream.
+	//
+	// Encoding that there are no more data.
+	if err := c.encodeBlankBlock(ctx); err != nil {
+		return errors.Wrap(err, "write end of data")
+	}
+
+	return nil
+}
+
+// encodeStream encodes input as one or more data blocks of table,
+// calling f (if provided) to ingest next block until io.EOF.
+// Each ingested block is validated and split according to opt.
+//
+// Does not encode blank block for "end of data".
+func (c *Client) encodeStream(ctx context.Context, tableName string, input proto.Input, f func(ctx context.Context) error, opt inputOptions) error {
+	rows := input[0].Data.Rows()
 	if f != nil && rows == 0 {
 		// Fetching initial input if no rows provided.
 		if err := f(ctx); err != nil {
 			if errors.Is(err, io.EOF) {
-				goto End // initial input was blank
+				if opt.Header {
+					// Server creates table from header of first block.
+					return c.encodeBlock(ctx, tableName, input)
+				}
+				return nil // initial input was blank
 			}
 			return errors.Wrap(err, "input")
 		}
@@ -365,12 +666,12 @@ func (c *Client) sendInput(ctx context.Context, info proto.ColInfoInput, q Query
 		if err := ctx.Err(); err != nil {
 			return errors.Wrap(err, "context")
 		}
-		if err := c.encodeBlock(ctx, "", q.Input); err != nil {
+		if err := c.encodeInput(ctx, tableName, input, opt); err != nil {
 			return errors.Wrap(err, "write block")
 		}
 		if f == nil {
 			// No callback, single block.
-			break
+			return nil
 		}
 		// Flushing the buffer to prevent high memory consumption.
 		if err := c.flush(ctx); err != nil {
@@ -379,7 +680,7 @@ func (c *Client) sendInput(ctx context.Context, info proto.ColInfoInput, q Query
 		if err := f(ctx); err != nil {
 			if errors.Is(err, io.EOF) {
 				// No more data.
-				if tailRows := q.Input[0].Data.Rows(); tailRows > 0 {
+				if tailRows := input[0].Data.Rows(); tailRows > 0 {
 					// Write data tail on next tick and break.
 					//
 					// This is required to resemble io.Reader behavior.
@@ -392,21 +693,12 @@ func (c *Client) sendInput(ctx context.Context, info proto.ColInfoInput, q Query
 					continue
 				}
 
-				break
+				return nil
 			}
 			// ClickHouse server persists blocks after receive.
 			return errors.Wrap(err, "next input (server already persisted previous blocks)")
 		}
 	}
-End:
-	// End of input stream.
-	//
-	// Encoding that there are no more data.
-	if err := c.encodeBlankBlock(ctx); err != nil {
-		return errors.Wrap(err, "write end of data")
-	}
-
-	return nil
 }
 
 func (c *Client) resultHandler(q Query) func(ctx context.Context, b proto.Block) error {
@@ -436,7 +728,7 @@ type (
 func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query) error {
 	switch p {
 	case proto.ServerCodeException:
-		e, err := c.exception()
+		e, err := c.exception(ctx)
 		if err != nil {
 			return errors.Wrap(err, "decode exception")
 		}
@@ -447,6 +739,10 @@ func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query)
 			return errors.Wrap(err, "progress")
 		}
 		c.metricsInc(ctx, queryMetrics{Rows: int(p.Rows), Bytes: int(p.Bytes)})
+		if q.Stats != nil {
+			q.Stats.addProgress(p)
+		}
+		c.progressEvent(p)
 		if ce := c.lg.Check(zap.DebugLevel, "Progress"); ce != nil {
 			ce.Write(
 				zap.Uint64("rows", p.Rows),
@@ -474,12 +770,34 @@ func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query)
 				zap.Uint64("blocks", p.Blocks),
 			)
 		}
+		if q.Stats != nil {
+			q.Stats.setProfile(p)
+		}
 		if f := q.OnProfile; f != nil {
 			if err := f(ctx, p); err != nil {
 				return errors.Wrap(err, "profile")
 			}
 		}
 		return nil
+	case proto.ServerCodeTimezoneUpdate:
+		name, err := c.reader.Str()
+		if err != nil {
+			return errors.Wrap(err, "timezone")
+		}
+		if ce := c.lg.Check(zap.DebugLevel, "Timezone update"); ce != nil {
+			ce.Write(zap.String("timezone", name))
+		}
+		if name == "" {
+			// Session timezone is not set, server timezone is used.
+			c.sessionTimezone = nil
+			return nil
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return errors.Wrapf(err, "load session timezone %q", name)
+		}
+		c.sessionTimezone = loc
+		return nil
 	case proto.ServerCodeTableColumns:
 		// Ignoring for now.
 		var info proto.TableColumns
@@ -491,7 +809,8 @@ func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query)
 		var data proto.ProfileEvents
 		onResult := func(ctx context.Context, b proto.Block) error {
 			ce := c.lg.Check(zap.DebugLevel, "ProfileEvents")
-			if ce == nil && q.OnProfileEvents == nil && q.OnProfileEvent == nil {
+			if ce == nil && q.OnProfileEvents == nil && q.OnProfileEvent == nil && q.Stats == nil &&
+				!(c.spanEvents.ProfileEvents && c.querySpan != nil) {
 				// No handlers, skipping.
 				return nil
 			}
@@ -499,6 +818,10 @@ func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query)
 			if err != nil {
 				return errors.Wrap(err, "events")
 			}
+			if q.Stats != nil {
+				q.Stats.addProfileEvents(events)
+			}
+			c.profileEventsEvent(events)
 			if f := q.OnProfileEvents; f != nil {
 				if err := f(ctx, events); err != nil {
 					return errors.Wrap(err, "profile events")
@@ -530,8 +853,12 @@ func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query)
 	case proto.ServerCodeLog:
 		var data proto.Logs
 		onResult := func(ctx context.Context, b proto.Block) error {
-			ce := c.lg.Check(zap.DebugLevel, "Logs")
-			if ce == nil && q.OnLogs == nil && q.OnLog == nil {
+			var ce *zapcore.CheckedEntry
+			if !c.forwardServerLogs {
+				ce = c.lg.Check(zap.DebugLevel, "Logs")
+			}
+			if ce == nil && q.OnLogs == nil && q.OnLog == nil && !c.forwardServerLogs &&
+				!(c.spanEvents.LogLevel.priority() > 0 && c.querySpan != nil) {
 				// No handlers, skipping.
 				return nil
 			}
@@ -539,6 +866,10 @@ func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query)
 			if ce != nil {
 				ce.Write(zap.Any("logs", logs))
 			}
+			if c.forwardServerLogs {
+				c.forwardLogs(logs, q)
+			}
+			c.logEvents(logs)
 			if f := q.OnLogs; f != nil {
 				if err := f(ctx, logs); err != nil {
 					return errors.Wrap(err, "logs")
@@ -563,13 +894,58 @@ func (c *Client) handlePacket(ctx context.Context, p proto.ServerCode, q Query)
 			return errors.Wrap(err, "decode block")
 		}
 		return nil
+	case proto.ServerPartUUIDs:
+		var data proto.PartUUIDs
+		if err := data.Decode(c.reader); err != nil {
+			return errors.Wrap(err, "part uuids")
+		}
+		if ce := c.lg.Check(zap.DebugLevel, "Part UUIDs"); ce != nil {
+			ce.Write(zap.Int("count", len(data.UUIDs)))
+		}
+		if f := q.OnPartUUIDs; f != nil {
+			if err := f(ctx, data.UUIDs); err != nil {
+				return errors.Wrap(err, "part uuids")
+			}
+		}
+		return nil
+	case proto.ServerReadTaskRequest:
+		var task string
+		if f := q.OnReadTask; f != nil {
+			v, err := f(ctx)
+			if err != nil {
+				return errors.Wrap(err, "read task")
+			}
+			task = v
+		}
+		if ce := c.lg.Check(zap.DebugLevel, "Read task"); ce != nil {
+			ce.Write(zap.String("task", task))
+		}
+		// Not using c.buf to prevent data race with input stream.
+		var b proto.Buffer
+		proto.ReadTaskResponse{Task: task}.Encode(&b)
+		if err := c.flushBuf(ctx, &b); err != nil {
+			return errors.Wrap(err, "flush read task")
+		}
+		return nil
+	case proto.ServerCodeMergeTreeAllRangesAnnouncement, proto.ServerCodeMergeTreeReadTaskRequest:
+		// Client can't be parallel replicas coordinator, so refusing
+		// explicitly instead of waiting for response that will never come.
+		return errors.Errorf("%q is not supported: client can't coordinate parallel replicas", p)
 	default:
 		return errors.Errorf("unexpected packet %q", p)
 	}
 }
 
-// Do performs Query on ClickHouse server.
-func (c *Client) Do(ctx context.Context, q Query) (err error) {
+// Do performs Query on ClickHouse server, calling Options.Interceptors
+// if any.
+func (c *Client) Do(ctx context.Context, q Query) error {
+	if q.QueryID == "" {
+		q.QueryID = uuid.New().String()
+	}
+	return c.invoke(ctx, q)
+}
+
+func (c *Client) do(ctx context.Context, q Query) (err error) {
 	if c.IsClosed() {
 		return ErrClosed
 	}
@@ -578,8 +954,10 @@ func (c *Client) Do(ctx context.Context, q Query) (err error) {
 			c.protocolVersion, c.server,
 		)
 	}
-	if q.QueryID == "" {
-		q.QueryID = uuid.New().String()
+	// Session time zone is query-scoped.
+	c.sessionTimezone = nil
+	if q.Stats != nil {
+		*q.Stats = QueryStats{}
 	}
 	{
 		// Setup query logger.
@@ -605,13 +983,13 @@ func (c *Client) Do(ctx context.Context, q Query) (err error) {
 		// This will be used by all function calls until query is done.
 		c.lg = lg
 	}
-	if c.otel {
+	if c.otel && !q.noTrace {
 		newCtx, span := c.tracer.Start(ctx, "Do",
 			trace.WithSpanKind(trace.SpanKindClient),
 			trace.WithAttributes(
 				semconv.DBSystemKey.String("clickhouse"),
 				semconv.DBStatementKey.String(q.Body),
-				semconv.DBUserKey.String(c.info.User),
+				semconv.DBUserKey.String(c.user),
 				semconv.DBNameKey.String(c.info.Database),
 				otelch.ProtocolVersion(c.protocolVersion),
 				otelch.QuotaKey(q.QuotaKey),
@@ -620,7 +998,12 @@ func (c *Client) Do(ctx context.Context, q Query) (err error) {
 		)
 		m := new(queryMetrics)
 		ctx = context.WithValue(newCtx, ctxQueryKey{}, m)
-		defer func() {
+		c.querySpan = span
+		c.progressEventAt = time.Time{}
+		c.progressPending = proto.Progress{}
+		defer func(ctx context.Context) {
+			c.flushProgressEvent(time.Now())
+			c.querySpan = nil
 			span.SetAttributes(
 				otelch.BlocksSent(m.BlocksSent),
 				otelch.BlocksReceived(m.BlocksReceived),
@@ -645,7 +1028,19 @@ func (c *Client) Do(ctx context.Context, q Query) (err error) {
 				span.SetStatus(codes.Ok, "")
 			}
 			span.End()
-		}()
+			if err == nil && c.serverSpans.Enabled {
+				c.importServerSpans(ctx, span, q.QueryID)
+			}
+		}(ctx)
+	}
+	if q.noTrace {
+		// Not reporting internal queries in metrics.
+		c.noMetrics.Store(true)
+		defer c.noMetrics.Store(false)
+	} else {
+		defer func(ctx context.Context, start time.Time) {
+			c.metrics.recordDuration(ctx, c.metrics.queryDuration, start, err)
+		}(ctx, time.Now())
 	}
 	g, ctx := errgroup.WithContext(ctx)
 	done := make(chan struct{})
@@ -674,9 +1069,14 @@ func (c *Client) Do(ctx context.Context, q Query) (err error) {
 			}
 		}
 	}
-	g.Go(func() error {
+	g.Go(func() (err error) {
 		// Sending data.
-		if err := c.sendQuery(ctx, q); err != nil {
+		//
+		// Server spans are children of query span, not of "Send" one.
+		sc := trace.SpanContextFromContext(ctx)
+		ctx, end := c.startSpan(ctx, "Send")
+		defer func() { end(err) }()
+		if err := c.sendQuery(ctx, q, sc); err != nil {
 			return errors.Wrap(err, "send query")
 		}
 		if err := c.flush(ctx); err != nil {
@@ -700,13 +1100,24 @@ func (c *Client) Do(ctx context.Context, q Query) (err error) {
 		}
 		return nil
 	})
-	g.Go(func() error {
+	g.Go(func() (err error) {
 		// Receiving query result, data and telemetry.
 		defer close(done)
+		ctx, end := c.startSpan(ctx, "Receive")
+		defer func() { end(err) }()
 		if colInfo != nil {
 			defer close(colInfo)
 		}
 		onResult := c.resultHandler(q)
+		onData := func(ctx context.Context, block proto.Block) error {
+			// Columns are inferred for each block, resetting Location
+			// of types without time zone, so applying on each block.
+			proto.SetDefaultLocation(q.Result, c.sessionTimezone)
+			if !q.noTrace {
+				c.metrics.rowsReceived.Add(ctx, int64(block.Rows), c.metrics.with())
+			}
+			return onResult(ctx, block)
+		}
 		for {
 			if ctx.Err() != nil {
 				return ctx.Err()
@@ -722,7 +1133,7 @@ func (c *Client) Do(ctx context.Context, q Query) (err error) {
 			switch code {
 			case proto.ServerCodeData:
 				if err := c.decodeBlock(ctx, decodeOptions{
-					Handler:      onResult,
+					Handler:      onData,
 					Result:       q.Result,
 					Compressible: code.Compressible(),
 				}); err != nil {
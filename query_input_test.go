@@ -0,0 +1,231 @@
+package ch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// bufConn is net.Conn that records written data.
+type bufConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *bufConn) Write(p []byte) (int, error) { return c.buf.Write(p) }
+
+func newInputClient(t *testing.T) (*Client, *bufConn) {
+	t.Helper()
+	metrics, err := newClientMetrics(noop.NewMeterProvider().Meter("test"))
+	require.NoError(t, err)
+	conn := new(bufConn)
+	return &Client{
+		lg:              zap.NewNop(),
+		conn:            conn,
+		buf:             new(proto.Buffer),
+		protocolVersion: proto.Version,
+		metrics:         metrics,
+	}, conn
+}
+
+// decodeInputBlocks decodes data packets written by client, calling f on
+// each decoded block.
+func decodeInputBlocks(t *testing.T, c *Client, conn *bufConn, result func() proto.Results, f func(b proto.Block, r proto.Results)) {
+	t.Helper()
+	require.NoError(t, c.flush(context.Background()))
+	r := proto.NewReader(&conn.buf)
+	for {
+		code, err := r.UVarInt()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		require.NoError(t, err)
+		require.Equal(t, proto.ClientCodeData, proto.ClientCode(code))
+		var data proto.ClientData
+		require.NoError(t, data.DecodeAware(r, c.protocolVersion))
+		var (
+			block   proto.Block
+			results = result()
+		)
+		require.NoError(t, block.DecodeBlock(r, c.protocolVersion, results))
+		f(block, results)
+	}
+}
+
+func TestClient_encodeInput(t *testing.T) {
+	ctx := context.Background()
+	t.Run("MaxBlockRows", func(t *testing.T) {
+		var (
+			id   proto.ColUInt64
+			tags = new(proto.ColStr).LowCardinality().Array()
+			kind = new(proto.ColStr).LowCardinality()
+		)
+		for i := 0; i < 100; i++ {
+			id.Append(uint64(i))
+			tags.Append([]string{fmt.Sprintf("tag%d", i%3), "tag"})
+			kind.Append(fmt.Sprintf("kind%d", i%5))
+		}
+		input := proto.Input{
+			{Name: "id", Data: id},
+			{Name: "tags", Data: tags},
+			{Name: "kind", Data: kind},
+		}
+		c, conn := newInputClient(t)
+		require.NoError(t, c.encodeInput(ctx, "", input, inputOptions{Limit: blockLimit{Rows: 30}}))
+
+		var (
+			blocks []int
+			ids    []uint64
+		)
+		decodeInputBlocks(t, c, conn, func() proto.Results {
+			return proto.Results{
+				{Name: "id", Data: new(proto.ColUInt64)},
+				{Name: "tags", Data: new(proto.ColStr).LowCardinality().Array()},
+				{Name: "kind", Data: new(proto.ColStr).LowCardinality()},
+			}
+		}, func(b proto.Block, r proto.Results) {
+			blocks = append(blocks, b.Rows)
+			var (
+				gotID   = r[0].Data.(*proto.ColUInt64)
+				gotTags = r[1].Data.(*proto.ColArr[string])
+				gotKind = r[2].Data.(*proto.ColLowCardinality[string])
+			)
+			for i := 0; i < b.Rows; i++ {
+				v := gotID.Row(i)
+				ids = append(ids, v)
+				require.Equal(t, tags.Row(int(v)), gotTags.Row(i))
+				require.Equal(t, kind.Row(int(v)), gotKind.Row(i))
+			}
+		})
+		require.Equal(t, []int{30, 30, 30, 10}, blocks)
+		require.Equal(t, []uint64(id), ids)
+	})
+	t.Run("MaxBlockBytes", func(t *testing.T) {
+		const maxBytes = 1000
+		var data proto.ColStr
+		for i := 0; i < 50; i++ {
+			data.Append(strings.Repeat("a", i*5))
+		}
+		// Row that exceeds limit is sent as single block.
+		data.Append(strings.Repeat("b", maxBytes*2))
+		input := proto.Input{{Name: "v", Data: data}}
+
+		c, conn := newInputClient(t)
+		require.NoError(t, c.encodeInput(ctx, "", input, inputOptions{Limit: blockLimit{Bytes: maxBytes}}))
+
+		var (
+			blocks int
+			rows   []string
+		)
+		decodeInputBlocks(t, c, conn, func() proto.Results {
+			return proto.Results{{Name: "v", Data: new(proto.ColStr)}}
+		}, func(b proto.Block, r proto.Results) {
+			blocks++
+			col := r[0].Data.(*proto.ColStr)
+			if b.Rows > 1 {
+				require.LessOrEqual(t, len(col.Buf), maxBytes)
+			}
+			for i := 0; i < col.Rows(); i++ {
+				rows = append(rows, col.Row(i))
+			}
+		})
+		require.Greater(t, blocks, 2)
+		require.Len(t, rows, data.Rows())
+		for i, v := range rows {
+			require.Equal(t, data.Row(i), v)
+		}
+	})
+	t.Run("MaxBlockBytesLarge", func(t *testing.T) {
+		const maxBytes = 8192
+		var data proto.ColUInt64
+		for i := 0; i < 100_000; i++ {
+			data.Append(uint64(i))
+		}
+		input := proto.Input{{Name: "v", Data: data}}
+
+		c, conn := newInputClient(t)
+		require.NoError(t, c.encodeInput(ctx, "", input, inputOptions{Limit: blockLimit{Bytes: maxBytes}}))
+		// Input should not be encoded at once to estimate block size.
+		require.Less(t, cap(c.buf.Buf), maxBytes*8)
+
+		var rows int
+		decodeInputBlocks(t, c, conn, func() proto.Results {
+			return proto.Results{{Name: "v", Data: new(proto.ColUInt64)}}
+		}, func(b proto.Block, r proto.Results) {
+			require.LessOrEqual(t, b.Rows*8, maxBytes)
+			rows += b.Rows
+		})
+		require.Equal(t, data.Rows(), rows)
+	})
+	t.Run("NoLimit", func(t *testing.T) {
+		input := proto.Input{{Name: "v", Data: proto.ColInt8{1, 2, 3}}}
+		c, conn := newInputClient(t)
+		require.NoError(t, c.encodeInput(ctx, "", input, inputOptions{Limit: blockLimit{Rows: 3}}))
+
+		var blocks []int
+		decodeInputBlocks(t, c, conn, func() proto.Results {
+			return proto.Results{{Name: "v", Data: new(proto.ColInt8)}}
+		}, func(b proto.Block, r proto.Results) {
+			blocks = append(blocks, b.Rows)
+		})
+		require.Equal(t, []int{3}, blocks)
+	})
+	t.Run("NotSliceable", func(t *testing.T) {
+		c, _ := newInputClient(t)
+		input := proto.Input{{Name: "v", Data: proto.NewArray[string](notSliceableStr{new(proto.ColStr)})}}
+		input[0].Data.(*proto.ColArr[string]).Append([]string{"foo"})
+		input[0].Data.(*proto.ColArr[string]).Append([]string{"bar"})
+		require.ErrorContains(t,
+			c.encodeInput(ctx, "", input, inputOptions{Limit: blockLimit{Rows: 1}}),
+			`column "v": Array(String) can't be split to blocks`,
+		)
+	})
+}
+
+func TestClient_encodeStream(t *testing.T) {
+	ctx := context.Background()
+	blank := func(ctx context.Context) error { return io.EOF }
+	t.Run("Header", func(t *testing.T) {
+		c, conn := newInputClient(t)
+		input := proto.Input{
+			{Name: "k", Data: new(proto.ColInt64)},
+			{Name: "v", Data: new(proto.ColStr)},
+		}
+		require.NoError(t, c.encodeStream(ctx, "external", input, blank, inputOptions{Header: true}))
+
+		var blocks []int
+		decodeInputBlocks(t, c, conn, func() proto.Results {
+			return proto.Results{
+				{Name: "k", Data: new(proto.ColInt64)},
+				{Name: "v", Data: new(proto.ColStr)},
+			}
+		}, func(b proto.Block, r proto.Results) {
+			require.Equal(t, 2, b.Columns)
+			blocks = append(blocks, b.Rows)
+		})
+		require.Equal(t, []int{0}, blocks)
+	})
+	t.Run("Blank", func(t *testing.T) {
+		c, conn := newInputClient(t)
+		input := proto.Input{{Name: "v", Data: new(proto.ColInt64)}}
+		require.NoError(t, c.encodeStream(ctx, "", input, blank, inputOptions{}))
+		require.NoError(t, c.flush(ctx))
+		require.Zero(t, conn.buf.Len())
+	})
+}
+
+// notSliceableStr hides Slice method of column.
+type notSliceableStr struct {
+	proto.ColumnOf[string]
+}
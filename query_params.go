@@ -2,20 +2,39 @@ package ch
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
 
 	"github.com/ClickHouse/ch-go/proto"
 )
 
 // Parameters is helper for building Query.Parameters.
 //
+// Types of parameters are inferred from Go values, use EncodeParameters
+// to validate values against placeholders of query.
+//
+// Integers, floats, booleans and strings are formatted as is, with special
+// characters of strings escaped. The time.Time is formatted as unix
+// timestamp (DateTime or DateTime64(9)), nil and nil pointers as NULL,
+// slices and maps as Array and Map literals. Values of other types are
+// formatted via fmt.Sprint.
+//
 // EXPERIMENTAL.
 func Parameters(m map[string]any) []proto.Parameter {
 	var out []proto.Parameter
 	for k, v := range m {
+		value, err := formatParameter(inferParameterType(v), v)
+		if err != nil {
+			// Fallback to string representation.
+			value = quoteParameter(fmt.Sprint(v))
+		}
 		out = append(out, proto.Parameter{
 			Key:   k,
-			Value: fmt.Sprintf("'%v'", v),
+			Value: value,
 		})
 	}
 	// Sorting to make output deterministic.
@@ -25,3 +44,208 @@ func Parameters(m map[string]any) []proto.Parameter {
 
 	return out
 }
+
+// ParameterError means that query parameter is invalid.
+type ParameterError struct {
+	Name string
+	Type proto.ColumnType // blank if parameter is not found in query
+	Err  error
+}
+
+func (e *ParameterError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("parameter %q: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("parameter %q (%s): %s", e.Name, e.Type, e.Err)
+}
+
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
+
+// EncodeParameters encodes params to Query.Parameters, validating them
+// against {name:Type} placeholders in query body.
+//
+// Values are formatted according to placeholder type, e.g. time.Time for
+// DateTime, slices for Array, maps for Map, []any for Tuple and nil
+// for Nullable. Each placeholder should have a value and each value should
+// have a placeholder.
+//
+// Returns *ParameterError if some parameter is invalid.
+func EncodeParameters(body string, params map[string]any) ([]proto.Parameter, error) {
+	types, err := parsePlaceholders(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse placeholders")
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := params[name]; !ok {
+			return nil, &ParameterError{
+				Name: name,
+				Type: types[name],
+				Err:  errors.New("value not provided"),
+			}
+		}
+	}
+
+	names = names[:0]
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]proto.Parameter, 0, len(names))
+	for _, name := range names {
+		t, ok := types[name]
+		if !ok {
+			return nil, &ParameterError{
+				Name: name,
+				Err:  errors.New("placeholder not found in query"),
+			}
+		}
+		v, err := formatParameter(t, params[name])
+		if err != nil {
+			return nil, &ParameterError{
+				Name: name,
+				Type: t,
+				Err:  err,
+			}
+		}
+		out = append(out, proto.Parameter{
+			Key:   name,
+			Value: v,
+		})
+	}
+
+	return out, nil
+}
+
+// parsePlaceholders returns types of {name:Type} placeholders in query body.
+func parsePlaceholders(body string) (map[string]proto.ColumnType, error) {
+	out := map[string]proto.ColumnType{}
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; c {
+		case '\'', '"', '`':
+			// Skipping literals and quoted identifiers.
+			i = skipQuoted(body, i)
+		case '-':
+			if strings.HasPrefix(body[i:], "--") {
+				end := strings.IndexByte(body[i:], '\n')
+				if end < 0 {
+					return out, nil
+				}
+				i += end
+			}
+		case '/':
+			if strings.HasPrefix(body[i:], "/*") {
+				end := strings.Index(body[i+2:], "*/")
+				if end < 0 {
+					return out, nil
+				}
+				i += end + 3
+			}
+		case '{':
+			name, t, n := parsePlaceholder(body[i:])
+			if n == 0 {
+				continue
+			}
+			if prev, ok := out[name]; ok && prev != t {
+				return nil, errors.Errorf("placeholder %q: conflicting types %q and %q", name, prev, t)
+			}
+			out[name] = t
+			i += n - 1
+		}
+	}
+	return out, nil
+}
+
+// skipQuoted returns index of closing quote for quote at s[start].
+func skipQuoted(s string, start int) int {
+	q := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case q:
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdent(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// parsePlaceholder parses "{name:Type}" prefix of s, returning zero length
+// if s is not started with placeholder.
+func parsePlaceholder(s string) (name string, t proto.ColumnType, n int) {
+	i := 1 // skip '{'
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	start := i
+	if i >= len(s) || !isIdentStart(s[i]) {
+		return "", "", 0
+	}
+	for i < len(s) && isIdent(s[i]) {
+		i++
+	}
+	name = s[start:i]
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if i >= len(s) || s[i] != ':' {
+		return "", "", 0
+	}
+	i++
+	start = i
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			i = skipQuoted(s, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '}':
+			if depth != 0 {
+				continue
+			}
+			typ := strings.TrimSpace(s[start:i])
+			if typ == "" {
+				return "", "", 0
+			}
+			return name, proto.ColumnType(typ), i + 1
+		}
+	}
+	return "", "", 0
+}
+
+// inferParameterType returns ClickHouse type of Go value v, defaulting to
+// String if type can't be inferred.
+func inferParameterType(v any) proto.ColumnType {
+	v = indirect(v)
+	switch v := v.(type) {
+	case nil:
+		return proto.ColumnTypeNullable.Sub(proto.ColumnTypeNothing)
+	case time.Time:
+		if v.Nanosecond() != 0 {
+			return proto.ColumnTypeDateTime64.With("9")
+		}
+		return proto.ColumnTypeDateTime
+	}
+	if t, ok := inferParameterTypeOf(reflect.TypeOf(v)); ok {
+		return t
+	}
+	return proto.ColumnTypeString
+}
@@ -2,8 +2,12 @@ package ch
 
 import (
 	"context"
+	"math"
+	"net/netip"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ClickHouse/ch-go/proto"
@@ -22,3 +26,165 @@ func TestQueryParameters(t *testing.T) {
 		Result: discardResult(),
 	}))
 }
+
+func TestEncodeParameters(t *testing.T) {
+	ts := time.Date(2023, 11, 14, 22, 13, 20, 123456789, time.UTC)
+	for _, tt := range []struct {
+		Body   string
+		Value  any
+		Result string
+	}{
+		{"{v:UInt8}", 100, `'100'`},
+		{"{v:Int64}", int64(-15), `'-15'`},
+		{"{v:Int128}", proto.Int128FromInt(-1), `'-1'`},
+		{"{v:UInt256}", proto.UInt256FromUInt64(42), `'42'`},
+		{"{v:Float64}", 1.5, `'1.5'`},
+		{"{v:Float32}", math.Inf(-1), `'-inf'`},
+		{"{v:Bool}", true, `'true'`},
+		{"{v:String}", "it's", `'it\'s'`},
+		{"{v:String}", "a\tb\\", `'a\\tb\\\\'`},
+		{"{v: String }", []byte("foo"), `'foo'`},
+		{"{v:FixedString(3)}", "foo", `'foo'`},
+		{"{v:Identifier}", "table", `'table'`},
+		{"{v:Enum8('a' = 1, 'b' = 2)}", "b", `'b'`},
+		{"{v:Enum16('a' = 1, 'b' = 2)}", 1, `'a'`},
+		{"{v:UUID}", uuid.MustParse("d8b8e2a1-5c3e-4b5a-9c5e-2f0a4b7b2d2e"), `'d8b8e2a1-5c3e-4b5a-9c5e-2f0a4b7b2d2e'`},
+		{"{v:IPv4}", netip.MustParseAddr("127.0.0.1"), `'127.0.0.1'`},
+		{"{v:IPv6}", "::1", `'::1'`},
+		{"{v:Date}", ts, `'2023-11-14'`},
+		{"{v:Date32}", proto.NewDate32(1900, 1, 1), `'1900-01-01'`},
+		{"{v:DateTime}", ts, `'1700000000'`},
+		{"{v:DateTime('Europe/Moscow')}", ts, `'1700000000'`},
+		{"{v:DateTime64(3)}", ts, `'1700000000.123'`},
+		{"{v:DateTime64(9, 'UTC')}", ts, `'1700000000.123456789'`},
+		{"{v:Decimal(9, 2)}", proto.Decimal32(-5), `'-0.05'`},
+		{"{v:Decimal64(3)}", "12.345", `'12.345'`},
+		{"{v:Decimal128(2)}", proto.Decimal128(proto.Int128FromInt(12345)), `'123.45'`},
+		{"{v:Nullable(String)}", nil, `'\\N'`},
+		{"{v:Nullable(String)}", (*string)(nil), `'\\N'`},
+		{"{v:LowCardinality(Nullable(String))}", "foo", `'foo'`},
+		{"{v:Array(String)}", []string{"a", "b'"}, `'[\'a\',\'b\\\'\']'`},
+		{"{v:Array(Nullable(UInt8))}", []*uint8{nil}, `'[NULL]'`},
+		{"{v:Array(Array(Int32))}", [][]int32{{1, 2}, {}}, `'[[1,2],[]]'`},
+		{"{v:Map(String, UInt64)}", map[string]uint64{"b": 2, "a": 1}, `'{\'a\':1,\'b\':2}'`},
+		{"{v:Tuple(String, Int8)}", []any{"a", 1}, `'(\'a\',1)'`},
+		{"{v:Tuple(s String, d Date)}", []any{"a", ts}, `'(\'a\',\'2023-11-14\')'`},
+	} {
+		t.Run(tt.Body, func(t *testing.T) {
+			params, err := EncodeParameters("SELECT "+tt.Body, map[string]any{"v": tt.Value})
+			require.NoError(t, err)
+			require.Equal(t, []proto.Parameter{{Key: "v", Value: tt.Result}}, params)
+		})
+	}
+	t.Run("Placeholders", func(t *testing.T) {
+		params, err := EncodeParameters(
+			"SELECT '{s:String}', `{q:String}` -- {c:String}\n"+
+				"FROM {table:Identifier} /* {c:String} */ WHERE x = {x:UInt8} AND y = {x:UInt8}",
+			map[string]any{"table": "t", "x": 1},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []proto.Parameter{
+			{Key: "table", Value: `'t'`},
+			{Key: "x", Value: `'1'`},
+		}, params)
+	})
+	for _, tt := range []struct {
+		Name   string
+		Body   string
+		Params map[string]any
+		Error  string
+	}{
+		{"Missing", "SELECT {v:UInt8}", nil, `parameter "v" (UInt8): value not provided`},
+		{"Unknown", "SELECT 1", map[string]any{"v": 1}, `parameter "v": placeholder not found in query`},
+		{"Conflict", "SELECT {v:UInt8}, {v:String}", nil, `conflicting types "UInt8" and "String"`},
+		{"Overflow", "SELECT {v:UInt8}", map[string]any{"v": 256}, `parameter "v" (UInt8): value 256 overflows 8 bits`},
+		{"Negative", "SELECT {v:UInt64}", map[string]any{"v": -1}, `parameter "v" (UInt64): value -1 overflows 64 bits`},
+		{"Nil", "SELECT {v:String}", map[string]any{"v": nil}, `nil value for non-Nullable type`},
+		{"Type", "SELECT {v:Int32}", map[string]any{"v": "1x"}, `unexpected string for integer`},
+		{"FixedString", "SELECT {v:FixedString(2)}", map[string]any{"v": "foo"}, `string of 3 bytes is too long`},
+		{"Enum", "SELECT {v:Enum8('a' = 1)}", map[string]any{"v": "b"}, `unknown enum value "b"`},
+		{"Tuple", "SELECT {v:Tuple(UInt8, UInt8)}", map[string]any{"v": []int{1}}, `got 1 elements instead of 2`},
+		{"ArrayElem", "SELECT {v:Array(UInt8)}", map[string]any{"v": []int{1, 1000}}, `[1]: value 1000 overflows 8 bits`},
+		{"IPv4", "SELECT {v:IPv4}", map[string]any{"v": "::1"}, `::1 is not IPv4`},
+		{"Unsupported", "SELECT {v:Point}", map[string]any{"v": 1}, `type "Point" is not supported`},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := EncodeParameters(tt.Body, tt.Params)
+			require.ErrorContains(t, err, tt.Error)
+			if tt.Name != "Conflict" {
+				var paramErr *ParameterError
+				require.ErrorAs(t, err, &paramErr)
+				require.Equal(t, "v", paramErr.Name)
+			}
+		})
+	}
+}
+
+func TestParameters(t *testing.T) {
+	require.Equal(t, []proto.Parameter{
+		{Key: "arr", Value: `'[\'a\']'`},
+		{Key: "n", Value: `'\\N'`},
+		{Key: "num", Value: `'100'`},
+		{Key: "str", Value: `'it\'s'`},
+	}, Parameters(map[string]any{
+		"arr": []string{"a"},
+		"n":   nil,
+		"num": 100,
+		"str": "it's",
+	}))
+}
+
+func TestParametersCompat(t *testing.T) {
+	ts := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	for _, tt := range []struct {
+		Name   string
+		Value  any
+		Result string
+	}{
+		// Same as fmt.Sprintf("'%v'", v).
+		{"Int", 100, `'100'`},
+		{"Uint64", uint64(42), `'42'`},
+		{"Float", 1.5, `'1.5'`},
+		{"Bool", true, `'true'`},
+		{"String", "foo", `'foo'`},
+		{"Struct", struct{ A int }{1}, `'{1}'`},
+		// Changed behavior.
+		{"StringQuote", "it's", `'it\'s'`},
+		{"Time", ts, `'1700000000'`},
+		{"TimeNano", ts.Add(123 * time.Millisecond), `'1700000000.123000000'`},
+		{"Nil", nil, `'\\N'`},
+		{"NilPointer", (*string)(nil), `'\\N'`},
+		{"Slice", []int{1, 2}, `'[1,2]'`},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			require.Equal(t, []proto.Parameter{{Key: "v", Value: tt.Result}},
+				Parameters(map[string]any{"v": tt.Value}),
+			)
+		})
+	}
+}
+
+func TestQueryParametersTyped(t *testing.T) {
+	conn := Conn(t)
+	SkipNoFeature(t, conn, proto.FeatureParameters)
+	ctx := context.Background()
+	params, err := EncodeParameters("select {s:String} s, {arr:Array(String)} arr", map[string]any{
+		"s":   "it's\t\\",
+		"arr": []string{"'", `\`},
+	})
+	require.NoError(t, err)
+	var (
+		s   proto.ColStr
+		arr = new(proto.ColStr).Array()
+	)
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:       "select {s:String} s, {arr:Array(String)} arr",
+		Parameters: params,
+		Result: proto.Results{
+			{Name: "s", Data: &s},
+			{Name: "arr", Data: arr},
+		},
+	}))
+	require.Equal(t, "it's\t\\", s.Row(0))
+	require.Equal(t, []string{"'", `\`}, arr.Row(0))
+}
@@ -0,0 +1,691 @@
+package ch
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// formatParameter formats v as value of parameter of type t.
+//
+// Value is represented in text (escaped) format of type t and then
+// dumped as String field, like 'value', which is expected by server.
+func formatParameter(t proto.ColumnType, v any) (string, error) {
+	b, err := appendParameter(nil, t, v, false)
+	if err != nil {
+		return "", err
+	}
+	return quoteParameter(string(b)), nil
+}
+
+// quoteParameter returns s as quoted String field.
+func quoteParameter(s string) string {
+	return string(appendEscaped(nil, s, true))
+}
+
+// appendEscaped appends s escaped with backslashes, quoting if requested,
+// like 'it\'s'.
+func appendEscaped(b []byte, s string, quoted bool) []byte {
+	if quoted {
+		b = append(b, '\'')
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b = append(b, '\\', '\\')
+		case '\'':
+			if quoted {
+				b = append(b, '\\', '\'')
+			} else {
+				b = append(b, c)
+			}
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		case 0:
+			b = append(b, '\\', '0')
+		default:
+			b = append(b, c)
+		}
+	}
+	if quoted {
+		b = append(b, '\'')
+	}
+	return b
+}
+
+// indirect dereferences pointers, returning nil for nil pointers.
+func indirect(v any) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.CanInterface() {
+		return v
+	}
+	return rv.Interface()
+}
+
+// typeArgs splits arguments of t, like Map(String, UInt8) -> [String, UInt8].
+func typeArgs(t proto.ColumnType) []string {
+	s := string(t.Elem())
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var (
+		out   []string
+		depth int
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			i = skipQuoted(s, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(out, strings.TrimSpace(s[start:]))
+}
+
+// tupleElemType returns type of tuple element, trimming element name if any,
+// like "name String" -> String.
+func tupleElemType(s string) proto.ColumnType {
+	idx := strings.IndexAny(s, " (")
+	if idx > 0 && s[idx] == ' ' {
+		return proto.ColumnType(strings.TrimSpace(s[idx:]))
+	}
+	return proto.ColumnType(s)
+}
+
+// appendParameter appends text representation of value v of type t.
+//
+// The nested values (i.e. elements of arrays, tuples and maps) are quoted.
+func appendParameter(b []byte, t proto.ColumnType, v any, nested bool) ([]byte, error) {
+	v = indirect(v)
+	switch t.Base() {
+	case proto.ColumnTypeNullable:
+		if v == nil {
+			if nested {
+				return append(b, "NULL"...), nil
+			}
+			return append(b, `\N`...), nil
+		}
+		return appendParameter(b, t.Elem(), v, nested)
+	case proto.ColumnTypeLowCardinality:
+		return appendParameter(b, t.Elem(), v, nested)
+	}
+	if v == nil {
+		return nil, errors.New("nil value for non-Nullable type")
+	}
+	switch base := t.Base(); base {
+	case proto.ColumnTypeInt8, proto.ColumnTypeInt16, proto.ColumnTypeInt32, proto.ColumnTypeInt64,
+		proto.ColumnTypeInt128, proto.ColumnTypeInt256:
+		bits, _ := strconv.Atoi(strings.TrimPrefix(string(base), "Int"))
+		return appendInteger(b, v, bits, true)
+	case proto.ColumnTypeUInt8, proto.ColumnTypeUInt16, proto.ColumnTypeUInt32, proto.ColumnTypeUInt64,
+		proto.ColumnTypeUInt128, proto.ColumnTypeUInt256:
+		bits, _ := strconv.Atoi(strings.TrimPrefix(string(base), "UInt"))
+		return appendInteger(b, v, bits, false)
+	case proto.ColumnTypeFloat32:
+		return appendFloat(b, v, 32)
+	case proto.ColumnTypeFloat64:
+		return appendFloat(b, v, 64)
+	case proto.ColumnTypeBool:
+		x, ok := v.(bool)
+		if !ok {
+			return nil, errors.Errorf("unexpected %T for Bool", v)
+		}
+		return strconv.AppendBool(b, x), nil
+	case proto.ColumnTypeString, "Identifier":
+		s, err := stringParameter(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeFixedString:
+		s, err := stringParameter(v)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(string(t.Elem()))
+		if err != nil {
+			return nil, errors.Wrap(err, "fixed string size")
+		}
+		if len(s) > n {
+			return nil, errors.Errorf("string of %d bytes is too long", len(s))
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeEnum8, proto.ColumnTypeEnum16:
+		s, err := enumParameter(t, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeUUID:
+		s, err := uuidParameter(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeIPv4, proto.ColumnTypeIPv6:
+		s, err := ipParameter(v, base == proto.ColumnTypeIPv4)
+		if err != nil {
+			return nil, err
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeDate, proto.ColumnTypeDate32:
+		s, err := dateParameter(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeDateTime:
+		s, err := dateTimeParameter(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeDateTime64:
+		args := typeArgs(t)
+		if len(args) == 0 {
+			return nil, errors.New("no precision")
+		}
+		p, err := strconv.ParseUint(args[0], 10, 8)
+		if err != nil || !proto.Precision(p).Valid() {
+			return nil, errors.Errorf("invalid precision %q", args[0])
+		}
+		s, err := dateTime64Parameter(v, proto.Precision(p))
+		if err != nil {
+			return nil, err
+		}
+		return appendEscaped(b, s, nested), nil
+	case proto.ColumnTypeDecimal, proto.ColumnTypeDecimal32, proto.ColumnTypeDecimal64,
+		proto.ColumnTypeDecimal128, proto.ColumnTypeDecimal256:
+		args := typeArgs(t)
+		scale := "0"
+		switch {
+		case base == proto.ColumnTypeDecimal && len(args) == 2:
+			scale = args[1]
+		case base != proto.ColumnTypeDecimal && len(args) == 1:
+			scale = args[0]
+		}
+		s, err := strconv.Atoi(scale)
+		if err != nil {
+			return nil, errors.Errorf("invalid scale %q", scale)
+		}
+		return appendDecimal(b, v, s)
+	case proto.ColumnTypeArray:
+		rv := reflect.ValueOf(v)
+		if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+			return nil, errors.Errorf("unexpected %T for Array", v)
+		}
+		b = append(b, '[')
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendParameter(b, t.Elem(), rv.Index(i).Interface(), true); err != nil {
+				return nil, errors.Wrapf(err, "[%d]", i)
+			}
+		}
+		return append(b, ']'), nil
+	case proto.ColumnTypeTuple:
+		args := typeArgs(t)
+		rv := reflect.ValueOf(v)
+		if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+			return nil, errors.Errorf("unexpected %T for Tuple", v)
+		}
+		if rv.Len() != len(args) {
+			return nil, errors.Errorf("got %d elements instead of %d", rv.Len(), len(args))
+		}
+		b = append(b, '(')
+		for i, arg := range args {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendParameter(b, tupleElemType(arg), rv.Index(i).Interface(), true); err != nil {
+				return nil, errors.Wrapf(err, "[%d]", i)
+			}
+		}
+		return append(b, ')'), nil
+	case proto.ColumnTypeMap:
+		args := typeArgs(t)
+		if len(args) != 2 {
+			return nil, errors.Errorf("invalid map type %q", t)
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map {
+			return nil, errors.Errorf("unexpected %T for Map", v)
+		}
+		// Sorting to make output deterministic.
+		type entry struct {
+			key   []byte
+			value reflect.Value
+		}
+		entries := make([]entry, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := appendParameter(nil, proto.ColumnType(args[0]), iter.Key().Interface(), true)
+			if err != nil {
+				return nil, errors.Wrapf(err, "key %v", iter.Key())
+			}
+			entries = append(entries, entry{key: key, value: iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return string(entries[i].key) < string(entries[j].key)
+		})
+		b = append(b, '{')
+		for i, e := range entries {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, e.key...)
+			b = append(b, ':')
+			var err error
+			if b, err = appendParameter(b, proto.ColumnType(args[1]), e.value.Interface(), true); err != nil {
+				return nil, errors.Wrapf(err, "value of %s", e.key)
+			}
+		}
+		return append(b, '}'), nil
+	default:
+		return nil, errors.Errorf("type %q is not supported", t)
+	}
+}
+
+func stringParameter(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	default:
+		return "", errors.Errorf("unexpected %T for string", v)
+	}
+}
+
+// bigFromWords returns integer from little-endian 64-bit words.
+func bigFromWords(signed bool, words ...uint64) *big.Int {
+	x := new(big.Int)
+	for i := len(words) - 1; i >= 0; i-- {
+		x.Lsh(x, 64)
+		x.Or(x, new(big.Int).SetUint64(words[i]))
+	}
+	if signed && words[len(words)-1]>>63 == 1 {
+		x.Sub(x, new(big.Int).Lsh(big.NewInt(1), uint(64*len(words))))
+	}
+	return x
+}
+
+// bigInteger returns integer value of v.
+func bigInteger(v any) (*big.Int, bool) {
+	switch v := v.(type) {
+	case proto.Int128:
+		return bigFromWords(true, v.Low, v.High), true
+	case proto.UInt128:
+		return bigFromWords(false, v.Low, v.High), true
+	case proto.Int256:
+		return bigFromWords(true, v.Low.Low, v.Low.High, v.High.Low, v.High.High), true
+	case proto.UInt256:
+		return bigFromWords(false, v.Low.Low, v.Low.High, v.High.Low, v.High.High), true
+	case big.Int:
+		return &v, true
+	case string:
+		return new(big.Int).SetString(v, 10)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(rv.Uint()), true
+	default:
+		return nil, false
+	}
+}
+
+func appendInteger(b []byte, v any, bits int, signed bool) ([]byte, error) {
+	x, ok := bigInteger(v)
+	if !ok {
+		return nil, errors.Errorf("unexpected %T for integer", v)
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	low := new(big.Int)
+	if signed {
+		limit.Rsh(limit, 1)
+		low.Neg(limit)
+	}
+	if x.Cmp(low) < 0 || x.Cmp(limit) >= 0 {
+		return nil, errors.Errorf("value %s overflows %d bits", x, bits)
+	}
+	return x.Append(b, 10), nil
+}
+
+func appendFloat(b []byte, v any, bits int) ([]byte, error) {
+	var f float64
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f = rv.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f = float64(rv.Uint())
+	default:
+		return nil, errors.Errorf("unexpected %T for float", v)
+	}
+	switch {
+	case math.IsNaN(f):
+		return append(b, "nan"...), nil
+	case math.IsInf(f, 1):
+		return append(b, "inf"...), nil
+	case math.IsInf(f, -1):
+		return append(b, "-inf"...), nil
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, bits), nil
+}
+
+// appendScaled appends x as decimal with scale digits after point.
+func appendScaled(b []byte, x *big.Int, scale int) []byte {
+	if x.Sign() < 0 {
+		b = append(b, '-')
+		x = new(big.Int).Abs(x)
+	}
+	s := x.String()
+	if scale == 0 {
+		return append(b, s...)
+	}
+	if len(s) <= scale {
+		s = strings.Repeat("0", scale-len(s)+1) + s
+	}
+	b = append(b, s[:len(s)-scale]...)
+	b = append(b, '.')
+	return append(b, s[len(s)-scale:]...)
+}
+
+func isDecimalText(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	digits, point := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !point:
+			point = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+func appendDecimal(b []byte, v any, scale int) ([]byte, error) {
+	switch v := v.(type) {
+	case proto.Decimal32:
+		return appendScaled(b, big.NewInt(int64(v)), scale), nil
+	case proto.Decimal64:
+		return appendScaled(b, big.NewInt(int64(v)), scale), nil
+	case proto.Decimal128:
+		return appendScaled(b, bigFromWords(true, v.Low, v.High), scale), nil
+	case proto.Decimal256:
+		return appendScaled(b, bigFromWords(true, v.Low.Low, v.Low.High, v.High.Low, v.High.High), scale), nil
+	case float32, float64:
+		f := reflect.ValueOf(v).Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.Errorf("invalid decimal %v", f)
+		}
+		return strconv.AppendFloat(b, f, 'f', -1, 64), nil
+	case string:
+		if !isDecimalText(v) {
+			return nil, errors.Errorf("invalid decimal %q", v)
+		}
+		return append(b, v...), nil
+	}
+	x, ok := bigInteger(v)
+	if !ok {
+		return nil, errors.Errorf("unexpected %T for decimal", v)
+	}
+	return x.Append(b, 10), nil
+}
+
+func enumParameter(t proto.ColumnType, v any) (string, error) {
+	values := map[int]string{}
+	for _, def := range typeArgs(t) {
+		idx := strings.LastIndexByte(def, '=')
+		if idx < 0 {
+			return "", errors.Errorf("bad enum definition %q", def)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(def[idx+1:]))
+		if err != nil {
+			return "", errors.Errorf("bad enum definition %q", def)
+		}
+		name := strings.TrimSpace(def[:idx])
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "'"), "'")
+		values[n] = strings.ReplaceAll(name, `\'`, `'`)
+	}
+	if s, err := stringParameter(v); err == nil {
+		for _, name := range values {
+			if name == s {
+				return s, nil
+			}
+		}
+		return "", errors.Errorf("unknown enum value %q", s)
+	}
+	x, ok := bigInteger(v)
+	if !ok || !x.IsInt64() {
+		return "", errors.Errorf("unexpected %T for enum", v)
+	}
+	name, ok := values[int(x.Int64())]
+	if !ok {
+		return "", errors.Errorf("unknown enum value %s", x)
+	}
+	return name, nil
+}
+
+func uuidParameter(v any) (string, error) {
+	switch v := v.(type) {
+	case uuid.UUID:
+		return v.String(), nil
+	case [16]byte:
+		return uuid.UUID(v).String(), nil
+	case string:
+		u, err := uuid.Parse(v)
+		if err != nil {
+			return "", errors.Wrap(err, "parse")
+		}
+		return u.String(), nil
+	default:
+		return "", errors.Errorf("unexpected %T for UUID", v)
+	}
+}
+
+func ipParameter(v any, v4 bool) (string, error) {
+	var addr netip.Addr
+	switch v := v.(type) {
+	case proto.IPv4:
+		addr = v.ToIP()
+	case proto.IPv6:
+		addr = v.ToIP()
+	case netip.Addr:
+		addr = v
+	case net.IP:
+		a, ok := netip.AddrFromSlice(v)
+		if !ok {
+			return "", errors.Errorf("invalid ip %v", v)
+		}
+		addr = a
+	case string:
+		a, err := netip.ParseAddr(v)
+		if err != nil {
+			return "", errors.Wrap(err, "parse")
+		}
+		addr = a
+	default:
+		return "", errors.Errorf("unexpected %T for ip", v)
+	}
+	if !addr.IsValid() {
+		return "", errors.New("invalid ip")
+	}
+	if v4 {
+		addr = addr.Unmap()
+		if !addr.Is4() {
+			return "", errors.Errorf("%s is not IPv4", addr)
+		}
+		return addr.String(), nil
+	}
+	return netip.AddrFrom16(addr.As16()).String(), nil
+}
+
+func dateParameter(v any) (string, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format("2006-01-02"), nil
+	case proto.Date:
+		return v.String(), nil
+	case proto.Date32:
+		return v.String(), nil
+	case string:
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return "", errors.Wrap(err, "parse")
+		}
+		return v, nil
+	default:
+		return "", errors.Errorf("unexpected %T for date", v)
+	}
+}
+
+func dateTimeParameter(v any) (string, error) {
+	// Using unix timestamp to be independent of server timezone.
+	switch v := v.(type) {
+	case time.Time:
+		sec := v.Unix()
+		if sec < 0 || sec > math.MaxUint32 {
+			return "", errors.Errorf("%s is out of DateTime range", v)
+		}
+		return strconv.FormatInt(sec, 10), nil
+	case proto.DateTime:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case string:
+		return v, nil
+	default:
+		return "", errors.Errorf("unexpected %T for DateTime", v)
+	}
+}
+
+func dateTime64Parameter(v any, p proto.Precision) (string, error) {
+	// Using unix timestamp to be independent of server timezone.
+	var ticks int64
+	switch v := v.(type) {
+	case time.Time:
+		ticks = int64(proto.ToDateTime64(v, p))
+	case proto.DateTime64:
+		ticks = int64(v)
+	case string:
+		return v, nil
+	default:
+		return "", errors.Errorf("unexpected %T for DateTime64", v)
+	}
+	return string(appendScaled(nil, big.NewInt(ticks), int(p))), nil
+}
+
+// inferParameterTypeOf returns ClickHouse type for Go type.
+func inferParameterTypeOf(t reflect.Type) (proto.ColumnType, bool) {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return proto.ColumnTypeDateTime64.With("9"), true
+	case reflect.TypeOf(uuid.UUID{}):
+		return proto.ColumnTypeUUID, true
+	case reflect.TypeOf(netip.Addr{}):
+		return proto.ColumnTypeIPv6, true
+	case reflect.TypeOf(proto.IPv4(0)):
+		return proto.ColumnTypeIPv4, true
+	case reflect.TypeOf(proto.IPv6{}):
+		return proto.ColumnTypeIPv6, true
+	case reflect.TypeOf(proto.Date(0)):
+		return proto.ColumnTypeDate, true
+	case reflect.TypeOf(proto.Date32(0)):
+		return proto.ColumnTypeDate32, true
+	case reflect.TypeOf(proto.Int128{}):
+		return proto.ColumnTypeInt128, true
+	case reflect.TypeOf(proto.UInt128{}):
+		return proto.ColumnTypeUInt128, true
+	case reflect.TypeOf(proto.Int256{}):
+		return proto.ColumnTypeInt256, true
+	case reflect.TypeOf(proto.UInt256{}):
+		return proto.ColumnTypeUInt256, true
+	case reflect.TypeOf([]byte(nil)):
+		return proto.ColumnTypeString, true
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return proto.ColumnTypeBool, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return proto.ColumnTypeInt64, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return proto.ColumnTypeUInt64, true
+	case reflect.Float32, reflect.Float64:
+		return proto.ColumnTypeFloat64, true
+	case reflect.String:
+		return proto.ColumnTypeString, true
+	case reflect.Pointer:
+		elem, ok := inferParameterTypeOf(t.Elem())
+		if !ok {
+			return "", false
+		}
+		return proto.ColumnTypeNullable.Sub(elem), true
+	case reflect.Slice, reflect.Array:
+		elem, ok := inferParameterTypeOf(t.Elem())
+		if !ok {
+			return "", false
+		}
+		return elem.Array(), true
+	case reflect.Map:
+		key, ok := inferParameterTypeOf(t.Key())
+		if !ok {
+			return "", false
+		}
+		value, ok := inferParameterTypeOf(t.Elem())
+		if !ok {
+			return "", false
+		}
+		return proto.ColumnTypeMap.Sub(key, value), true
+	default:
+		return "", false
+	}
+}
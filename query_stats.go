@@ -0,0 +1,107 @@
+package ch
+
+import (
+	"time"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// Names of profile events that are exposed as QueryStats fields.
+const (
+	ProfileEventSelectedParts          = "SelectedParts"
+	ProfileEventSelectedRanges         = "SelectedRanges"
+	ProfileEventSelectedMarks          = "SelectedMarks"
+	ProfileEventReadCompressedBytes    = "ReadCompressedBytes"
+	ProfileEventMemoryTrackerPeakUsage = "MemoryTrackerPeakUsage"
+)
+
+// QueryStats is summary of query execution, aggregated from all
+// progress, profile and profile events packets of query.
+//
+// See Query.Stats.
+type QueryStats struct {
+	// Rows and bytes read by server.
+	ReadRows  uint64
+	ReadBytes uint64
+	// Rows and bytes written by server, e.g. on INSERT.
+	WrittenRows  uint64
+	WrittenBytes uint64
+	// TotalRowsToRead is estimated total rows to read, if known.
+	TotalRowsToRead uint64
+	// Elapsed is server-side execution time, if supported by server.
+	Elapsed time.Duration
+
+	// Rows, bytes and blocks of query result.
+	ResultRows   uint64
+	ResultBytes  uint64
+	ResultBlocks uint64
+	// AppliedLimit reports whether LIMIT was applied, and RowsBeforeLimit
+	// is count of rows before LIMIT if calculated.
+	AppliedLimit    bool
+	RowsBeforeLimit uint64
+	// AppliedAggregation reports whether aggregation was applied, and
+	// RowsBeforeAggregation is count of rows before it.
+	AppliedAggregation    bool
+	RowsBeforeAggregation uint64
+
+	// Selected profile events.
+	SelectedParts       int64
+	SelectedRanges      int64
+	SelectedMarks       int64
+	ReadCompressedBytes int64
+	// MemoryPeak is peak memory usage of query in bytes.
+	MemoryPeak int64
+
+	// ProfileEvents are all increment profile events, summed by name.
+	ProfileEvents map[string]int64
+}
+
+func (s *QueryStats) addProgress(p proto.Progress) {
+	s.ReadRows += p.Rows
+	s.ReadBytes += p.Bytes
+	s.WrittenRows += p.WroteRows
+	s.WrittenBytes += p.WroteBytes
+	s.TotalRowsToRead += p.TotalRows
+	s.Elapsed += time.Duration(p.ElapsedNs)
+}
+
+func (s *QueryStats) setProfile(p proto.Profile) {
+	// Same as clickhouse-client, using last profile info.
+	s.ResultRows = p.Rows
+	s.ResultBytes = p.Bytes
+	s.ResultBlocks = p.Blocks
+	s.AppliedLimit = p.AppliedLimit
+	s.RowsBeforeLimit = p.RowsBeforeLimit
+	s.AppliedAggregation = p.AppliedAggregation
+	s.RowsBeforeAggregation = p.RowsBeforeAggregation
+}
+
+func (s *QueryStats) addProfileEvents(events []ProfileEvent) {
+	for _, e := range events {
+		if e.ThreadID != 0 {
+			// Same as clickhouse-client, using only rows of thread group,
+			// which already include values of per-thread rows.
+			continue
+		}
+		if e.Type == proto.ProfileGauge {
+			if e.Name == ProfileEventMemoryTrackerPeakUsage && e.Value > s.MemoryPeak {
+				s.MemoryPeak = e.Value
+			}
+			continue
+		}
+		if s.ProfileEvents == nil {
+			s.ProfileEvents = make(map[string]int64)
+		}
+		s.ProfileEvents[e.Name] += e.Value
+		switch e.Name {
+		case ProfileEventSelectedParts:
+			s.SelectedParts += e.Value
+		case ProfileEventSelectedRanges:
+			s.SelectedRanges += e.Value
+		case ProfileEventSelectedMarks:
+			s.SelectedMarks += e.Value
+		case ProfileEventReadCompressedBytes:
+			s.ReadCompressedBytes += e.Value
+		}
+	}
+}
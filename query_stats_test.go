@@ -0,0 +1,66 @@
+package ch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func TestQueryStats(t *testing.T) {
+	var s QueryStats
+	s.addProgress(proto.Progress{Rows: 10, Bytes: 80, TotalRows: 100, ElapsedNs: 1000})
+	s.addProgress(proto.Progress{Rows: 5, Bytes: 40, ElapsedNs: 500})
+	s.addProgress(proto.Progress{WroteRows: 3, WroteBytes: 24})
+	s.setProfile(proto.Profile{Rows: 1, Bytes: 8, Blocks: 1})
+	s.setProfile(proto.Profile{Rows: 2, Bytes: 16, Blocks: 1, AppliedLimit: true, RowsBeforeLimit: 15})
+	s.addProfileEvents([]ProfileEvent{
+		{Type: proto.ProfileIncrement, Name: ProfileEventSelectedParts, Value: 2},
+		{Type: proto.ProfileIncrement, Name: ProfileEventSelectedMarks, Value: 4},
+		{Type: proto.ProfileIncrement, Name: ProfileEventReadCompressedBytes, Value: 100},
+		{Type: proto.ProfileGauge, Name: ProfileEventMemoryTrackerPeakUsage, Value: 4096},
+		{Type: proto.ProfileGauge, Name: "MemoryTrackerUsage", Value: 1024},
+	})
+	s.addProfileEvents([]ProfileEvent{
+		{Type: proto.ProfileIncrement, Name: ProfileEventSelectedParts, Value: 1},
+		{Type: proto.ProfileIncrement, Name: ProfileEventSelectedRanges, Value: 3},
+		{Type: proto.ProfileGauge, Name: ProfileEventMemoryTrackerPeakUsage, Value: 2048},
+	})
+	// Per-thread rows are included in thread group row.
+	s.addProfileEvents([]ProfileEvent{
+		{ThreadID: 42, Type: proto.ProfileIncrement, Name: ProfileEventSelectedMarks, Value: 6},
+		{ThreadID: 0, Type: proto.ProfileIncrement, Name: ProfileEventSelectedMarks, Value: 10},
+		{ThreadID: 43, Type: proto.ProfileIncrement, Name: ProfileEventSelectedMarks, Value: 4},
+		{ThreadID: 43, Type: proto.ProfileGauge, Name: ProfileEventMemoryTrackerPeakUsage, Value: 8192},
+	})
+
+	require.Equal(t, QueryStats{
+		ReadRows:        15,
+		ReadBytes:       120,
+		WrittenRows:     3,
+		WrittenBytes:    24,
+		TotalRowsToRead: 100,
+		Elapsed:         1500 * time.Nanosecond,
+
+		ResultRows:      2,
+		ResultBytes:     16,
+		ResultBlocks:    1,
+		AppliedLimit:    true,
+		RowsBeforeLimit: 15,
+
+		SelectedParts:       3,
+		SelectedRanges:      3,
+		SelectedMarks:       14,
+		ReadCompressedBytes: 100,
+		MemoryPeak:          4096,
+
+		ProfileEvents: map[string]int64{
+			ProfileEventSelectedParts:       3,
+			ProfileEventSelectedRanges:      3,
+			ProfileEventSelectedMarks:       14,
+			ProfileEventReadCompressedBytes: 100,
+		},
+	}, s)
+}
@@ -3,6 +3,7 @@ package ch
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math/rand"
@@ -43,6 +44,142 @@ func TestDateTimeOverflow(t *testing.T) {
 	require.Equal(t, "2061-02-01 00:00:00", data.Row(0).Format("2006-01-02 15:04:05"))
 }
 
+func TestSessionTimezone(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := Conn(t)
+	SkipNoFeature(t, conn, proto.FeatureTimezoneUpdates)
+
+	var (
+		data     proto.ColDateTime
+		explicit proto.ColDateTime
+	)
+	query := Query{
+		Body: "SELECT toDateTime('2023-02-01 10:00:00') as v, toDateTime('2023-02-01 10:00:00', 'UTC') as e " +
+			"SETTINGS session_timezone = 'Asia/Tokyo'",
+		Result: proto.Results{
+			{Name: "v", Data: &data},
+			{Name: "e", Data: &explicit},
+		},
+	}
+	require.NoError(t, conn.Do(ctx, query))
+	require.Equal(t, 1, data.Rows())
+	require.Equal(t, "Asia/Tokyo", data.Location.String())
+	require.Equal(t, "2023-02-01 10:00:00", data.Row(0).Format("2006-01-02 15:04:05"))
+	require.Equal(t, "UTC", explicit.Location.String())
+}
+
+func TestSessionTimezoneReuse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := Conn(t)
+	SkipNoFeature(t, conn, proto.FeatureTimezoneUpdates)
+
+	var (
+		dt   proto.ColDateTime
+		dt64 proto.ColDateTime64
+	)
+	for _, tt := range []struct {
+		Name     string
+		Type     string
+		Data     proto.ColResult
+		Location func() *time.Location
+	}{
+		{Name: "DateTime", Type: "DateTime", Data: &dt, Location: func() *time.Location { return dt.Location }},
+		{Name: "DateTime64", Type: "DateTime64(3)", Data: &dt64, Location: func() *time.Location { return dt64.Location }},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			// Same result column is reused across queries.
+			do := func(settings string) {
+				tt.Data.Reset()
+				require.NoError(t, conn.Do(ctx, Query{
+					Body: fmt.Sprintf("SELECT CAST(0 as %s) as v %s", tt.Type, settings),
+					Result: proto.Results{
+						{Name: "v", Data: tt.Data},
+					},
+				}))
+			}
+
+			do("SETTINGS session_timezone = 'Asia/Tokyo'")
+			require.Equal(t, "Asia/Tokyo", tt.Location().String())
+
+			// No TimezoneUpdate, location of previous query is not kept.
+			do("")
+			require.Nil(t, tt.Location())
+		})
+	}
+}
+
+func TestClient_PartUUIDs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := Conn(t)
+
+	require.NoError(t, conn.Do(ctx, Query{
+		Body: "CREATE TABLE test_part_uuids (v UInt8) ENGINE = MergeTree ORDER BY v " +
+			"SETTINGS assign_part_uuids = 1",
+	}))
+	v := proto.ColUInt8{1, 2, 3}
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:  "INSERT INTO test_part_uuids VALUES",
+		Input: proto.Input{{Name: "v", Data: &v}},
+	}))
+
+	var (
+		data proto.ColUInt8
+		got  []uuid.UUID
+	)
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:   "SELECT v FROM test_part_uuids SETTINGS allow_experimental_query_deduplication = 1",
+		Result: proto.Results{{Name: "v", Data: &data}},
+		OnPartUUIDs: func(ctx context.Context, uuids []uuid.UUID) error {
+			got = append(got, uuids...)
+			return nil
+		},
+	}))
+	require.Equal(t, 3, data.Rows())
+	for _, u := range got {
+		require.NotEqual(t, uuid.Nil, u)
+	}
+}
+
+func TestClient_Do_Stats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := Conn(t)
+	SkipNoFeature(t, conn, proto.FeatureProfileEvents)
+
+	var (
+		data  proto.ColUInt64
+		stats QueryStats
+	)
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:   "SELECT number FROM system.numbers LIMIT 10",
+		Result: proto.Results{{Name: "number", Data: &data}},
+		Stats:  &stats,
+	}))
+	require.Equal(t, 10, data.Rows())
+	require.NotZero(t, stats.ReadRows)
+	require.NotZero(t, stats.ReadBytes)
+	require.Equal(t, uint64(10), stats.ResultRows)
+	require.True(t, stats.AppliedLimit)
+	require.NotZero(t, stats.MemoryPeak)
+	require.NotEmpty(t, stats.ProfileEvents)
+
+	// Stats are reset on every query.
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:  "SELECT 1",
+		Stats: &stats,
+		OnResult: func(ctx context.Context, block proto.Block) error {
+			return nil
+		},
+		Result: discardResult(),
+	}))
+	require.Equal(t, uint64(1), stats.ResultRows)
+	require.False(t, stats.AppliedLimit)
+}
+
 func TestProtoVersion(t *testing.T) {
 	t.Skip("Long")
 	t.Parallel()
@@ -1122,6 +1259,91 @@ func TestClient_ExternalData(t *testing.T) {
 		require.NoError(t, Conn(t).Do(ctx, selectStr))
 		require.Equal(t, 3, data.Rows())
 	})
+	t.Run("Multiple", func(t *testing.T) {
+		t.Parallel()
+		var data proto.ColInt64
+		selectStr := Query{
+			Body: "SELECT a.v + b.v AS v FROM a INNER JOIN b ON a.k = b.k ORDER BY v",
+			ExternalTables: []ExternalTable{
+				{
+					Name: "a",
+					Data: []proto.InputColumn{
+						{Name: "k", Data: proto.ColInt64{1, 2}},
+						{Name: "v", Data: proto.ColInt64{1, 2}},
+					},
+				},
+				{
+					Name: "b",
+					Data: []proto.InputColumn{
+						{Name: "k", Data: proto.ColInt64{1, 2}},
+						{Name: "v", Data: proto.ColInt64{10, 20}},
+					},
+				},
+			},
+			Result: proto.Results{
+				{Name: "v", Data: &data},
+			},
+		}
+		require.NoError(t, Conn(t).Do(ctx, selectStr))
+		require.Equal(t, proto.ColInt64{11, 22}, data)
+	})
+	t.Run("Stream", func(t *testing.T) {
+		t.Parallel()
+		var (
+			data   proto.ColInt64
+			values proto.ColInt64
+			blocks int
+		)
+		selectStr := Query{
+			Body: "SELECT sum(v) AS v FROM stream",
+			ExternalTables: []ExternalTable{
+				{
+					Name: "stream",
+					Data: []proto.InputColumn{
+						{Name: "v", Data: &values},
+					},
+					OnInput: func(ctx context.Context) error {
+						values.Reset()
+						if blocks == 5 {
+							return io.EOF
+						}
+						blocks++
+						for i := 0; i < 10; i++ {
+							values.Append(1)
+						}
+						return nil
+					},
+				},
+			},
+			Result: proto.Results{
+				{Name: "v", Data: &data},
+			},
+		}
+		require.NoError(t, Conn(t).Do(ctx, selectStr))
+		require.Equal(t, proto.ColInt64{50}, data)
+	})
+}
+
+func TestClient_StageWithMergeableState(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var sum, count proto.ColAggregateFunction
+	require.NoError(t, Conn(t).Do(ctx, Query{
+		Body:  "SELECT sum(number), count() FROM numbers(10)",
+		Stage: StageWithMergeableState,
+		Result: proto.Results{
+			{Data: &sum},
+			{Data: &count},
+		},
+	}))
+	require.Equal(t, 1, sum.Rows())
+	require.Equal(t, proto.ColumnTypeUInt64, sum.ValueType())
+	s, err := sum.State(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(45), binary.LittleEndian.Uint64(s.Value))
+	c, err := count.State(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), c.Count)
 }
 
 func TestClient_ServerProfile(t *testing.T) {
@@ -1399,6 +1621,136 @@ func TestClientInsert(t *testing.T) {
 	}))
 }
 
+func TestClientInsert_MaxBlock(t *testing.T) {
+	ctx := context.Background()
+	conn := Conn(t)
+	require.NoError(t, conn.Do(ctx, Query{
+		Body: `CREATE TABLE test_table
+(
+    id    UInt64,
+    kind  LowCardinality(String),
+    tags  Array(LowCardinality(String)),
+    attrs Map(String, UInt64)
+) ENGINE = Memory`,
+	}))
+
+	const rows = 1000
+	var (
+		id    proto.ColUInt64
+		kind  = new(proto.ColStr).LowCardinality()
+		tags  = new(proto.ColStr).LowCardinality().Array()
+		attrs = proto.NewMap[string, uint64](new(proto.ColStr), new(proto.ColUInt64))
+	)
+	for i := 0; i < rows; i++ {
+		id.Append(uint64(i))
+		kind.Append(fmt.Sprintf("kind%d", i%10))
+		tags.Append([]string{"a", fmt.Sprintf("tag%d", i%7)})
+		attrs.Append(map[string]uint64{"v": uint64(i)})
+	}
+	require.NoError(t, conn.Do(ctx, Query{
+		Body: "INSERT INTO test_table VALUES",
+		Input: proto.Input{
+			{Name: "id", Data: id},
+			{Name: "kind", Data: kind},
+			{Name: "tags", Data: tags},
+			{Name: "attrs", Data: attrs},
+		},
+		MaxBlockRows:  300,
+		MaxBlockBytes: 4096,
+	}))
+
+	var (
+		count proto.ColUInt64
+		diff  proto.ColUInt64
+	)
+	require.NoError(t, conn.Do(ctx, Query{
+		Body: `SELECT count() AS count, countIf(
+    kind != concat('kind', toString(id % 10))
+    OR tags != ['a', concat('tag', toString(id % 7))]
+    OR attrs['v'] != id
+) AS diff FROM test_table`,
+		Result: proto.Results{
+			{Name: "count", Data: &count},
+			{Name: "diff", Data: &diff},
+		},
+	}))
+	require.Equal(t, uint64(rows), count.Row(0))
+	require.Zero(t, diff.Row(0), "rows should be inserted correctly")
+}
+
+func TestClientInsert_ValidateInput(t *testing.T) {
+	ctx := context.Background()
+	connect := func(t *testing.T) *Client {
+		conn := Conn(t)
+		require.NoError(t, conn.Do(ctx, Query{
+			Body: `CREATE TABLE test_table
+(
+    id    UInt64,
+    level Enum8('info' = 1, 'error' = 2),
+    ts    DateTime64(3)
+) ENGINE = Memory`,
+		}))
+		return conn
+	}
+
+	var (
+		id    proto.ColUInt64
+		level proto.ColEnum
+		ts    = new(proto.ColDateTime64).WithPrecision(proto.PrecisionMilli)
+		now   = time.Date(2010, 1, 1, 10, 22, 33, 0, time.UTC)
+	)
+	for i, v := range []struct {
+		Level string
+		TS    time.Time
+	}{
+		{"info", now},
+		{"debug", now},
+		{"error", now.AddDate(300, 0, 0)},
+		{"error", now},
+	} {
+		id.Append(uint64(i))
+		level.Append(v.Level)
+		ts.Append(v.TS)
+	}
+	input := proto.Input{
+		{Name: "id", Data: id},
+		{Name: "level", Data: &level},
+		{Name: "ts", Data: ts},
+	}
+
+	// Failing without handler.
+	var verr *ValidationError
+	require.ErrorAs(t, connect(t).Do(ctx, Query{
+		Body:          input.Into("test_table"),
+		Input:         input,
+		ValidateInput: true,
+	}), &verr)
+	require.Equal(t, []int{1, 2}, verr.InvalidRows())
+
+	// Skipping invalid rows.
+	conn := connect(t)
+	var invalid []uint64
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:          input.Into("test_table"),
+		Input:         input,
+		ValidateInput: true,
+		OnInvalidInput: func(ctx context.Context, err *ValidationError) error {
+			for _, row := range err.InvalidRows() {
+				invalid = append(invalid, id.Row(row))
+			}
+			return nil
+		},
+	}))
+	require.Equal(t, []uint64{1, 2}, invalid)
+
+	var got proto.ColUInt64
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:   "SELECT id FROM test_table ORDER BY id",
+		Result: proto.Results{{Name: "id", Data: &got}},
+	}))
+	require.Equal(t, proto.ColUInt64{0, 3}, got)
+}
+
 func TestClientQueryCancellation(t *testing.T) {
 	ctx := context.Background()
 	server := cht.New(t)
@@ -1432,3 +1784,11 @@ func TestClientQueryCancellation(t *testing.T) {
 	// Connection should be closed after query cancellation.
 	require.True(t, c.IsClosed())
 }
+
+func TestStage_protoStage(t *testing.T) {
+	var zero Stage
+	require.Equal(t, proto.StageComplete, zero.protoStage())
+	require.Equal(t, proto.StageComplete, StageComplete.protoStage())
+	require.Equal(t, proto.StageFetchColumns, StageFetchColumns.protoStage())
+	require.Equal(t, proto.StageWithMergeableState, StageWithMergeableState.protoStage())
+}
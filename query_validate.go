@@ -0,0 +1,162 @@
+package ch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-faster/errors"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// InputError means that value of Input is invalid.
+type InputError struct {
+	// Row index in Input block, -1 if whole column is invalid, e.g. due
+	// to type mismatch.
+	Row    int
+	Column string
+	Type   proto.ColumnType // type of column in table, blank if not found
+	Err    error
+}
+
+func (e *InputError) Error() string {
+	col := fmt.Sprintf("column %q", e.Column)
+	if e.Type != "" {
+		col = fmt.Sprintf("column %q (%s)", e.Column, e.Type)
+	}
+	if e.Row < 0 {
+		return fmt.Sprintf("%s: %s", col, e.Err)
+	}
+	return fmt.Sprintf("row %d: %s: %s", e.Row, col, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError means that Input block does not match table schema.
+//
+// See Query.ValidateInput.
+type ValidationError struct {
+	// Rows is count of rows in Input block.
+	Rows int
+	// Errors ordered by row, column errors go first.
+	Errors []InputError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("invalid input: %s", &e.Errors[0])
+	}
+	return fmt.Sprintf("invalid input: %s (and %d more)", &e.Errors[0], len(e.Errors)-1)
+}
+
+// InvalidRows returns sorted indexes of invalid rows. All rows are invalid
+// if some column is invalid.
+func (e *ValidationError) InvalidRows() []int {
+	var rows []int
+	for _, v := range e.Errors {
+		if v.Row < 0 {
+			rows = rows[:0]
+			for i := 0; i < e.Rows; i++ {
+				rows = append(rows, i)
+			}
+			return rows
+		}
+		if n := len(rows); n > 0 && rows[n-1] == v.Row {
+			continue
+		}
+		rows = append(rows, v.Row)
+	}
+	return rows
+}
+
+// rowRange is range [Start, End) of Input rows.
+type rowRange struct {
+	Start int
+	End   int
+}
+
+// validRanges returns ranges of valid rows.
+func (e *ValidationError) validRanges() []rowRange {
+	var (
+		ranges []rowRange
+		start  int
+	)
+	for _, row := range e.InvalidRows() {
+		if row > start {
+			ranges = append(ranges, rowRange{Start: start, End: row})
+		}
+		start = row + 1
+	}
+	if start < e.Rows {
+		ranges = append(ranges, rowRange{Start: start, End: e.Rows})
+	}
+	return ranges
+}
+
+// validateInput validates input block against column types of table,
+// returning nil if input is valid.
+func validateInput(info proto.ColInfoInput, input proto.Input) *ValidationError {
+	types := make(map[string]proto.ColumnType, len(info))
+	for _, v := range info {
+		types[v.Name] = v.Type
+	}
+	var errs []InputError
+	for _, col := range input {
+		t, ok := types[col.Name]
+		if !ok {
+			errs = append(errs, InputError{
+				Row:    -1,
+				Column: col.Name,
+				Err:    errors.New("not found in table"),
+			})
+			continue
+		}
+		if err := proto.ValidateColumn(col.Data, t, func(row int, err error) {
+			errs = append(errs, InputError{
+				Row:    row,
+				Column: col.Name,
+				Type:   t,
+				Err:    err,
+			})
+		}); err != nil {
+			errs = append(errs, InputError{
+				Row:    -1,
+				Column: col.Name,
+				Type:   t,
+				Err:    err,
+			})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Row < errs[j].Row
+	})
+	return &ValidationError{
+		Rows:   input[0].Data.Rows(),
+		Errors: errs,
+	}
+}
+
+// inputValidator returns function that validates Input blocks of query
+// against column types of table, returning ranges of rows to send.
+func (q Query) inputValidator(info proto.ColInfoInput) func(ctx context.Context, input proto.Input) ([]rowRange, error) {
+	return func(ctx context.Context, input proto.Input) ([]rowRange, error) {
+		verr := validateInput(info, input)
+		if verr == nil {
+			return []rowRange{{End: input[0].Data.Rows()}}, nil
+		}
+		if q.OnInvalidInput == nil {
+			return nil, verr
+		}
+		if err := q.OnInvalidInput(ctx, verr); err != nil {
+			return nil, errors.Wrap(err, "invalid input")
+		}
+		// Skipping invalid rows.
+		return verr.validRanges(), nil
+	}
+}
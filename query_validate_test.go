@@ -0,0 +1,147 @@
+package ch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func validateTestInput() (proto.ColInfoInput, proto.Input) {
+	info := proto.ColInfoInput{
+		{Name: "id", Type: proto.ColumnTypeUInt64},
+		{Name: "code", Type: "FixedString(2)"},
+		{Name: "level", Type: "Enum8('info' = 1, 'error' = 2)"},
+	}
+	var (
+		id    proto.ColUInt64
+		code  proto.ColStr
+		level proto.ColStr
+	)
+	for _, v := range []struct {
+		Code  string
+		Level string
+	}{
+		{"ok", "info"},
+		{"bad", "info"},
+		{"ok", "error"},
+		{"bad", "debug"},
+		{"ok", "warn"},
+		{"ok", "info"},
+	} {
+		id.Append(uint64(id.Rows()))
+		code.Append(v.Code)
+		level.Append(v.Level)
+	}
+	return info, proto.Input{
+		{Name: "id", Data: id},
+		{Name: "code", Data: code},
+		{Name: "level", Data: level},
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	info, input := validateTestInput()
+	require.Nil(t, validateInput(info, input[:1]))
+
+	err := validateInput(info, input)
+	require.NotNil(t, err)
+	require.Equal(t, 6, err.Rows)
+	require.Equal(t, []int{1, 3, 4}, err.InvalidRows())
+	require.Equal(t, []rowRange{{0, 1}, {2, 3}, {5, 6}}, err.validRanges())
+
+	var got []string
+	for _, e := range err.Errors {
+		got = append(got, e.Error())
+	}
+	require.Equal(t, []string{
+		`row 1: column "code" (FixedString(2)): value of 3 bytes is too long for FixedString(2)`,
+		`row 3: column "code" (FixedString(2)): value of 3 bytes is too long for FixedString(2)`,
+		`row 3: column "level" (Enum8('info' = 1, 'error' = 2)): unknown value "debug" of Enum8('info' = 1, 'error' = 2)`,
+		`row 4: column "level" (Enum8('info' = 1, 'error' = 2)): unknown value "warn" of Enum8('info' = 1, 'error' = 2)`,
+	}, got)
+	require.Equal(t, "invalid input: "+got[0]+" (and 3 more)", err.Error())
+
+	t.Run("Column", func(t *testing.T) {
+		var id proto.ColStr
+		id.AppendArr(make([]string, 6))
+		input := append(proto.Input{
+			{Name: "extra", Data: make(proto.ColInt8, 6)},
+			{Name: "id", Data: id},
+		}, input[1:]...)
+		err := validateInput(info, input)
+		require.NotNil(t, err)
+		require.Equal(t, `column "extra": not found in table`, err.Errors[0].Error())
+		require.Equal(t, `column "id" (UInt64): String conflicts with UInt64`, err.Errors[1].Error())
+		require.Equal(t, []int{0, 1, 2, 3, 4, 5}, err.InvalidRows())
+		require.Empty(t, err.validRanges())
+	})
+}
+
+func TestClient_encodeInput_Validate(t *testing.T) {
+	ctx := context.Background()
+	info, input := validateTestInput()
+	decodeIDs := func(t *testing.T, c *Client, conn *bufConn) [][]uint64 {
+		var blocks [][]uint64
+		decodeInputBlocks(t, c, conn, func() proto.Results {
+			return proto.Results{
+				{Name: "id", Data: new(proto.ColUInt64)},
+				{Name: "code", Data: new(proto.ColStr)},
+				{Name: "level", Data: new(proto.ColStr)},
+			}
+		}, func(b proto.Block, r proto.Results) {
+			blocks = append(blocks, *r[0].Data.(*proto.ColUInt64))
+		})
+		return blocks
+	}
+	t.Run("Skip", func(t *testing.T) {
+		var invalid []int
+		q := Query{
+			OnInvalidInput: func(ctx context.Context, err *ValidationError) error {
+				invalid = err.InvalidRows()
+				return nil
+			},
+		}
+		c, conn := newInputClient(t)
+		require.NoError(t, c.encodeInput(ctx, "", input, inputOptions{
+			Validate: q.inputValidator(info),
+		}))
+		require.Equal(t, []int{1, 3, 4}, invalid)
+		require.Equal(t, [][]uint64{{0}, {2}, {5}}, decodeIDs(t, c, conn))
+	})
+	t.Run("Valid", func(t *testing.T) {
+		c, conn := newInputClient(t)
+		require.NoError(t, c.encodeInput(ctx, "", input[:1], inputOptions{
+			Validate: Query{}.inputValidator(info),
+		}))
+		var rows int
+		decodeInputBlocks(t, c, conn, func() proto.Results {
+			return proto.Results{{Name: "id", Data: new(proto.ColUInt64)}}
+		}, func(b proto.Block, r proto.Results) {
+			rows += b.Rows
+		})
+		require.Equal(t, 6, rows)
+	})
+	t.Run("Fail", func(t *testing.T) {
+		c, conn := newInputClient(t)
+		err := c.encodeInput(ctx, "", input, inputOptions{
+			Validate: Query{}.inputValidator(info),
+		})
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		require.Len(t, verr.Errors, 4)
+		require.Empty(t, decodeIDs(t, c, conn), "nothing should be sent")
+
+		testErr := errors.New("test")
+		q := Query{
+			OnInvalidInput: func(ctx context.Context, err *ValidationError) error {
+				return testErr
+			},
+		}
+		err = c.encodeInput(ctx, "", input, inputOptions{Validate: q.inputValidator(info)})
+		require.ErrorIs(t, err, testErr)
+	})
+}
@@ -2,37 +2,68 @@ package ch
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"log/slog"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-faster/errors"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/ClickHouse/ch-go/compress"
+	"github.com/ClickHouse/ch-go/internal/zslog"
 	"github.com/ClickHouse/ch-go/proto"
 )
 
 // Server is basic ClickHouse server.
 type Server struct {
-	lg    *zap.Logger
-	tz    *time.Location
-	conn  atomic.Uint64
-	ver   int
-	onErr func(err error)
+	lg      *zap.Logger
+	tz      *time.Location
+	conn    atomic.Uint64
+	ver     int
+	onErr   func(err error)
+	sshKeys map[string]ssh.PublicKey
+	auth    func(c Credentials) error
+
+	chunkedSend string
+	chunkedRecv string
 }
 
 // ServerOptions wraps possible Server configuration.
 type ServerOptions struct {
-	Logger   *zap.Logger
+	Logger *zap.Logger
+	// LogHandler is used for logging if Logger is not set.
+	LogHandler slog.Handler
+
 	Timezone *time.Location
 	OnError  func(err error)
+	// SSHKeys are public keys of users that are allowed to authenticate
+	// with SSH key.
+	SSHKeys map[string]ssh.PublicKey
+	// Authenticate checks credentials of password or JWT authentication,
+	// all credentials are accepted if not set.
+	Authenticate func(c Credentials) error
+	// ChunkedSend and ChunkedRecv are chunked packets framing settings
+	// that are advertised to clients, like proto.ChunkedOptional.
+	//
+	// Default is proto.NotChunkedOptional, so classic stream is used
+	// unless client requires chunked framing.
+	ChunkedSend string
+	ChunkedRecv string
 }
 
 // NewServer returns new ClickHouse Server.
 func NewServer(opt ServerOptions) *Server {
+	if opt.Logger == nil && opt.LogHandler != nil {
+		opt.Logger = zslog.New(opt.LogHandler)
+	}
 	if opt.Logger == nil {
 		opt.Logger = zap.NewNop()
 	}
@@ -42,11 +73,22 @@ func NewServer(opt ServerOptions) *Server {
 	if opt.OnError == nil {
 		opt.OnError = func(err error) {}
 	}
+	if opt.ChunkedSend == "" {
+		opt.ChunkedSend = proto.NotChunkedOptional
+	}
+	if opt.ChunkedRecv == "" {
+		opt.ChunkedRecv = proto.NotChunkedOptional
+	}
 	return &Server{
-		lg:    opt.Logger,
-		tz:    opt.Timezone,
-		ver:   proto.Version,
-		onErr: opt.OnError,
+		lg:      opt.Logger,
+		tz:      opt.Timezone,
+		ver:     proto.Version,
+		onErr:   opt.OnError,
+		sshKeys: opt.SSHKeys,
+		auth:    opt.Authenticate,
+
+		chunkedSend: opt.ChunkedSend,
+		chunkedRecv: opt.ChunkedRecv,
 	}
 }
 
@@ -61,6 +103,13 @@ type ServerConn struct {
 	info   proto.ServerHello
 	ver    int
 
+	addendum proto.Addendum
+	sshKeys  map[string]ssh.PublicKey
+	auth     func(c Credentials) error
+
+	// chunked reports whether chunked framing is used for sending.
+	chunked bool
+
 	// compressor performs block compression,
 	// see encodeBlock.
 	compressor *compress.Writer
@@ -99,11 +148,54 @@ func (c *ServerConn) handshake() error {
 	if err := c.client.Decode(c.reader); err != nil {
 		return errors.Wrap(err, "decode hello")
 	}
-	c.ver = c.client.ProtocolVersion
+	if user, ok := strings.CutPrefix(c.client.User, proto.SSHKeyAuthMarker); ok {
+		if err := c.sshAuth(user); err != nil {
+			return errors.Wrap(err, "ssh auth")
+		}
+	} else if c.auth != nil {
+		creds := Credentials{
+			User:     c.client.User,
+			Password: c.client.Password,
+		}
+		if creds.User == proto.JWTAuthMarker {
+			creds = Credentials{JWT: c.client.Password}
+		}
+		if err := c.auth(creds); err != nil {
+			return c.authFailed(creds.User, err)
+		}
+	}
+	if c.client.ProtocolVersion < c.ver {
+		// Downgrade to client version.
+		c.ver = c.client.ProtocolVersion
+	}
 	c.info.EncodeAware(c.buf, c.ver)
 	if err := c.flush(); err != nil {
 		return errors.Wrap(err, "flush")
 	}
+	if proto.FeatureAddendum.In(c.ver) {
+		if err := c.addendum.DecodeAware(c.reader, c.ver); err != nil {
+			return errors.Wrap(err, "decode addendum")
+		}
+	}
+	if proto.FeatureChunkedPackets.In(c.ver) {
+		// Client sends final decision in addendum, just checking that it is
+		// compatible with server settings.
+		send, err := proto.NegotiateChunked(c.info.ChunkedSend, c.addendum.ChunkedRecv)
+		if err != nil {
+			return errors.Wrap(err, "chunked send")
+		}
+		recv, err := proto.NegotiateChunked(c.info.ChunkedRecv, c.addendum.ChunkedSend)
+		if err != nil {
+			return errors.Wrap(err, "chunked recv")
+		}
+		if recv {
+			c.reader.EnableChunked()
+		}
+		c.chunked = send
+		if ce := c.lg.Check(zap.DebugLevel, "Chunked framing"); ce != nil {
+			ce.Write(zap.Bool("send", send), zap.Bool("recv", recv))
+		}
+	}
 
 	_ = c.compressor // hack
 	_ = c.settings   // hack
@@ -111,7 +203,85 @@ func (c *ServerConn) handshake() error {
 	return nil
 }
 
+// sshAuth performs SSH key authentication of user.
+func (c *ServerConn) sshAuth(user string) error {
+	p, err := c.packet()
+	if err != nil {
+		return errors.Wrap(err, "packet")
+	}
+	if p != proto.ClientCodeSSHChallengeRequest {
+		return errors.Errorf("unexpected packet %q", p)
+	}
+	var nonce [32]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return errors.Wrap(err, "nonce")
+	}
+	challenge := proto.SSHChallenge{
+		Challenge: hex.EncodeToString(nonce[:]),
+	}
+	challenge.Encode(c.buf)
+	if err := c.flush(); err != nil {
+		return errors.Wrap(err, "flush")
+	}
+
+	if p, err = c.packet(); err != nil {
+		return errors.Wrap(err, "packet")
+	}
+	if p != proto.ClientCodeSSHChallengeResponse {
+		return errors.Errorf("unexpected packet %q", p)
+	}
+	var resp proto.SSHChallengeResponse
+	if err := resp.Decode(c.reader); err != nil {
+		return errors.Wrap(err, "decode response")
+	}
+
+	verify := func() error {
+		key, ok := c.sshKeys[user]
+		if !ok {
+			return errors.Errorf("no SSH key for %q", user)
+		}
+		sig := new(ssh.Signature)
+		if err := ssh.Unmarshal(resp.Signature, sig); err != nil {
+			return errors.Wrap(err, "unmarshal signature")
+		}
+		msg := challenge.Message(c.client.ProtocolVersion, c.client.Database, user)
+		return key.Verify(msg, sig)
+	}
+	if err := verify(); err != nil {
+		return c.authFailed(user, errors.Wrap(err, "verify"))
+	}
+
+	return nil
+}
+
+// authFailed sends authentication failure exception to client.
+func (c *ServerConn) authFailed(user string, err error) error {
+	c.lg.Info("Authentication failed", zap.String("user", user), zap.Error(err))
+	e := proto.Exception{
+		Code:    proto.ErrAuthenticationFailed,
+		Name:    "DB::Exception",
+		Message: fmt.Sprintf("%s: Authentication failed", user),
+	}
+	proto.ServerCodeException.Encode(c.buf)
+	e.EncodeAware(c.buf, c.ver)
+	if err := c.flush(); err != nil {
+		return errors.Wrap(err, "flush")
+	}
+	return errors.Wrap(err, "authentication failed")
+}
+
 func (c *ServerConn) flush() error {
+	if c.chunked {
+		n, err := c.buf.WriteChunked(c.conn)
+		if err != nil {
+			return errors.Wrap(err, "write chunked")
+		}
+		if ce := c.lg.Check(zap.DebugLevel, "Flush"); ce != nil {
+			ce.Write(zap.Int64("bytes", n), zap.Bool("chunked", true))
+		}
+		c.buf.Reset()
+		return nil
+	}
 	n, err := c.conn.Write(c.buf.Buf)
 	if err != nil {
 		return errors.Wrap(err, "write")
@@ -132,6 +302,8 @@ func (c *ServerConn) handlePacket(p proto.ClientCode) error {
 		return c.handlePing()
 	case proto.ClientCodeQuery:
 		return c.handleQuery()
+	case proto.ClientCodeKeepAlive:
+		return nil
 	default:
 		return errors.Errorf("%q not implemented", p)
 	}
@@ -205,6 +377,8 @@ Ingest:
 				return errors.Wrap(err, "client data")
 			}
 			break Ingest
+		case proto.ClientCodeKeepAlive:
+			continue
 		default:
 			return errors.Errorf("unexpected packet %q", p)
 		}
@@ -249,11 +423,16 @@ func (s *Server) handle(conn net.Conn) error {
 		reader: proto.NewReader(conn),
 		client: proto.ClientHello{},
 		info: proto.ServerHello{
-			Name:     "CH",
-			Revision: s.ver,
+			Name:                     "CH",
+			Revision:                 s.ver,
+			ChunkedSend:              s.chunkedSend,
+			ChunkedRecv:              s.chunkedRecv,
+			ParallelReplicasProtocol: proto.ParallelReplicasProtocolVersion,
 		},
 		tz:         time.UTC,
 		compressor: compress.NewWriter(),
+		sshKeys:    s.sshKeys,
+		auth:       s.auth,
 	}
 	return sConn.Handle()
 }
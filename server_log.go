@@ -0,0 +1,65 @@
+package ch
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// ServerLogLevel is minimum level of server logs that are sent to client,
+// value of "send_logs_level" setting.
+type ServerLogLevel string
+
+// Possible server log levels.
+const (
+	ServerLogNone        ServerLogLevel = "none"
+	ServerLogFatal       ServerLogLevel = "fatal"
+	ServerLogError       ServerLogLevel = "error"
+	ServerLogWarning     ServerLogLevel = "warning"
+	ServerLogInformation ServerLogLevel = "information"
+	ServerLogDebug       ServerLogLevel = "debug"
+	ServerLogTrace       ServerLogLevel = "trace"
+	ServerLogTest        ServerLogLevel = "test"
+)
+
+const settingSendLogsLevel = "send_logs_level"
+
+// serverLogLevel maps priority of server log to logger level.
+//
+// Fatal and critical server logs are mapped to error level, because
+// client should not panic or exit on them.
+func serverLogLevel(priority int8) zapcore.Level {
+	switch {
+	case priority <= proto.LogPriorityError:
+		return zap.ErrorLevel
+	case priority == proto.LogPriorityWarning:
+		return zap.WarnLevel
+	case priority <= proto.LogPriorityInformation:
+		return zap.InfoLevel
+	default:
+		return zap.DebugLevel
+	}
+}
+
+// forwardLogs writes server logs of query q to query logger.
+func (c *Client) forwardLogs(logs []Log, q Query) {
+	lg := c.lg.Named("server")
+	for _, l := range logs {
+		ce := lg.Check(serverLogLevel(l.Priority), l.Text)
+		if ce == nil {
+			continue
+		}
+		fields := []zap.Field{
+			zap.Uint64("thread_id", l.ThreadID),
+			zap.String("source", l.Source),
+			zap.String("host", l.Host),
+			zap.Time("event_time", l.Time),
+		}
+		if q.Logger != nil || l.QueryID != q.QueryID {
+			// Default query logger already has query_id field.
+			fields = append(fields, zap.String("query_id", l.QueryID))
+		}
+		ce.Write(fields...)
+	}
+}
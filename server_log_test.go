@@ -0,0 +1,118 @@
+package ch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func TestServerLogLevel(t *testing.T) {
+	for _, tt := range []struct {
+		Priority int8
+		Level    zapcore.Level
+	}{
+		{proto.LogPriorityFatal, zap.ErrorLevel},
+		{proto.LogPriorityCritical, zap.ErrorLevel},
+		{proto.LogPriorityError, zap.ErrorLevel},
+		{proto.LogPriorityWarning, zap.WarnLevel},
+		{proto.LogPriorityNotice, zap.InfoLevel},
+		{proto.LogPriorityInformation, zap.InfoLevel},
+		{proto.LogPriorityDebug, zap.DebugLevel},
+		{proto.LogPriorityTrace, zap.DebugLevel},
+		{proto.LogPriorityTest, zap.DebugLevel},
+	} {
+		require.Equal(t, tt.Level, serverLogLevel(tt.Priority), tt.Priority)
+	}
+}
+
+func TestClient_forwardLogs(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	c := &Client{lg: zap.New(core)}
+	now := time.Unix(1700000000, 0).UTC()
+	c.forwardLogs([]Log{
+		{
+			QueryID:  "1",
+			Source:   "executeQuery",
+			Text:     "Read 1 rows",
+			Time:     now,
+			Host:     "ch-0",
+			ThreadID: 10,
+			Priority: proto.LogPriorityInformation,
+		},
+		{
+			QueryID:  "1",
+			Text:     "Gated",
+			Priority: proto.LogPriorityTrace,
+		},
+		{
+			QueryID:  "2",
+			Source:   "MergeTreeReadPool",
+			Text:     "Failed",
+			Host:     "ch-1",
+			Priority: proto.LogPriorityError,
+		},
+	}, Query{QueryID: "1"})
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "Read 1 rows", entries[0].Message)
+	require.Equal(t, zap.InfoLevel, entries[0].Level)
+	require.Equal(t, "server", entries[0].LoggerName)
+	require.Equal(t, map[string]any{
+		"thread_id":  uint64(10),
+		"source":     "executeQuery",
+		"host":       "ch-0",
+		"event_time": now,
+	}, entries[0].ContextMap())
+
+	require.Equal(t, zap.ErrorLevel, entries[1].Level)
+	require.Equal(t, "2", entries[1].ContextMap()["query_id"], "query_id of other query should be attached")
+}
+
+func TestOptions_ServerLogLevel(t *testing.T) {
+	settings := make([]Setting, 1, 2)
+	settings[0] = SettingInt("max_threads", 1)
+	opt := Options{
+		ServerLogLevel: ServerLogDebug,
+		Settings:       settings,
+	}
+	opt.setDefaults()
+	require.Equal(t, []Setting{
+		SettingInt("max_threads", 1),
+		{Key: "send_logs_level", Value: "debug"},
+	}, opt.Settings)
+	require.Len(t, settings[:cap(settings)][1].Key, 0, "caller slice should not be modified")
+
+	explicit := Options{
+		ServerLogLevel: ServerLogDebug,
+		Settings:       []Setting{{Key: "send_logs_level", Value: "trace"}},
+	}
+	explicit.setDefaults()
+	require.Equal(t, []Setting{{Key: "send_logs_level", Value: "trace"}}, explicit.Settings)
+}
+
+func TestClient_ForwardServerLogs(t *testing.T) {
+	t.Parallel()
+	core, logs := observer.New(zap.DebugLevel)
+	conn := ConnOpt(t, Options{
+		Logger:            zap.New(core),
+		ServerLogLevel:    ServerLogTrace,
+		ForwardServerLogs: true,
+	})
+	require.NoError(t, conn.Do(context.Background(), Query{
+		Body:   "SELECT 1",
+		Result: discardResult(),
+	}))
+	forwarded := logs.Filter(func(e observer.LoggedEntry) bool {
+		return e.LoggerName == "server"
+	})
+	require.NotZero(t, forwarded.Len())
+}
@@ -0,0 +1,190 @@
+package ch
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/ClickHouse/ch-go/otelch"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// ServerSpans configures import of server spans into client trace.
+//
+// Server records spans of traced queries into system.opentelemetry_span_log,
+// so they can be fetched after query and re-emitted via TracerProvider as
+// children of query span. Re-emitted spans have new span IDs, original ones
+// are available in otelch.ServerSpanIDKey attribute.
+type ServerSpans struct {
+	// Enabled enables import of server spans after each successful query.
+	//
+	// Requires Options.OpenTelemetryInstrumentation and sampled query span.
+	Enabled bool
+	// Flush executes "SYSTEM FLUSH LOGS" before import, because server
+	// writes span log periodically, so recent spans can be missing.
+	//
+	// Requires SYSTEM FLUSH LOGS privilege.
+	Flush bool
+}
+
+// serverSpan is span from system.opentelemetry_span_log.
+type serverSpan struct {
+	ID         uint64
+	Parent     uint64
+	Name       string
+	Kind       trace.SpanKind
+	Start      time.Time
+	End        time.Time
+	Attributes []attribute.KeyValue
+}
+
+// serverSpansQuery returns query that selects server spans of trace, limited
+// to spans of query with queryID or spans without query.
+func serverSpansQuery(traceID trace.TraceID, queryID string) string {
+	// Server stores 128-bit trace id as UUID with same hex representation.
+	id := fmt.Sprintf("%x-%x-%x-%x-%x", traceID[0:4], traceID[4:6], traceID[6:8], traceID[8:10], traceID[10:])
+	return "SELECT span_id, parent_span_id, toString(operation_name) AS name, " +
+		"CAST(kind, 'Int8') AS kind, start_time_us, finish_time_us, " +
+		"arrayMap(k -> toString(k), mapKeys(attribute)) AS keys, mapValues(attribute) AS values " +
+		"FROM system.opentelemetry_span_log " +
+		"WHERE trace_id = toUUID(" + quoteParameter(id) + ") " +
+		"AND attribute['clickhouse.query_id'] IN ('', " + quoteParameter(queryID) + ") " +
+		"ORDER BY start_time_us"
+}
+
+// fetchServerSpans selects server spans of query.
+func (c *Client) fetchServerSpans(ctx context.Context, traceID trace.TraceID, queryID string) ([]serverSpan, error) {
+	if c.serverSpans.Flush {
+		if err := c.do(ctx, Query{Body: "SYSTEM FLUSH LOGS", noTrace: true}); err != nil {
+			return nil, errors.Wrap(err, "flush logs")
+		}
+	}
+	var (
+		id     proto.ColUInt64
+		parent proto.ColUInt64
+		name   proto.ColStr
+		kind   proto.ColInt8
+		start  proto.ColUInt64
+		end    proto.ColUInt64
+		keys   = proto.NewArray[string](new(proto.ColStr))
+		values = proto.NewArray[string](new(proto.ColStr))
+		spans  []serverSpan
+	)
+	if err := c.do(ctx, Query{
+		Body:    serverSpansQuery(traceID, queryID),
+		noTrace: true,
+		Result: proto.Results{
+			{Name: "span_id", Data: &id},
+			{Name: "parent_span_id", Data: &parent},
+			{Name: "name", Data: &name},
+			{Name: "kind", Data: &kind},
+			{Name: "start_time_us", Data: &start},
+			{Name: "finish_time_us", Data: &end},
+			{Name: "keys", Data: keys},
+			{Name: "values", Data: values},
+		},
+		OnResult: func(ctx context.Context, block proto.Block) error {
+			for i := 0; i < block.Rows; i++ {
+				s := serverSpan{
+					ID:     id[i],
+					Parent: parent[i],
+					Name:   name.Row(i),
+					// Server kinds start from INTERNAL = 0.
+					Kind:  trace.SpanKind(kind[i] + 1),
+					Start: time.UnixMicro(int64(start[i])),
+					End:   time.UnixMicro(int64(end[i])),
+				}
+				k, v := keys.Row(i), values.Row(i)
+				for j := range k {
+					if j >= len(v) {
+						break
+					}
+					s.Attributes = append(s.Attributes, attribute.String(k[j], v[j]))
+				}
+				spans = append(spans, s)
+			}
+			return nil
+		},
+	}); err != nil {
+		return nil, errors.Wrap(err, "select")
+	}
+	return spans, nil
+}
+
+// emitServerSpans starts and ends spans with tracer, preserving hierarchy.
+//
+// Spans with parent that is not in the list are emitted as children of
+// span from ctx.
+func emitServerSpans(ctx context.Context, tracer trace.Tracer, spans []serverSpan) {
+	known := make(map[uint64]struct{}, len(spans))
+	for _, s := range spans {
+		known[s.ID] = struct{}{}
+	}
+	var roots []serverSpan
+	children := make(map[uint64][]serverSpan, len(spans))
+	for _, s := range spans {
+		if _, ok := known[s.Parent]; ok && s.Parent != s.ID {
+			children[s.Parent] = append(children[s.Parent], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+	emitted := make(map[uint64]struct{}, len(spans))
+	var emit func(ctx context.Context, list []serverSpan)
+	emit = func(ctx context.Context, list []serverSpan) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+		for _, s := range list {
+			if _, ok := emitted[s.ID]; ok {
+				// Cycle or duplicate.
+				continue
+			}
+			emitted[s.ID] = struct{}{}
+			attrs := append([]attribute.KeyValue{
+				otelch.ServerSpanIDKey.String(spanIDString(s.ID)),
+			}, s.Attributes...)
+			spanCtx, span := tracer.Start(ctx, s.Name,
+				trace.WithTimestamp(s.Start),
+				trace.WithSpanKind(s.Kind),
+				trace.WithAttributes(attrs...),
+			)
+			emit(spanCtx, children[s.ID])
+			span.End(trace.WithTimestamp(s.End))
+		}
+	}
+	emit(ctx, roots)
+}
+
+// spanIDString formats span id as in server span log, which
+// is big-endian representation of trace.SpanID.
+func spanIDString(id uint64) string {
+	var b trace.SpanID
+	binary.BigEndian.PutUint64(b[:], id)
+	return b.String()
+}
+
+// importServerSpans fetches server spans of query and emits them as children
+// of span.
+func (c *Client) importServerSpans(ctx context.Context, span trace.Span, queryID string) {
+	sc := span.SpanContext()
+	if !sc.IsSampled() {
+		// Server does not trace queries that are not sampled.
+		return
+	}
+	// Internal query should not be part of the trace.
+	spans, err := c.fetchServerSpans(trace.ContextWithSpanContext(ctx, trace.SpanContext{}), sc.TraceID(), queryID)
+	if err != nil {
+		c.lg.Warn("Failed to import server spans", zap.Error(err))
+		return
+	}
+	emitServerSpans(trace.ContextWithSpan(ctx, span), c.tracer, spans)
+	if ce := c.lg.Check(zap.DebugLevel, "Imported server spans"); ce != nil {
+		ce.Write(zap.Int("count", len(spans)), zap.Stringer("trace_id", sc.TraceID()))
+	}
+}
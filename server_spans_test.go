@@ -0,0 +1,152 @@
+package ch
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ClickHouse/ch-go/internal/ztest"
+	"github.com/ClickHouse/ch-go/otelch"
+)
+
+func TestServerSpansQuery(t *testing.T) {
+	traceID := trace.TraceID{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+	}
+	q := serverSpansQuery(traceID, "it's")
+	require.Contains(t, q, "trace_id = toUUID('01020304-0506-0708-090a-0b0c0d0e0f10')")
+	require.Contains(t, q, `IN ('', 'it\'s')`)
+}
+
+func TestEmitServerSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	tracer := tp.Tracer("test")
+
+	ctx, do := tracer.Start(context.Background(), "Do")
+	do.End()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	emitServerSpans(ctx, tracer, []serverSpan{
+		{
+			ID:     1,
+			Parent: 0xdead, // client span
+			Name:   "query",
+			Kind:   trace.SpanKindServer,
+			Start:  start,
+			End:    start.Add(time.Second),
+			Attributes: []attribute.KeyValue{
+				attribute.String("clickhouse.query_id", "id"),
+			},
+		},
+		{ID: 3, Parent: 2, Name: "read", Start: start.Add(time.Millisecond * 20), End: start.Add(time.Millisecond * 30)},
+		{ID: 2, Parent: 1, Name: "pipeline", Start: start.Add(time.Millisecond * 10), End: start.Add(time.Millisecond * 900)},
+		{ID: 4, Parent: 4, Name: "self", Start: start, End: start},
+	})
+
+	spans := map[string]tracesdk.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	require.Len(t, spans, 5)
+	parent := func(name string) trace.SpanID { return spans[name].Parent().SpanID() }
+	id := func(name string) trace.SpanID { return spans[name].SpanContext().SpanID() }
+	require.Equal(t, id("Do"), parent("query"))
+	require.Equal(t, id("Do"), parent("self"))
+	require.Equal(t, id("query"), parent("pipeline"))
+	require.Equal(t, id("pipeline"), parent("read"))
+
+	query := spans["query"]
+	require.Equal(t, trace.SpanKindServer, query.SpanKind())
+	require.Equal(t, start, query.StartTime())
+	require.Equal(t, start.Add(time.Second), query.EndTime())
+	require.Contains(t, query.Attributes(), attribute.String("clickhouse.query_id", "id"))
+	require.Contains(t, query.Attributes(), otelch.ServerSpanIDKey.String("0000000000000001"))
+}
+
+func TestClient_ServerSpansInternal(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	lg := ztest.NewLogger(t)
+	s := NewServer(ServerOptions{Logger: lg.Named("srv")})
+	go func() { _ = s.Serve(ln) }()
+
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	c, err := Dial(ctx, Options{
+		Logger:                       lg.Named("usr"),
+		Address:                      ln.Addr().String(),
+		OpenTelemetryInstrumentation: true,
+		TracerProvider:               tp,
+		MeterProvider:                sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		ServerSpans:                  ServerSpans{Enabled: true, Flush: true},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	connectSpans := len(recorder.Ended())
+
+	require.NoError(t, c.Do(ctx, Query{Body: "SELECT 1"}))
+
+	// Internal queries for import are not traced.
+	var names []string
+	for _, span := range recorder.Ended()[connectSpans:] {
+		names = append(names, span.Name())
+	}
+	require.ElementsMatch(t, []string{"Do", "Send", "Receive"}, names)
+
+	// Internal queries are not reported in metrics.
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	queries := findMetric(t, rm, otelch.MetricQueryDuration)
+	require.Equal(t, uint64(1), histogramCount(t, queries, otelch.OutcomeOK))
+
+	require.NoError(t, c.Ping(ctx), "connection should be usable")
+}
+
+func TestClient_ServerSpans(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	conn := ConnOpt(t, Options{
+		OpenTelemetryInstrumentation: true,
+		TracerProvider:               tp,
+		ServerSpans:                  ServerSpans{Enabled: true, Flush: true},
+	})
+	require.NoError(t, conn.Do(ctx, Query{
+		Body:   "SELECT 1",
+		Result: discardResult(),
+	}))
+
+	var do tracesdk.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "Do" {
+			do = span
+		}
+	}
+	require.NotNil(t, do)
+	var imported int
+	for _, span := range recorder.Ended() {
+		for _, a := range span.Attributes() {
+			if a.Key == otelch.ServerSpanIDKey {
+				imported++
+				require.Equal(t, do.SpanContext().TraceID(), span.SpanContext().TraceID())
+			}
+		}
+	}
+	require.NotZero(t, imported, "server spans should be imported")
+}
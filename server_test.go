@@ -2,16 +2,24 @@ package ch
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"log/slog"
 	"net"
+	"sync"
 	"testing"
 
 	"github.com/go-faster/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/crypto/ssh"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/ClickHouse/ch-go/cht"
 	"github.com/ClickHouse/ch-go/internal/ztest"
+	"github.com/ClickHouse/ch-go/proto"
 )
 
 func TestServer_Serve(t *testing.T) {
@@ -65,3 +73,302 @@ func TestServer_Serve(t *testing.T) {
 	})
 	require.NoError(t, g.Wait())
 }
+
+func TestServer_SSHAuth(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	lg := ztest.NewLogger(t)
+	s := NewServer(ServerOptions{
+		Logger: lg.Named("srv"),
+		SSHKeys: map[string]ssh.PublicKey{
+			"ssh_user": signer.PublicKey(),
+		},
+	})
+	go func() { _ = s.Serve(ln) }()
+
+	ctx := context.Background()
+	t.Run("Ok", func(t *testing.T) {
+		c, err := Dial(ctx, Options{
+			Logger:    lg.Named("usr"),
+			Address:   ln.Addr().String(),
+			User:      "ssh_user",
+			Password:  "ignored",
+			SSHSigner: signer,
+		})
+		require.NoError(t, err)
+		require.NoError(t, c.Ping(ctx))
+		require.NoError(t, c.Close())
+	})
+	t.Run("UnknownKey", func(t *testing.T) {
+		_, other, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		otherSigner, err := ssh.NewSignerFromKey(other)
+		require.NoError(t, err)
+
+		_, err = Dial(ctx, Options{
+			Logger:    lg.Named("usr"),
+			Address:   ln.Addr().String(),
+			User:      "ssh_user",
+			SSHSigner: otherSigner,
+		})
+		require.True(t, IsErr(err, proto.ErrAuthenticationFailed), "%v", err)
+	})
+	t.Run("UnknownUser", func(t *testing.T) {
+		_, err := Dial(ctx, Options{
+			Logger:    lg.Named("usr"),
+			Address:   ln.Addr().String(),
+			User:      "default",
+			SSHSigner: signer,
+		})
+		require.True(t, IsErr(err, proto.ErrAuthenticationFailed), "%v", err)
+	})
+	t.Run("OldProtocol", func(t *testing.T) {
+		_, err := Dial(ctx, Options{
+			Logger:          lg.Named("usr"),
+			Address:         ln.Addr().String(),
+			User:            "ssh_user",
+			SSHSigner:       signer,
+			ProtocolVersion: int(proto.FeatureSSHAuthentication) - 1,
+		})
+		require.ErrorContains(t, err, "SSH authentication is not supported")
+	})
+	t.Run("OldServer", func(t *testing.T) {
+		old, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = old.Close() })
+		go func() {
+			conn, err := old.Accept()
+			if err != nil {
+				return
+			}
+			defer func() { _ = conn.Close() }()
+			// Server without SSH support replies with hello.
+			const version = int(proto.FeatureSSHAuthentication) - 1
+			r := proto.NewReader(conn)
+			if _, err := r.UVarInt(); err != nil {
+				return
+			}
+			var hello proto.ClientHello
+			if err := hello.Decode(r); err != nil {
+				return
+			}
+			b := new(proto.Buffer)
+			(&proto.ServerHello{Name: "old", Revision: version}).EncodeAware(b, version)
+			_, _ = conn.Write(b.Buf)
+			_, _ = io.Copy(io.Discard, conn)
+		}()
+
+		_, err = Dial(ctx, Options{
+			Logger:    lg.Named("usr"),
+			Address:   old.Addr().String(),
+			User:      "ssh_user",
+			SSHSigner: signer,
+		})
+		require.ErrorContains(t, err, "server does not support SSH authentication")
+	})
+}
+
+func TestServer_Credentials(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	const token = "eyJhbGciOiJIUzI1NiJ9.e30.ZRrHA1JJJW8opsbCGfG_HACGpVUMN_a9IV7pAx_Zmeo"
+	var password atomic.String
+	password.Store("first")
+
+	lg := ztest.NewLogger(t)
+	s := NewServer(ServerOptions{
+		Logger: lg.Named("srv"),
+		Authenticate: func(c Credentials) error {
+			if c.JWT != "" {
+				if c.JWT != token {
+					return errors.New("bad token")
+				}
+				return nil
+			}
+			if c.User != "user" || c.Password != password.Load() {
+				return errors.New("bad password")
+			}
+			return nil
+		},
+	})
+	go func() { _ = s.Serve(ln) }()
+
+	ctx := context.Background()
+	dial := func(creds CredentialsProvider) error {
+		c, err := Dial(ctx, Options{
+			Logger:      lg.Named("usr"),
+			Address:     ln.Addr().String(),
+			User:        "ignored",
+			Credentials: creds,
+		})
+		if err != nil {
+			return err
+		}
+		if err := c.Ping(ctx); err != nil {
+			return err
+		}
+		return c.Close()
+	}
+	t.Run("Rotate", func(t *testing.T) {
+		var calls atomic.Int64
+		creds := CredentialsFunc(func(ctx context.Context) (Credentials, error) {
+			calls.Inc()
+			return Credentials{User: "user", Password: password.Load()}, nil
+		})
+		require.NoError(t, dial(creds))
+		password.Store("second")
+		require.NoError(t, dial(creds))
+		require.Equal(t, int64(2), calls.Load())
+
+		err := dial(StaticCredentials(Credentials{User: "user", Password: "first"}))
+		require.True(t, IsErr(err, proto.ErrAuthenticationFailed), "%v", err)
+	})
+	t.Run("JWT", func(t *testing.T) {
+		require.NoError(t, dial(StaticCredentials(Credentials{JWT: token})))
+		err := dial(StaticCredentials(Credentials{JWT: "bad"}))
+		require.True(t, IsErr(err, proto.ErrAuthenticationFailed), "%v", err)
+	})
+	t.Run("Error", func(t *testing.T) {
+		err := dial(CredentialsFunc(func(ctx context.Context) (Credentials, error) {
+			return Credentials{}, errors.New("secret store is unavailable")
+		}))
+		require.ErrorContains(t, err, "secret store is unavailable")
+	})
+}
+
+func TestServer_Chunked(t *testing.T) {
+	ctx := context.Background()
+	lg := ztest.NewLogger(t)
+	for _, tt := range []struct {
+		Name   string
+		Server ServerOptions
+		Client Options
+		Send   bool
+		Error  bool
+	}{
+		{Name: "Default"},
+		{
+			Name:   "ClientOptional",
+			Client: Options{ChunkedSend: proto.ChunkedOptional, ChunkedRecv: proto.ChunkedOptional},
+			Send:   true,
+		},
+		{
+			Name:   "ServerRequired",
+			Server: ServerOptions{ChunkedSend: proto.Chunked, ChunkedRecv: proto.Chunked},
+			Send:   true,
+		},
+		{
+			Name:   "SendOnly",
+			Client: Options{ChunkedSend: proto.Chunked},
+			Send:   true,
+		},
+		{
+			Name:   "RecvOnly",
+			Client: Options{ChunkedRecv: proto.Chunked},
+		},
+		{
+			Name:   "Fallback",
+			Server: ServerOptions{ChunkedSend: proto.NotChunked, ChunkedRecv: proto.NotChunked},
+			Client: Options{ChunkedSend: proto.ChunkedOptional, ChunkedRecv: proto.ChunkedOptional},
+		},
+		{
+			Name:   "Incompatible",
+			Server: ServerOptions{ChunkedSend: proto.NotChunked, ChunkedRecv: proto.NotChunked},
+			Client: Options{ChunkedSend: proto.Chunked},
+			Error:  true,
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = ln.Close() })
+
+			tt.Server.Logger = lg.Named("srv")
+			s := NewServer(tt.Server)
+			go func() { _ = s.Serve(ln) }()
+
+			tt.Client.Logger = lg.Named("usr")
+			tt.Client.Address = ln.Addr().String()
+			c, err := Dial(ctx, tt.Client)
+			if tt.Error {
+				require.ErrorContains(t, err, "incompatible chunked settings")
+				return
+			}
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+			require.Equal(t, tt.Send, c.chunked)
+
+			for i := 0; i < 3; i++ {
+				require.NoError(t, c.Ping(ctx))
+				require.NoError(t, c.Do(ctx, Query{Body: "SELECT 1"}))
+			}
+		})
+	}
+}
+
+// recordHandler records messages of slog records.
+type recordHandler struct {
+	slog.Handler
+	mux      sync.Mutex
+	messages []string
+}
+
+func (h *recordHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.messages = append(h.messages, r.Message)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) Messages() []string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+func TestServer_LogHandler(t *testing.T) {
+	newHandler := func(level slog.Level) *recordHandler {
+		return &recordHandler{
+			Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: level}),
+		}
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	srv := newHandler(slog.LevelInfo)
+	s := NewServer(ServerOptions{LogHandler: srv})
+	go func() { _ = s.Serve(ln) }()
+
+	ctx := context.Background()
+	dial := func(h slog.Handler) {
+		c, err := Dial(ctx, Options{
+			Address:    ln.Addr().String(),
+			LogHandler: h,
+		})
+		require.NoError(t, err)
+		require.NoError(t, c.Ping(ctx))
+		require.NoError(t, c.Close())
+	}
+
+	debug := newHandler(slog.LevelDebug)
+	dial(debug)
+	require.Contains(t, debug.Messages(), "Connected")
+	require.Contains(t, debug.Messages(), "Flush")
+
+	info := newHandler(slog.LevelInfo)
+	dial(info)
+	require.Empty(t, info.Messages(), "debug messages should be gated")
+	require.Contains(t, srv.Messages(), "Connected")
+}
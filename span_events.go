@@ -0,0 +1,167 @@
+package ch
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ClickHouse/ch-go/otelch"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+// SpanEvents configures events that are added to query span if
+// Options.OpenTelemetryInstrumentation is enabled.
+type SpanEvents struct {
+	// Progress enables events for progress packets.
+	Progress bool
+	// ProgressInterval is minimum interval between progress events,
+	// so long queries are sampled. Zero means event for every packet.
+	ProgressInterval time.Duration
+	// LogLevel is minimum level of server logs that are added as events,
+	// blank or ServerLogNone disables log events.
+	//
+	// Server sends logs only if Options.ServerLogLevel or
+	// "send_logs_level" setting is set.
+	LogLevel ServerLogLevel
+	// ProfileEvents enables events for profile events snapshots,
+	// where increments are summed and gauges are maximized by name.
+	ProfileEvents bool
+}
+
+// priority returns maximum priority of server log that is sent for level,
+// or zero if logs are disabled.
+func (l ServerLogLevel) priority() int8 {
+	switch l {
+	case ServerLogFatal:
+		return proto.LogPriorityFatal
+	case ServerLogError:
+		return proto.LogPriorityError
+	case ServerLogWarning:
+		return proto.LogPriorityWarning
+	case ServerLogInformation:
+		return proto.LogPriorityInformation
+	case ServerLogDebug:
+		return proto.LogPriorityDebug
+	case ServerLogTrace:
+		return proto.LogPriorityTrace
+	case ServerLogTest:
+		return proto.LogPriorityTest
+	default:
+		return 0
+	}
+}
+
+func (c *Client) progressEvent(p proto.Progress) {
+	if !c.spanEvents.Progress || c.querySpan == nil || !c.querySpan.IsRecording() {
+		return
+	}
+	// Progress packets are deltas, so skipped ones are summed to
+	// keep totals of events.
+	pending := &c.progressPending
+	pending.Rows += p.Rows
+	pending.Bytes += p.Bytes
+	pending.TotalRows += p.TotalRows
+	pending.TotalBytes += p.TotalBytes
+	pending.WroteRows += p.WroteRows
+	pending.WroteBytes += p.WroteBytes
+	pending.ElapsedNs += p.ElapsedNs
+
+	now := time.Now()
+	if interval := c.spanEvents.ProgressInterval; interval > 0 && now.Sub(c.progressEventAt) < interval {
+		return
+	}
+	c.flushProgressEvent(now)
+}
+
+// flushProgressEvent adds event with pending progress, if any.
+func (c *Client) flushProgressEvent(now time.Time) {
+	p := c.progressPending
+	if p == (proto.Progress{}) || c.querySpan == nil || !c.querySpan.IsRecording() {
+		return
+	}
+	c.progressPending = proto.Progress{}
+	c.progressEventAt = now
+	c.querySpan.AddEvent(otelch.EventProgress,
+		trace.WithTimestamp(now),
+		trace.WithAttributes(
+			otelch.ProgressRowsKey.Int64(int64(p.Rows)),
+			otelch.ProgressBytesKey.Int64(int64(p.Bytes)),
+			otelch.ProgressTotalRowsKey.Int64(int64(p.TotalRows)),
+			otelch.ProgressTotalBytesKey.Int64(int64(p.TotalBytes)),
+			otelch.ProgressWroteRowsKey.Int64(int64(p.WroteRows)),
+			otelch.ProgressWroteBytesKey.Int64(int64(p.WroteBytes)),
+			otelch.ProgressElapsedKey.Int64(int64(p.ElapsedNs)),
+		),
+	)
+}
+
+func (c *Client) logEvents(logs []Log) {
+	maxPriority := c.spanEvents.LogLevel.priority()
+	if maxPriority == 0 || c.querySpan == nil || !c.querySpan.IsRecording() {
+		return
+	}
+	for _, l := range logs {
+		if l.Priority > maxPriority {
+			continue
+		}
+		c.querySpan.AddEvent(otelch.EventLog,
+			trace.WithTimestamp(l.Time),
+			trace.WithAttributes(
+				otelch.LogTextKey.String(l.Text),
+				otelch.LogSourceKey.String(l.Source),
+				otelch.LogPriorityKey.Int(int(l.Priority)),
+				otelch.LogHostKey.String(l.Host),
+				otelch.LogThreadIDKey.Int64(int64(l.ThreadID)),
+			),
+		)
+	}
+}
+
+func (c *Client) profileEventsEvent(events []ProfileEvent) {
+	if !c.spanEvents.ProfileEvents || c.querySpan == nil || !c.querySpan.IsRecording() || len(events) == 0 {
+		return
+	}
+	values := make(map[string]int64, len(events))
+	for _, e := range events {
+		if e.ThreadID != 0 {
+			// Per-thread rows are included in thread group row.
+			continue
+		}
+		v, ok := values[e.Name]
+		switch {
+		case e.Type == proto.ProfileIncrement:
+			values[e.Name] = v + e.Value
+		case !ok || e.Value > v:
+			values[e.Name] = e.Value
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, len(values))
+	for name, v := range values {
+		attrs = append(attrs, otelch.ProfileEventKey(name).Int64(v))
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	c.querySpan.AddEvent(otelch.EventProfileEvents, trace.WithAttributes(attrs...))
+}
+
+// startSpan starts child span of query if query is traced,
+// returning function that ends span with err.
+func (c *Client) startSpan(ctx context.Context, name string) (context.Context, func(err error)) {
+	if c.querySpan == nil {
+		return ctx, func(error) {}
+	}
+	ctx, span := c.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,163 @@
+package ch
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+
+	"github.com/ClickHouse/ch-go/internal/ztest"
+	"github.com/ClickHouse/ch-go/otelch"
+	"github.com/ClickHouse/ch-go/proto"
+)
+
+func TestClient_QuerySpans(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	lg := ztest.NewLogger(t)
+	s := NewServer(ServerOptions{Logger: lg.Named("srv")})
+	go func() { _ = s.Serve(ln) }()
+
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	c, err := Dial(ctx, Options{
+		Logger:                       lg.Named("usr"),
+		Address:                      ln.Addr().String(),
+		OpenTelemetryInstrumentation: true,
+		TracerProvider:               tp,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	connectSpans := len(recorder.Ended())
+
+	require.NoError(t, c.Do(ctx, Query{Body: "SELECT 1"}))
+	spans := map[string]tracesdk.ReadOnlySpan{}
+	for _, span := range recorder.Ended()[connectSpans:] {
+		spans[span.Name()] = span
+	}
+	require.Len(t, spans, 3)
+	do := spans["Do"]
+	require.NotNil(t, do)
+	for _, name := range []string{"Send", "Receive"} {
+		span := spans[name]
+		require.NotNil(t, span, name)
+		require.Equal(t, do.SpanContext().SpanID(), span.Parent().SpanID(), name)
+	}
+	require.Nil(t, c.querySpan, "query span should be reset")
+}
+
+func newSpanEventsClient(t *testing.T, events SpanEvents) (*Client, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder))
+	_, span := tp.Tracer("test").Start(context.Background(), "Do")
+	return &Client{
+		lg:         zap.NewNop(),
+		spanEvents: events,
+		querySpan:  span,
+	}, recorder
+}
+
+func endedEvents(t *testing.T, c *Client, recorder *tracetest.SpanRecorder) []tracesdk.Event {
+	t.Helper()
+	c.querySpan.End()
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	return spans[0].Events()
+}
+
+func TestClient_progressEvent(t *testing.T) {
+	t.Run("Every", func(t *testing.T) {
+		c, recorder := newSpanEventsClient(t, SpanEvents{Progress: true})
+		for i := 0; i < 3; i++ {
+			c.progressEvent(proto.Progress{Rows: uint64(i), TotalRows: 10})
+		}
+		events := endedEvents(t, c, recorder)
+		require.Len(t, events, 3)
+		require.Equal(t, otelch.EventProgress, events[2].Name)
+		require.Contains(t, events[2].Attributes, otelch.ProgressRowsKey.Int64(2))
+		require.Contains(t, events[2].Attributes, otelch.ProgressTotalRowsKey.Int64(10))
+	})
+	t.Run("Sampled", func(t *testing.T) {
+		c, recorder := newSpanEventsClient(t, SpanEvents{Progress: true, ProgressInterval: time.Hour})
+		for i := 1; i <= 3; i++ {
+			c.progressEvent(proto.Progress{Rows: uint64(i), ElapsedNs: 10})
+		}
+		// Skipped deltas are summed and emitted on query end.
+		c.flushProgressEvent(time.Now())
+		events := endedEvents(t, c, recorder)
+		require.Len(t, events, 2)
+		require.Contains(t, events[0].Attributes, otelch.ProgressRowsKey.Int64(1))
+		require.Contains(t, events[1].Attributes, otelch.ProgressRowsKey.Int64(5))
+		require.Contains(t, events[1].Attributes, otelch.ProgressElapsedKey.Int64(20))
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		c, recorder := newSpanEventsClient(t, SpanEvents{})
+		c.progressEvent(proto.Progress{Rows: 1})
+		require.Empty(t, endedEvents(t, c, recorder))
+	})
+}
+
+func TestClient_logEvents(t *testing.T) {
+	logs := []Log{
+		{Text: "error", Priority: proto.LogPriorityError},
+		{Text: "warning", Priority: proto.LogPriorityWarning},
+		{Text: "info", Priority: proto.LogPriorityInformation},
+		{Text: "trace", Priority: proto.LogPriorityTrace},
+	}
+	for _, tt := range []struct {
+		Level ServerLogLevel
+		Texts []string
+	}{
+		{"", nil},
+		{ServerLogNone, nil},
+		{ServerLogError, []string{"error"}},
+		{ServerLogWarning, []string{"error", "warning"}},
+		{ServerLogTrace, []string{"error", "warning", "info", "trace"}},
+	} {
+		t.Run(string(tt.Level), func(t *testing.T) {
+			c, recorder := newSpanEventsClient(t, SpanEvents{LogLevel: tt.Level})
+			c.logEvents(logs)
+			var texts []string
+			for _, e := range endedEvents(t, c, recorder) {
+				require.Equal(t, otelch.EventLog, e.Name)
+				for _, a := range e.Attributes {
+					if a.Key == otelch.LogTextKey {
+						texts = append(texts, a.Value.AsString())
+					}
+				}
+			}
+			require.Equal(t, tt.Texts, texts)
+		})
+	}
+}
+
+func TestClient_profileEventsEvent(t *testing.T) {
+	c, recorder := newSpanEventsClient(t, SpanEvents{ProfileEvents: true})
+	c.profileEventsEvent([]ProfileEvent{
+		{Name: "SelectedRows", Type: proto.ProfileIncrement, Value: 10},
+		{Name: "SelectedRows", Type: proto.ProfileIncrement, Value: 5},
+		{Name: "MemoryTrackerUsage", Type: proto.ProfileGauge, Value: 100},
+		{Name: "MemoryTrackerUsage", Type: proto.ProfileGauge, Value: 50},
+		// Per-thread rows are included in thread group row.
+		{Name: "SelectedRows", Type: proto.ProfileIncrement, Value: 7, ThreadID: 42},
+		{Name: "MemoryTrackerUsage", Type: proto.ProfileGauge, Value: 1000, ThreadID: 42},
+	})
+	c.profileEventsEvent(nil)
+	events := endedEvents(t, c, recorder)
+	require.Len(t, events, 1)
+	require.Equal(t, otelch.EventProfileEvents, events[0].Name)
+	require.Equal(t, []attribute.KeyValue{
+		otelch.ProfileEventKey("MemoryTrackerUsage").Int64(100),
+		otelch.ProfileEventKey("SelectedRows").Int64(15),
+	}, events[0].Attributes)
+}